@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cuimingda/subs-cli/internal/mkv"
+
+	"github.com/spf13/cobra"
+)
+
+func NewAttachmentsCmd() *cobra.Command {
+	attachmentsCmd := &cobra.Command{
+		Use:   "attachments",
+		Short: "List and extract mkv attachments such as fonts",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	attachmentsListCmd := &cobra.Command{
+		Use:   "list <mkv_filename>",
+		Short: "List attachments of an mkv file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			attachments, err := listMKVAttachments(args[0])
+			if err != nil {
+				return err
+			}
+
+			for _, attachment := range attachments {
+				kind := ""
+				if mkv.IsFontAttachment(attachment.Attachment) {
+					kind = " [font]"
+				}
+				if _, err := fmt.Fprintf(
+					cmd.OutOrStdout(),
+					"%s %s %s %d bytes%s\n",
+					attachment.StreamID,
+					attachment.FileName,
+					displayOrEmpty(attachment.MimeType),
+					attachment.Size,
+					kind,
+				); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	var streamID string
+	var outputDir string
+	var fontDir bool
+	var overwrite bool
+	attachmentsExtractCmd := &cobra.Command{
+		Use:   "extract <mkv_filename>",
+		Short: "Extract attachments of an mkv file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fileName := args[0]
+			if fontDir && outputDir != "" {
+				return fmt.Errorf("--font-dir cannot be used with --output")
+			}
+
+			attachments, err := listMKVAttachments(fileName)
+			if err != nil {
+				return err
+			}
+
+			if streamID != "" {
+				var selected []mkv.AttachedFile
+				for _, attachment := range attachments {
+					if streamIDMatch(attachment.StreamID, streamID) {
+						selected = append(selected, attachment)
+					}
+				}
+				if len(selected) == 0 {
+					return fmt.Errorf("attachment id %s not found", streamID)
+				}
+				attachments = selected
+			}
+
+			mode := newRunMode(cmd)
+			if fontDir {
+				var fonts []mkv.AttachedFile
+				for _, attachment := range attachments {
+					if mkv.IsFontAttachment(attachment.Attachment) {
+						fonts = append(fonts, attachment)
+					}
+				}
+				if len(fonts) == 0 {
+					return fmt.Errorf("no font attachments found in %s", fileName)
+				}
+				attachments = fonts
+
+				if outputDir, err = mkv.UserFontDir(); err != nil {
+					return err
+				}
+			} else {
+				if outputDir == "" {
+					outputDir = filepath.Dir(fileName)
+				} else if info, err := os.Stat(outputDir); err != nil {
+					return err
+				} else if !info.IsDir() {
+					return fmt.Errorf("output is not a directory: %s", outputDir)
+				}
+				base := strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+				outputDir = filepath.Join(outputDir, base+"_attachments")
+			}
+
+			if err := mkv.RequireFFmpegInstalled(); err != nil {
+				return err
+			}
+			if mode.dryRun {
+				if err := mode.printPlan(shellCommand("mkdir", "-p", outputDir)); err != nil {
+					return err
+				}
+			} else if err := os.MkdirAll(outputDir, 0o755); err != nil {
+				return err
+			}
+
+			extracted := 0
+			for _, attachment := range attachments {
+				outputPath := filepath.Join(outputDir, mkv.AttachmentFileName(attachment.Attachment))
+				if _, err := os.Stat(outputPath); err == nil && !overwrite {
+					if _, err := fmt.Fprintf(cmd.OutOrStdout(), "Skipping %s (exists): %s\n", attachment.StreamID, outputPath); err != nil {
+						return err
+					}
+					continue
+				}
+
+				ffmpegArgs := mkv.BuildAttachmentExtractFFmpegArgs(fileName, attachment, outputPath)
+				if mode.dryRun {
+					if err := mode.printPlan(shellCommand("ffmpeg", ffmpegArgs...)); err != nil {
+						return err
+					}
+					continue
+				}
+
+				if _, err := fmt.Fprintf(cmd.OutOrStdout(), "Exporting attachment %s -> %s\n", attachment.StreamID, outputPath); err != nil {
+					return err
+				}
+				if output, err := mode.runFFmpeg(ffmpegArgs...); err != nil {
+					return fmt.Errorf("failed to export attachment %s: %w: %s", attachment.StreamID, err, strings.TrimSpace(string(output)))
+				}
+				extracted++
+			}
+
+			if mode.dryRun {
+				return nil
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Extracted %d of %d attachments to %s\n", extracted, len(attachments), outputDir)
+			return err
+		},
+	}
+	attachmentsExtractCmd.Flags().StringVar(&streamID, "id", "", "Only extract one attachment by stream id (for example: 11)")
+	attachmentsExtractCmd.Flags().StringVar(&outputDir, "output", "", "Directory in which the <mkv-name>_attachments directory is created")
+	attachmentsExtractCmd.Flags().BoolVar(&fontDir, "font-dir", false, "Install font attachments into the user font directory")
+	attachmentsExtractCmd.Flags().BoolVar(&overwrite, "overwrite", false, "Overwrite existing files instead of skipping them")
+
+	attachmentsCmd.AddCommand(attachmentsListCmd)
+	attachmentsCmd.AddCommand(attachmentsExtractCmd)
+
+	return attachmentsCmd
+}
+
+func listMKVAttachments(fileName string) ([]mkv.AttachedFile, error) {
+	if !mkv.IsMKVFile(fileName) {
+		return nil, fmt.Errorf("file must be an mkv file: %s", fileName)
+	}
+	if _, err := os.Stat(fileName); err != nil {
+		return nil, err
+	}
+
+	attachments, err := mkv.ListAttachments(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments) == 0 {
+		return nil, fmt.Errorf("no attachments found in %s", fileName)
+	}
+	return attachments, nil
+}
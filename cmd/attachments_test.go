@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cuimingda/subs-cli/internal/mkv"
+)
+
+func TestAttachmentsCommand_RejectsFilesWithoutAttachments(t *testing.T) {
+	samplePath := resolveTestMkvPath(t)
+	if samplePath == "" {
+		t.Skip("skip attachments command test: test mkv not found")
+	}
+
+	for _, args := range [][]string{
+		{"attachments", "list", samplePath},
+		{"attachments", "extract", samplePath},
+	} {
+		cmd := NewRootCmd()
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		if err == nil || !strings.Contains(err.Error(), "no attachments found in") {
+			t.Fatalf("%v: error = %v, want no attachments error", args, err)
+		}
+	}
+}
+
+func TestAttachmentsCommand_RejectsInvalidUsage(t *testing.T) {
+	target, _ := writeTestMP4(t)
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"attachments", "list", target}, want: "file must be an mkv file"},
+		{args: []string{"attachments", "extract", "--font-dir", "--output", ".", "movie.mkv"}, want: "--font-dir cannot be used with --output"},
+		{args: []string{"attachments", "list"}, want: "accepts 1 arg(s)"},
+	}
+	for _, tt := range tests {
+		cmd := NewRootCmd()
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs(tt.args)
+		err := cmd.Execute()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%v: error = %v, want contains %q", tt.args, err, tt.want)
+		}
+	}
+}
+
+// attachmentFFmpegRunner writes FONT to the -dump_attachment path of each
+// call.
+type attachmentFFmpegRunner struct {
+	calls [][]string
+}
+
+func (r *attachmentFFmpegRunner) IsInstalled() error {
+	return nil
+}
+
+func (r *attachmentFFmpegRunner) Run(args ...string) ([]byte, error) {
+	r.calls = append(r.calls, args)
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "-dump_attachment") && i+1 < len(args) {
+			return nil, os.WriteFile(args[i+1], []byte("FONT"), 0o644)
+		}
+	}
+	return nil, nil
+}
+
+func (r *attachmentFFmpegRunner) RunContext(_ context.Context, _ func(mkv.FFmpegProgress), args ...string) ([]byte, error) {
+	return r.Run(args...)
+}
+
+func copyAttachmentTestFile(t *testing.T) string {
+	t.Helper()
+
+	target := filepath.Join(t.TempDir(), "movie.mkv")
+	if err := copyFile(filepath.Join(testPackageDir, "..", "resources", "attached_font.mkv"), target); err != nil {
+		t.Fatalf("copy fixture failed: %v", err)
+	}
+	return target
+}
+
+func TestAttachmentsExtractCommand_DumpsThroughFFmpeg(t *testing.T) {
+	target := copyAttachmentTestFile(t)
+	runner := &attachmentFFmpegRunner{}
+	mkv.SetFFmpegRunner(runner)
+	t.Cleanup(func() { mkv.SetFFmpegRunner(nil) })
+
+	var out bytes.Buffer
+	cmd := NewRootCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"attachments", "extract", target})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	outputPath := filepath.Join(filepath.Dir(target), "movie_attachments", "font.ttf")
+	if len(runner.calls) != 1 {
+		t.Fatalf("ffmpeg calls = %v, want one", runner.calls)
+	}
+	want := "-hide_banner -y -dump_attachment:2 " + outputPath + " -i " + target + " -t 0 -f null -"
+	if got := strings.Join(runner.calls[0], " "); got != want {
+		t.Fatalf("ffmpeg args = %q, want %q", got, want)
+	}
+	if data, err := os.ReadFile(outputPath); err != nil || string(data) != "FONT" {
+		t.Fatalf("extracted font = %q, %v", data, err)
+	}
+	if !strings.Contains(out.String(), "Extracted 1 of 1 attachments") {
+		t.Fatalf("output = %q, want summary", out.String())
+	}
+}
+
+func TestAttachmentsExtractCommand_DryRunWritesNothing(t *testing.T) {
+	target := copyAttachmentTestFile(t)
+	runner := &attachmentFFmpegRunner{}
+	mkv.SetFFmpegRunner(runner)
+	t.Cleanup(func() { mkv.SetFFmpegRunner(nil) })
+
+	var out bytes.Buffer
+	cmd := NewRootCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"--dry-run", "attachments", "extract", target})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	outputDir := filepath.Join(filepath.Dir(target), "movie_attachments")
+	want := shellCommand("mkdir", "-p", outputDir) + "\n" +
+		shellCommand("ffmpeg", mkv.BuildAttachmentExtractFFmpegArgs(target, mkv.AttachedFile{StreamID: "0:2"}, filepath.Join(outputDir, "font.ttf"))...) + "\n"
+	if out.String() != want {
+		t.Fatalf("output = %q, want %q", out.String(), want)
+	}
+	if len(runner.calls) != 0 {
+		t.Fatalf("ffmpeg calls = %v, want none", runner.calls)
+	}
+	if _, err := os.Stat(outputDir); !os.IsNotExist(err) {
+		t.Fatalf("dry run should not create %s: %v", outputDir, err)
+	}
+}
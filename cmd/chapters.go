@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cuimingda/subs-cli/internal/mkv"
+
+	"github.com/spf13/cobra"
+)
+
+func NewChaptersCmd() *cobra.Command {
+	chaptersCmd := &cobra.Command{
+		Use:   "chapters",
+		Short: "Export and import chapters of an mkv or mp4 file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	var exportFormat string
+	var outputFile string
+	chaptersExportCmd := &cobra.Command{
+		Use:   "export <mkv_or_mp4_filename>",
+		Short: "Print or write the chapters of an mkv or mp4 file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fileName := args[0]
+			exportFormat = strings.ToLower(strings.TrimSpace(exportFormat))
+			if err := mkv.ValidateChapterFormat(exportFormat); err != nil {
+				return err
+			}
+			if err := checkChapterTarget(fileName); err != nil {
+				return err
+			}
+
+			chapters, err := mkv.ReadChapters(fileName)
+			if err != nil {
+				return err
+			}
+			if len(chapters) == 0 {
+				return fmt.Errorf("no chapters found in %s", fileName)
+			}
+
+			data, err := mkv.FormatChapters(chapters, exportFormat)
+			if err != nil {
+				return err
+			}
+			if outputFile == "" {
+				_, err = cmd.OutOrStdout().Write(data)
+				return err
+			}
+			if mode := newRunMode(cmd); mode.dryRun {
+				return mode.printPlan(fmt.Sprintf("# write %d chapters as %s to %s", len(chapters), exportFormat, shellQuote(outputFile)))
+			}
+			if err := os.WriteFile(outputFile, data, 0o644); err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Exported %d chapters to %s\n", len(chapters), outputFile)
+			return err
+		},
+	}
+	chaptersExportCmd.Flags().StringVar(&exportFormat, "format", mkv.ChapterFormatXML, "Chapter format: ogm, xml or ffmetadata")
+	chaptersExportCmd.Flags().StringVar(&outputFile, "output", "", "Write chapters to this file instead of stdout")
+
+	var importFormat string
+	var backup string
+	chaptersImportCmd := &cobra.Command{
+		Use:   "import <mkv_or_mp4_filename> <chapters_file>",
+		Short: "Replace the chapters of an mkv or mp4 file",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			targetFile := args[0]
+			chaptersFile := args[1]
+			if err := validateBackupMode(backup); err != nil {
+				return err
+			}
+			importFormat = strings.ToLower(strings.TrimSpace(importFormat))
+			if importFormat != "" {
+				if err := mkv.ValidateChapterFormat(importFormat); err != nil {
+					return err
+				}
+			}
+			if err := checkChapterTarget(targetFile); err != nil {
+				return err
+			}
+
+			data, err := os.ReadFile(chaptersFile)
+			if err != nil {
+				return err
+			}
+			if importFormat == "" {
+				if importFormat, err = mkv.DetectChapterFormat(data); err != nil {
+					return fmt.Errorf("%w of %s; set it with --format", err, chaptersFile)
+				}
+			}
+			chapters, err := mkv.ParseChapters(data, importFormat)
+			if err != nil {
+				return fmt.Errorf("%s: %w", chaptersFile, err)
+			}
+			if len(chapters) == 0 {
+				return fmt.Errorf("no chapters found in %s", chaptersFile)
+			}
+
+			if err := mkv.RequireFFmpegInstalled(); err != nil {
+				return err
+			}
+
+			outputFile := mkvMergeOutputPath(targetFile)
+			mode := newRunMode(cmd)
+			if mode.dryRun {
+				// The converted chapters only live in a temporary file, so the
+				// plan names where they would have to be written.
+				metadataName := chaptersFile
+				if importFormat != mkv.ChapterFormatFFMetadata {
+					metadataName += ".ffmetadata"
+					if err := mode.printPlan(fmt.Sprintf("# write the %d chapters of %s as ffmetadata to %s", len(chapters), shellQuote(chaptersFile), shellQuote(metadataName))); err != nil {
+						return err
+					}
+				}
+				ffmpegArgs := append(mkv.BuildChapterImportFFmpegArgs(targetFile, metadataName), outputFile)
+				return mode.printRemuxPlan(ffmpegArgs, targetFile, outputFile, backup)
+			}
+
+			metadata, err := mkv.FormatChapters(chapters, mkv.ChapterFormatFFMetadata)
+			if err != nil {
+				return err
+			}
+			metadataFile, err := os.CreateTemp("", "subs-chapters-*.txt")
+			if err != nil {
+				return err
+			}
+			defer os.Remove(metadataFile.Name())
+			if _, err := metadataFile.Write(metadata); err != nil {
+				metadataFile.Close()
+				return err
+			}
+			if err := metadataFile.Close(); err != nil {
+				return err
+			}
+
+			streams, err := probeMKVStreams(targetFile)
+			if err != nil {
+				return err
+			}
+			if err := mkv.RemoveTempOutputIfExists(outputFile); err != nil {
+				return err
+			}
+			if err := checkRemuxDiskSpace(targetFile); err != nil {
+				return err
+			}
+
+			ffmpegArgs := append(mkv.BuildChapterImportFFmpegArgs(targetFile, metadataFile.Name()), outputFile)
+			importOutput, err := runFFmpegRemux(mode, targetFile, outputFile, ffmpegArgs)
+			if err != nil {
+				return fmt.Errorf("failed to import chapters into %s: %w: %s", targetFile, err, bytes.TrimSpace(importOutput))
+			}
+
+			if err := replaceWithVerifiedOutput(targetFile, outputFile, mkv.ExpectSameStreams(streams), backup); err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Imported %d chapters into %s\n", len(chapters), targetFile)
+			return err
+		},
+	}
+	chaptersImportCmd.Flags().StringVar(&importFormat, "format", "", "Chapter file format: ogm, xml or ffmetadata (detected when empty)")
+	addBackupFlag(chaptersImportCmd, &backup)
+
+	chaptersCmd.AddCommand(chaptersExportCmd)
+	chaptersCmd.AddCommand(chaptersImportCmd)
+
+	return chaptersCmd
+}
+
+func checkChapterTarget(fileName string) error {
+	if !mkv.IsVideoContainerFile(fileName) {
+		return fmt.Errorf("file must be an mkv or mp4 file: %s", fileName)
+	}
+	_, err := os.Stat(fileName)
+	return err
+}
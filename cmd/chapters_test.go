@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChaptersImportCommand_RemuxesWithFFMetadata(t *testing.T) {
+	target, runner := writeTestMP4(t)
+	skipRemuxVerification(t)
+	chaptersFile := filepath.Join(t.TempDir(), "chapters.txt")
+	if err := os.WriteFile(chaptersFile, []byte("CHAPTER01=00:00:00.000\nCHAPTER01NAME=Intro\nCHAPTER02=00:01:00.000\nCHAPTER02NAME=Part A\n"), 0o644); err != nil {
+		t.Fatalf("write chapters failed: %v", err)
+	}
+
+	cmd := NewRootCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"chapters", "import", target, chaptersFile})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	if !strings.Contains(out.String(), "Imported 2 chapters into "+target) {
+		t.Fatalf("output = %q, want import summary", out.String())
+	}
+	args := strings.Join(runner.calls[len(runner.calls)-1], " ")
+	if !strings.Contains(args, "-map 0 -map_metadata 0 -map_chapters 1 -c copy "+target+".tmp_subs.mp4") {
+		t.Fatalf("ffmpeg args = %q, want chapters mapped from the metadata input", args)
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != "remuxed" {
+		t.Fatalf("target = %q, %v, want remuxed file", data, err)
+	}
+}
+
+func TestChaptersCommand_RejectsInvalidInput(t *testing.T) {
+	target, _ := writeTestMP4(t)
+	unknownFile := filepath.Join(t.TempDir(), "chapters.txt")
+	if err := os.WriteFile(unknownFile, []byte("0:00 Intro\n"), 0o644); err != nil {
+		t.Fatalf("write chapters failed: %v", err)
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"chapters", "export", "--format", "cue", target}, want: "unsupported chapter format: cue"},
+		{args: []string{"chapters", "export", "movie.avi"}, want: "file must be an mkv or mp4 file"},
+		{args: []string{"chapters", "export", target}, want: "no chapters found in " + target},
+		{args: []string{"chapters", "import", target, unknownFile}, want: "cannot detect the chapter format of " + unknownFile + "; set it with --format"},
+		{args: []string{"chapters", "import", "--format", "ogm", target, unknownFile}, want: "unexpected line: 0:00 Intro"},
+	}
+	for _, tt := range tests {
+		cmd := NewRootCmd()
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs(tt.args)
+		err := cmd.Execute()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%v: error = %v, want contains %q", tt.args, err, tt.want)
+		}
+	}
+}
@@ -2,9 +2,9 @@ package cmd
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strconv"
 
 	"github.com/cuimingda/subs-cli/internal/mkv"
@@ -14,16 +14,22 @@ import (
 
 func NewDefaultCmd() *cobra.Command {
 	var streamID string
+	var backup string
 
 	cmd := &cobra.Command{
-		Use:   "default <mkv_filename>",
-		Short: "Toggle default disposition for a subtitle stream in an mkv file",
+		Use:   "default <mkv_or_mp4_filename>",
+		Short: "Toggle default disposition for a subtitle stream in an mkv or mp4 file",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cobraCmd *cobra.Command, args []string) error {
 			targetFile := args[0]
+			mode := newRunMode(cobraCmd)
 
-			if filepath.Ext(targetFile) != ".mkv" && filepath.Ext(targetFile) != ".MKV" {
-				return fmt.Errorf("file must be an mkv file: %s", targetFile)
+			if err := validateBackupMode(backup); err != nil {
+				return err
+			}
+
+			if !mkv.IsVideoContainerFile(targetFile) {
+				return fmt.Errorf("file must be an mkv or mp4 file: %s", targetFile)
 			}
 
 			if _, err := os.Stat(targetFile); err != nil {
@@ -34,11 +40,7 @@ func NewDefaultCmd() *cobra.Command {
 				return fmt.Errorf("invalid stream id: %s", streamID)
 			}
 
-			if err := mkv.RequireFFmpegInstalled(); err != nil {
-				return err
-			}
-
-			streams, err := getMKVStreams(targetFile)
+			streams, err := probeMKVStreams(targetFile)
 			if err != nil {
 				return err
 			}
@@ -48,32 +50,68 @@ func NewDefaultCmd() *cobra.Command {
 				return err
 			}
 
-			outputFile := mkvMergeOutputPath(targetFile)
-			if err := mkv.RemoveTempOutputIfExists(outputFile); err != nil {
+			if mkv.IsMP4File(targetFile) {
+				if err := mkv.ValidateMP4DefaultToggle(streams, targetStream); err != nil {
+					return err
+				}
+			} else {
+				trackEdits, err := mkvDefaultToggleTrackEdits(streams, targetStream)
+				if err != nil {
+					return err
+				}
+				err = editTracksInPlace(mode, targetFile, trackEdits, backup)
+				if err == nil {
+					if mode.dryRun {
+						return nil
+					}
+					_, err = fmt.Fprintf(cobraCmd.OutOrStdout(), "Toggled default for stream %s\n", targetStream.ID)
+					return err
+				}
+				if !errors.Is(err, mkv.ErrInPlaceEditUnavailable) {
+					return err
+				}
+			}
+
+			if err := mkv.RequireFFmpegInstalled(); err != nil {
 				return err
 			}
 
+			outputFile := mkvMergeOutputPath(targetFile)
 			ffmpegArgs := mkvDefaultToggleFFmpegArgs(targetFile, streams, targetStream)
 			if len(ffmpegArgs) == 0 {
 				return fmt.Errorf("failed to build ffmpeg args for stream %s", streamID)
 			}
 			ffmpegArgs = append(ffmpegArgs, outputFile)
 
-			defaultOutput, err := mkv.RunFFmpeg(ffmpegArgs...)
+			if mode.dryRun {
+				return mode.printRemuxPlan(ffmpegArgs, targetFile, outputFile, backup)
+			}
+			if err := mkv.RemoveTempOutputIfExists(outputFile); err != nil {
+				return err
+			}
+			if err := checkRemuxDiskSpace(targetFile); err != nil {
+				return err
+			}
+
+			defaultOutput, err := runFFmpegRemux(mode, targetFile, outputFile, ffmpegArgs)
 			if err != nil {
 				return fmt.Errorf("failed to set default for stream %s: %w: %s", streamID, err, bytes.TrimSpace(defaultOutput))
 			}
 
-			if _, err := fmt.Fprintf(cobraCmd.OutOrStdout(), "Toggled default for stream %s\n", targetStream.ID); err != nil {
+			expected := mkv.ExpectStreamsAfterToggle(streams, targetStream, "default", targetStream.IsDefault)
+			if err := replaceWithVerifiedOutput(targetFile, outputFile, expected, backup); err != nil {
 				return err
 			}
 
-			return os.Rename(outputFile, targetFile)
+			_, err = fmt.Fprintf(cobraCmd.OutOrStdout(), "Toggled default for stream %s\n", targetStream.ID)
+			return err
 		},
 	}
 
 	cmd.Flags().StringVar(&streamID, "id", "", "Target subtitle stream id (pure number)")
 	_ = cmd.MarkFlagRequired("id")
 
+	addBackupFlag(cmd, &backup)
+
 	return cmd
 }
@@ -224,25 +224,70 @@ func TestDefaultCommand_RejectsMissingIDFlag(t *testing.T) {
 	}
 }
 
-func TestDefaultCommand_RejectsWhenFFmpegMissing(t *testing.T) {
+func TestDefaultCommand_EditsInPlaceWithoutFFmpeg(t *testing.T) {
 	samplePath := resolveTestMkvPath(t)
 	if samplePath == "" {
 		t.Skip("skip default command test: test mkv not found")
 	}
 
 	cmd := NewRootCmd()
-	tmpDir := t.TempDir()
-	target := filepath.Join(tmpDir, filepath.Base(samplePath))
+	target := filepath.Join(t.TempDir(), filepath.Base(samplePath))
 	if err := copyFile(samplePath, target); err != nil {
 		t.Fatalf("copy target failed: %v", err)
 	}
-	if err := os.Chdir(tmpDir); err != nil {
-		t.Fatalf("chdir failed: %v", err)
+	beforeInfo, err := os.Stat(target)
+	if err != nil {
+		t.Fatalf("stat target failed: %v", err)
 	}
 
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"default", target, "--id", "3"})
+	t.Setenv("PATH", "/tmp/no-path-for-test")
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	afterStreams, err := probeMKVStreams(target)
+	if err != nil {
+		t.Fatalf("probeMKVStreams() after error = %v", err)
+	}
+	for _, stream := range afterStreams {
+		if stream.ID == "0:3" && !stream.IsDefault {
+			t.Fatalf("expected stream 0:3 to be default")
+		}
+		if stream.ID == "0:2" && stream.IsDefault {
+			t.Fatalf("expected previous default stream 0:2 to be reset")
+		}
+	}
+
+	afterInfo, err := os.Stat(target)
+	if err != nil {
+		t.Fatalf("stat target failed: %v", err)
+	}
+	if afterInfo.Size() != beforeInfo.Size() {
+		t.Fatalf("file size changed: before=%d after=%d", beforeInfo.Size(), afterInfo.Size())
+	}
+	if _, err := os.Stat(target + ".tmp_subs.mkv"); err == nil {
+		t.Fatalf("temporary output should not be created")
+	}
+	if !strings.Contains(out.String(), "Toggled default for stream 0:3") {
+		t.Fatalf("output = %q, want toggle success message", out.String())
+	}
+}
+
+func TestDefaultCommand_RejectsWhenFFmpegMissing(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "target.mkv")
+	if err := os.WriteFile(target, []byte("not real mkv"), 0o644); err != nil {
+		t.Fatalf("write target.mkv failed: %v", err)
+	}
+
+	cmd := NewRootCmd()
 	cmd.SetOut(&bytes.Buffer{})
 	cmd.SetErr(&bytes.Buffer{})
-	cmd.SetArgs([]string{"default", filepath.Base(target), "--id", "2"})
+	cmd.SetArgs([]string{"default", target, "--id", "2"})
 	t.Setenv("PATH", "/tmp/no-path-for-test")
 
 	err := cmd.Execute()
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/cuimingda/subs-cli/internal/journal"
 	"github.com/cuimingda/subs-cli/internal/subtitles"
 	"github.com/spf13/cobra"
 )
@@ -52,12 +53,26 @@ func NewDialogueCmd() *cobra.Command {
 		},
 	}
 
+	var pruneCheck bool
 	dialogueFontPruneCmd := &cobra.Command{
 		Use:   "prune",
 		Short: "Remove \\fn font tags from ASS files",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			result, err := subtitles.PruneDialogueFontTagsFromAssFiles()
+			if mode := newRunMode(cmd); mode.dryRun || pruneCheck {
+				changes, err := subtitles.PlanDialogueFontTagPrune()
+				if err != nil {
+					return err
+				}
+				return reportFileChanges(mode, pruneCheck, changes)
+			}
+
+			var result subtitles.DialogueFontPruneResult
+			err := runJournaled(cmd, func(*journal.Journal) error {
+				var err error
+				result, err = subtitles.PruneDialogueFontTagsFromAssFiles()
+				return err
+			})
 			if err != nil {
 				return err
 			}
@@ -75,6 +90,8 @@ func NewDialogueCmd() *cobra.Command {
 		},
 	}
 
+	addCheckFlag(dialogueFontPruneCmd, &pruneCheck)
+
 	dialogueCmd.AddCommand(dialogueFontCmd)
 	dialogueFontCmd.AddCommand(dialogueFontListCmd)
 	dialogueFontCmd.AddCommand(dialogueFontPruneCmd)
@@ -288,3 +288,49 @@ func TestDialogueFontPruneCommand_RepeatedRun(t *testing.T) {
 		t.Fatalf("second run output = %q, want no-tag summary", out.String())
 	}
 }
+
+func TestDialogueFontPruneCommand_DryRunPrintsDiff(t *testing.T) {
+	cmd := NewRootCmd()
+	tmpDir := t.TempDir()
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(testPackageDir)
+	})
+
+	original := "Dialogue: 0,0:00:00.00,0:00:01.00,Default,,0,0,0,,{\\fnArial\\fs18}Hello\n"
+	if err := os.WriteFile("fonts.ass", []byte(original), 0o644); err != nil {
+		t.Fatalf("write fonts.ass failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"--dry-run", "dialogue", "font", "prune"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	output := out.String()
+	for _, want := range []string{
+		"--- a/fonts.ass\n",
+		"+++ b/fonts.ass\n",
+		"-Dialogue: 0,0:00:00.00,0:00:01.00,Default,,0,0,0,,{\\fnArial\\fs18}Hello\n",
+		"+Dialogue: 0,0:00:00.00,0:00:01.00,Default,,0,0,0,,{\\fs18}Hello\n",
+		"1 file(s) would change",
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("output = %q, want contains %q", output, want)
+		}
+	}
+
+	content, err := os.ReadFile("fonts.ass")
+	if err != nil {
+		t.Fatalf("read file failed: %v", err)
+	}
+	if string(content) != original {
+		t.Fatalf("content = %q, want unchanged", string(content))
+	}
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cuimingda/subs-cli/internal/mkv"
+
+	"github.com/spf13/cobra"
+)
+
+func NewDispositionCmd() *cobra.Command {
+	var streamID string
+	var setValue string
+	var clearValue string
+	var exclusive bool
+	var backup string
+
+	cmd := &cobra.Command{
+		Use:   "disposition <mkv_filename>",
+		Short: "Set or clear disposition flags of a subtitle or audio stream in an mkv file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			targetFile := args[0]
+			mode := newRunMode(cobraCmd)
+
+			if err := validateBackupMode(backup); err != nil {
+				return err
+			}
+
+			if filepath.Ext(targetFile) != ".mkv" && filepath.Ext(targetFile) != ".MKV" {
+				return fmt.Errorf("file must be an mkv file: %s", targetFile)
+			}
+
+			if _, err := os.Stat(targetFile); err != nil {
+				return err
+			}
+
+			setFlags, err := mkv.ParseDispositionFlags(setValue)
+			if err != nil {
+				return err
+			}
+			clearFlags, err := mkv.ParseDispositionFlags(clearValue)
+			if err != nil {
+				return err
+			}
+			if err := mkv.ValidateDispositionChange(setFlags, clearFlags); err != nil {
+				return err
+			}
+
+			streams, err := probeMKVStreams(targetFile)
+			if err != nil {
+				return err
+			}
+
+			targetStream, err := findStreamForTrackEdit(streams, streamID)
+			if err != nil {
+				return err
+			}
+
+			trackEdits, err := mkvDispositionTrackEdits(streams, targetStream, setFlags, clearFlags, exclusive)
+			if err == nil {
+				err = editTracksInPlace(mode, targetFile, trackEdits, backup)
+			}
+			if err == nil {
+				if mode.dryRun {
+					return nil
+				}
+				_, err = fmt.Fprintf(cobraCmd.OutOrStdout(), "Updated disposition for stream %s\n", targetStream.ID)
+				return err
+			}
+			if !errors.Is(err, mkv.ErrInPlaceEditUnavailable) {
+				return err
+			}
+
+			if err := mkv.RequireFFmpegInstalled(); err != nil {
+				return err
+			}
+
+			outputFile := mkvMergeOutputPath(targetFile)
+			ffmpegArgs := mkvDispositionFFmpegArgs(targetFile, streams, targetStream, setFlags, clearFlags, exclusive)
+			ffmpegArgs = append(ffmpegArgs, outputFile)
+
+			if mode.dryRun {
+				return mode.printRemuxPlan(ffmpegArgs, targetFile, outputFile, backup)
+			}
+			if err := mkv.RemoveTempOutputIfExists(outputFile); err != nil {
+				return err
+			}
+			if err := checkRemuxDiskSpace(targetFile); err != nil {
+				return err
+			}
+
+			dispositionOutput, err := runFFmpegRemux(mode, targetFile, outputFile, ffmpegArgs)
+			if err != nil {
+				return fmt.Errorf("failed to update disposition for stream %s: %w: %s", streamID, err, bytes.TrimSpace(dispositionOutput))
+			}
+
+			expected := mkv.ExpectStreamsAfterDisposition(streams, targetStream, setFlags, clearFlags, exclusive)
+			if err := replaceWithVerifiedOutput(targetFile, outputFile, expected, backup); err != nil {
+				return err
+			}
+
+			_, err = fmt.Fprintf(cobraCmd.OutOrStdout(), "Updated disposition for stream %s\n", targetStream.ID)
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&streamID, "id", "", "Target subtitle or audio stream id (pure number)")
+	_ = cmd.MarkFlagRequired("id")
+	cmd.Flags().StringVar(&setValue, "set", "", "Comma separated disposition flags to set")
+	cmd.Flags().StringVar(&clearValue, "clear", "", "Comma separated disposition flags to clear")
+	cmd.Flags().BoolVar(&exclusive, "exclusive", false, "Clear the set flags on other streams of the same type")
+
+	addBackupFlag(cmd, &backup)
+
+	return cmd
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDispositionCommand_SetsExclusiveFlagsInPlace(t *testing.T) {
+	samplePath := resolveTestMkvPath(t)
+	if samplePath == "" {
+		t.Skip("skip disposition command test: test mkv not found")
+	}
+
+	target := filepath.Join(t.TempDir(), filepath.Base(samplePath))
+	if err := copyFile(samplePath, target); err != nil {
+		t.Fatalf("copy target failed: %v", err)
+	}
+
+	cmd := NewRootCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"disposition", target, "--id", "4", "--set", "default,hearing_impaired", "--exclusive"})
+	t.Setenv("PATH", "/tmp/no-path-for-test")
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	streams, err := probeMKVStreams(target)
+	if err != nil {
+		t.Fatalf("probeMKVStreams() error = %v", err)
+	}
+	for _, stream := range streams {
+		switch {
+		case stream.ID == "0:4":
+			if !stream.IsDefault || !stream.IsHearingImpaired {
+				t.Fatalf("stream 0:4 = %+v, want default and hearing impaired", stream)
+			}
+		case stream.Type == "Subtitle":
+			if stream.IsDefault || stream.IsHearingImpaired {
+				t.Fatalf("stream %s = %+v, want flags cleared", stream.ID, stream)
+			}
+		case stream.ID == "0:1":
+			if !stream.IsDefault {
+				t.Fatalf("audio stream 0:1 lost its default flag")
+			}
+		}
+	}
+	if !strings.Contains(out.String(), "Updated disposition for stream 0:4") {
+		t.Fatalf("output = %q, want update message", out.String())
+	}
+}
+
+func TestDispositionCommand_ClearsFlagOnAudioStream(t *testing.T) {
+	samplePath := resolveTestMkvPath(t)
+	if samplePath == "" {
+		t.Skip("skip disposition command test: test mkv not found")
+	}
+
+	target := filepath.Join(t.TempDir(), filepath.Base(samplePath))
+	if err := copyFile(samplePath, target); err != nil {
+		t.Fatalf("copy target failed: %v", err)
+	}
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"disposition", target, "--id", "1", "--clear", "default"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	streams, err := probeMKVStreams(target)
+	if err != nil {
+		t.Fatalf("probeMKVStreams() error = %v", err)
+	}
+	if streams[1].IsDefault {
+		t.Fatalf("audio stream 0:1 should no longer be default")
+	}
+	if !streams[2].IsDefault {
+		t.Fatalf("subtitle stream 0:2 should keep its default flag")
+	}
+}
+
+func TestDispositionCommand_FallsBackToFFmpegForCaptions(t *testing.T) {
+	samplePath := resolveTestMkvPath(t)
+	if samplePath == "" {
+		t.Skip("skip disposition command test: test mkv not found")
+	}
+
+	target := filepath.Join(t.TempDir(), filepath.Base(samplePath))
+	if err := copyFile(samplePath, target); err != nil {
+		t.Fatalf("copy target failed: %v", err)
+	}
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"disposition", target, "--id", "3", "--set", "captions"})
+	t.Setenv("PATH", "/tmp/no-path-for-test")
+
+	err := cmd.Execute()
+	if err == nil {
+		t.Fatalf("expected ffmpeg missing error, got nil")
+	}
+	if err.Error() != "ffmpeg is not installed or not in PATH, please install ffmpeg" {
+		t.Fatalf("error = %q, want ffmpeg missing message", err)
+	}
+}
+
+func TestDispositionCommand_RejectsInvalidFlags(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "target.mkv")
+	if err := os.WriteFile(tmpFile, []byte("mock mkv"), 0o644); err != nil {
+		t.Fatalf("write target.mkv failed: %v", err)
+	}
+
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--set", "subtitled"}, "unknown disposition flag: subtitled"},
+		{[]string{"--set", "default", "--clear", "default"}, "cannot be both set and cleared"},
+		{nil, "at least one of --set or --clear is required"},
+	}
+	for _, tc := range cases {
+		cmd := NewRootCmd()
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs(append([]string{"disposition", tmpFile, "--id", "2"}, tc.args...))
+
+		err := cmd.Execute()
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("args %v: error = %v, want %q", tc.args, err, tc.want)
+		}
+	}
+}
@@ -3,17 +3,33 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/cuimingda/subs-cli/internal/journal"
 	"github.com/cuimingda/subs-cli/internal/subtitles"
 	"github.com/spf13/cobra"
 )
 
 func NewEncodingResetCmd() *cobra.Command {
-	return &cobra.Command{
+	var check bool
+
+	encodingResetCmd := &cobra.Command{
 		Use:   "reset",
 		Short: "Reset subtitle file encoding to UTF-8",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			result, err := subtitles.ResetCurrentDirSubtitleFilesToUTF8()
+			if mode := newRunMode(cmd); mode.dryRun || check {
+				changes, err := subtitles.PlanCurrentDirSubtitleFilesUTF8Reset()
+				if err != nil {
+					return err
+				}
+				return reportFileChanges(mode, check, changes)
+			}
+
+			var result subtitles.ResetResult
+			err := runJournaled(cmd, func(*journal.Journal) error {
+				var err error
+				result, err = subtitles.ResetCurrentDirSubtitleFilesToUTF8()
+				return err
+			})
 			if err != nil {
 				return err
 			}
@@ -22,4 +38,7 @@ func NewEncodingResetCmd() *cobra.Command {
 			return err
 		},
 	}
+	addCheckFlag(encodingResetCmd, &check)
+
+	return encodingResetCmd
 }
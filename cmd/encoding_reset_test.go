@@ -85,3 +85,43 @@ func TestEncodingResetCommand_RejectsArgs(t *testing.T) {
 		t.Fatalf("expected args validation error, got nil")
 	}
 }
+
+func TestEncodingResetCommand_CheckFailsWhenFilesWouldChange(t *testing.T) {
+	cmd := NewRootCmd()
+	tmpDir := t.TempDir()
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(testPackageDir)
+	})
+
+	utf16 := []byte{0xFF, 0xFE, 'h', 0, 'i', 0, '\n', 0}
+	if err := os.WriteFile("a.srt", utf16, 0o644); err != nil {
+		t.Fatalf("write a.srt failed: %v", err)
+	}
+	if err := os.WriteFile("b.srt", []byte("hello\n"), 0o644); err != nil {
+		t.Fatalf("write b.srt failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"--dry-run", "encoding", "reset", "--check"})
+
+	err := cmd.Execute()
+	if err == nil || err.Error() != "1 file(s) would change" {
+		t.Fatalf("cmd.Execute() error = %v, want 1 file(s) would change", err)
+	}
+	if !strings.HasPrefix(out.String(), "--- a/a.srt (UTF-16") {
+		t.Fatalf("output = %q, want encoding diff header", out.String())
+	}
+
+	content, err := os.ReadFile("a.srt")
+	if err != nil {
+		t.Fatalf("read a.srt failed: %v", err)
+	}
+	if !bytes.Equal(content, utf16) {
+		t.Fatalf("a.srt = %v, want unchanged", content)
+	}
+}
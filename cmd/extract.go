@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/cuimingda/subs-cli/internal/mkv"
@@ -14,26 +17,62 @@ import (
 func NewExtractCmd() *cobra.Command {
 	var streamID string
 	var outputDir string
+	var all bool
+	var languages string
+	var format string
+	var nameTemplate string
+	var overwrite bool
+	var convert string
 
 	cmd := &cobra.Command{
-		Use:   "extract <mkv-file>",
-		Short: "Extract all subtitle streams from an mkv file",
-		Args:  cobra.ExactArgs(1),
+		Use:   "extract <mkv-or-mp4-file>",
+		Short: "Extract all subtitle streams from an mkv or mp4 file",
+		Args: func(cobraCmd *cobra.Command, args []string) error {
+			if all {
+				return cobra.NoArgs(cobraCmd, args)
+			}
+			return cobra.ExactArgs(1)(cobraCmd, args)
+		},
 		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			selection := mkv.ExtractSelection{Languages: splitCommaList(languages), Format: format}
+			for _, language := range selection.Languages {
+				if !validLanguageTag(language) {
+					return fmt.Errorf("invalid language tag: %s", language)
+				}
+			}
+			convert = strings.ToLower(strings.TrimSpace(convert))
+			if convert != "" {
+				if err := mkv.ValidateSubtitleConversion(convert); err != nil {
+					return err
+				}
+			}
+			if all {
+				if streamID != "" {
+					return errors.New("--id cannot be used with --all")
+				}
+				return runExtractAll(cobraCmd.OutOrStdout(), extractAllOptions{
+					outputDir:    outputDir,
+					selection:    selection,
+					nameTemplate: nameTemplate,
+					overwrite:    overwrite,
+					convert:      convert,
+					mode:         newRunMode(cobraCmd),
+				})
+			}
+			if cobraCmd.Flags().Changed("template") || overwrite {
+				return errors.New("--template and --overwrite require --all")
+			}
+
 			fileName := args[0]
 
-			if filepath.Ext(fileName) != ".mkv" && filepath.Ext(fileName) != ".MKV" {
-				return fmt.Errorf("file must be an mkv file: %s", fileName)
+			if !mkv.IsVideoContainerFile(fileName) {
+				return fmt.Errorf("file must be an mkv or mp4 file: %s", fileName)
 			}
 
 			if _, err := os.Stat(fileName); err != nil {
 				return err
 			}
 
-			if err := mkv.RequireFFmpegInstalled(); err != nil {
-				return err
-			}
-
 			mkvOutputDir := filepath.Dir(fileName)
 			if outputDir != "" {
 				info, err := os.Stat(outputDir)
@@ -46,7 +85,7 @@ func NewExtractCmd() *cobra.Command {
 				mkvOutputDir = outputDir
 			}
 
-			streams, err := getMKVStreams(fileName)
+			streams, err := probeMKVStreams(fileName)
 			if err != nil {
 				return err
 			}
@@ -62,12 +101,30 @@ func NewExtractCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			selectedStreams, err = mkv.SelectSubtitleStreamsForExtraction(selectedStreams, selection)
+			if err != nil {
+				return err
+			}
+
+			if convert != "" {
+				if err := mkv.CheckSubtitleConversion(selectedStreams, convert); err != nil {
+					return err
+				}
+			}
+			if err := requireFFmpegForExtraction(selectedStreams); err != nil {
+				return err
+			}
 
 			outDir := mkvSubtitleOutputDir(fileName, mkvOutputDir)
 			if _, err := os.Stat(outDir); err == nil {
 				return fmt.Errorf("subtitle output directory already exists: %s", outDir)
 			}
-			if err := os.MkdirAll(outDir, 0o755); err != nil {
+			mode := newRunMode(cobraCmd)
+			if mode.dryRun {
+				if err := mode.printPlan(shellCommand("mkdir", "-p", outDir)); err != nil {
+					return err
+				}
+			} else if err := os.MkdirAll(outDir, 0o755); err != nil {
 				return err
 			}
 
@@ -80,6 +137,9 @@ func NewExtractCmd() *cobra.Command {
 				if err != nil {
 					return err
 				}
+				if convert != "" {
+					outputPath = strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + "." + convert
+				}
 
 				if _, err := fmt.Fprintf(
 					cobraCmd.OutOrStdout(),
@@ -92,11 +152,8 @@ func NewExtractCmd() *cobra.Command {
 					return err
 				}
 
-				extractOutput, err := mkv.RunFFmpeg(
-					mkv.BuildExtractFFmpegArgs(fileName, stream, outputPath)...,
-				)
-				if err != nil {
-					return fmt.Errorf("failed to export stream %s: %w: %s", stream.ID, err, strings.TrimSpace(string(extractOutput)))
+				if err := extractSubtitleStream(mode, fileName, stream, outputPath, convert, false); err != nil {
+					return err
 				}
 			}
 
@@ -106,6 +163,188 @@ func NewExtractCmd() *cobra.Command {
 
 	cmd.Flags().StringVar(&streamID, "id", "", "Only export one subtitle stream by stream id (for example: 4)")
 	cmd.Flags().StringVar(&outputDir, "output", "", "Output directory for extracted subtitle files")
+	cmd.Flags().BoolVar(&all, "all", false, "Extract from every mkv and mp4 file in current directory, next to each video")
+	cmd.Flags().StringVar(&languages, "language", "", "Comma separated language tags to extract (for example: chi,eng)")
+	cmd.Flags().StringVar(&format, "format", "", "Only extract text or image subtitles, or one codec or extension (for example: ass)")
+	cmd.Flags().StringVar(&nameTemplate, "template", mkv.DefaultExtractNameTemplate, "File name template for --all using {base}, {lang}, {title}, {id} and {ext}")
+	cmd.Flags().BoolVar(&overwrite, "overwrite", false, "Overwrite existing subtitle files with --all instead of skipping them")
+	cmd.Flags().StringVar(&convert, "convert", "", "Convert text subtitles while extracting: srt, vtt or ass")
 
 	return cmd
 }
+
+type extractAllOptions struct {
+	outputDir    string
+	selection    mkv.ExtractSelection
+	nameTemplate string
+	overwrite    bool
+	convert      string
+	mode         runMode
+}
+
+func runExtractAll(out io.Writer, options extractAllOptions) error {
+	if err := mkv.ValidateExtractNameTemplate(options.nameTemplate); err != nil {
+		return err
+	}
+	if options.outputDir != "" {
+		info, err := os.Stat(options.outputDir)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("output is not a directory: %s", options.outputDir)
+		}
+	}
+
+	videoFiles, err := listCurrentDirVideoFiles()
+	if err != nil {
+		return err
+	}
+	if len(videoFiles) == 0 {
+		return errors.New("no mkv or mp4 files found in current directory")
+	}
+
+	failures := 0
+	var summary []string
+	for _, videoFile := range videoFiles {
+		extracted, skipped, err := extractVideoSubtitles(out, videoFile, options)
+		if err != nil {
+			failures++
+			summary = append(summary, fmt.Sprintf("%s => %s: %v", videoFile, colorize("failed", "31"), err))
+			continue
+		}
+		status := colorize("extracted", "32")
+		if options.mode.dryRun {
+			status = "planned"
+		}
+		summary = append(summary, fmt.Sprintf("%s => %s %d, skipped %d", videoFile, status, extracted, skipped))
+	}
+
+	for _, line := range summary {
+		if _, err := fmt.Fprintln(out, line); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(out, "Extracted subtitles from %d of %d video files.\n", len(videoFiles)-failures, len(videoFiles)); err != nil {
+		return err
+	}
+	if failures > 0 {
+		return fmt.Errorf("failed to extract subtitles from %d video files", failures)
+	}
+	return nil
+}
+
+// extractVideoSubtitles extracts the selected streams of videoFile next to it,
+// or into outputDir, and returns how many files were written and skipped.
+func extractVideoSubtitles(out io.Writer, videoFile string, options extractAllOptions) (int, int, error) {
+	streams, err := probeMKVStreams(videoFile)
+	if err != nil {
+		return 0, 0, err
+	}
+	selectedStreams, err := mkv.SelectSubtitleStreamsForExtraction(streams, options.selection)
+	if err != nil {
+		return 0, 0, err
+	}
+	if options.convert != "" {
+		if err := mkv.CheckSubtitleConversion(selectedStreams, options.convert); err != nil {
+			return 0, 0, fmt.Errorf("%w; use --format text to skip image streams", err)
+		}
+	}
+	if err := requireFFmpegForExtraction(selectedStreams); err != nil {
+		return 0, 0, err
+	}
+
+	outputDir := options.outputDir
+	if outputDir == "" {
+		outputDir = filepath.Dir(videoFile)
+	}
+
+	extracted, skipped := 0, 0
+	usedNames := map[string]bool{}
+	for _, stream := range selectedStreams {
+		fileName, err := mkv.RenderExtractFileName(options.nameTemplate, videoFile, stream, options.convert)
+		if err != nil {
+			return extracted, skipped, err
+		}
+		outputPath := filepath.Join(outputDir, mkv.UniqueExtractFileName(fileName, usedNames))
+
+		if _, err := os.Stat(outputPath); err == nil && !options.overwrite {
+			skipped++
+			if _, err := fmt.Fprintf(out, "%s: stream %s -> %s (exists, skipped)\n", videoFile, stream.ID, outputPath); err != nil {
+				return extracted, skipped, err
+			}
+			continue
+		}
+
+		if _, err := fmt.Fprintf(out, "%s: stream %s (lang=%s, format=%s) -> %s\n", videoFile, stream.ID, displayOrEmpty(stream.Language), displayOrEmpty(stream.SubtitleFormat), outputPath); err != nil {
+			return extracted, skipped, err
+		}
+		if err := extractSubtitleStream(options.mode, videoFile, stream, outputPath, options.convert, options.overwrite); err != nil {
+			return extracted, skipped, err
+		}
+		extracted++
+	}
+	return extracted, skipped, nil
+}
+
+// extractSubtitleStream writes stream to outputPath, transcoded to convert
+// when it is not empty, or prints how it would in a dry run. With overwrite,
+// ffmpeg replaces an existing outputPath instead of asking.
+func extractSubtitleStream(mode runMode, fileName string, stream mkvStreamInfo, outputPath, convert string, overwrite bool) error {
+	if convert == "" && mkv.IsVobSubStream(stream) {
+		if mode.dryRun {
+			return mode.printPlan(fmt.Sprintf("# extract VobSub stream %s of %s natively to %s", stream.ID, shellQuote(fileName), shellQuote(outputPath)))
+		}
+		if err := mkv.ExtractVobSub(fileName, stream, outputPath); err != nil {
+			return fmt.Errorf("failed to export stream %s: %w", stream.ID, err)
+		}
+		return nil
+	}
+
+	ffmpegArgs := mkv.BuildExtractFFmpegArgs(fileName, stream, outputPath)
+	if convert != "" {
+		var err error
+		ffmpegArgs, err = mkv.BuildConvertExtractFFmpegArgs(fileName, stream, outputPath, convert)
+		if err != nil {
+			return err
+		}
+	}
+	if overwrite {
+		ffmpegArgs = slices.Insert(ffmpegArgs, 1, "-y")
+	}
+
+	if mode.dryRun {
+		return mode.printPlan(shellCommand("ffmpeg", ffmpegArgs...))
+	}
+	extractOutput, err := mode.runFFmpeg(ffmpegArgs...)
+	if err != nil {
+		return fmt.Errorf("failed to export stream %s: %w: %s", stream.ID, err, strings.TrimSpace(string(extractOutput)))
+	}
+	return nil
+}
+
+// requireFFmpegForExtraction checks for ffmpeg unless every stream is VobSub,
+// which is extracted natively.
+func requireFFmpegForExtraction(streams []mkvStreamInfo) error {
+	for _, stream := range streams {
+		if !mkv.IsVobSubStream(stream) {
+			return mkv.RequireFFmpegInstalled()
+		}
+	}
+	return nil
+}
+
+func listCurrentDirVideoFiles() ([]string, error) {
+	entries, err := os.ReadDir(".")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && mkv.IsVideoContainerFile(entry.Name()) {
+			files = append(files, entry.Name())
+		}
+	}
+	return files, nil
+}
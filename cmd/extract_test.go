@@ -8,6 +8,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/cuimingda/subs-cli/internal/mkv"
 )
 
 func TestExtractCommand_Success_AllSubtitles(t *testing.T) {
@@ -286,8 +288,8 @@ func TestExtractCommand_RejectsNonMkvFile(t *testing.T) {
 	if err == nil {
 		t.Fatalf("expected non-mkv validation error, got nil")
 	}
-	if err.Error() != "file must be an mkv file: sample.txt" {
-		t.Fatalf("error = %q, want file must be an mkv file: sample.txt", err)
+	if err.Error() != "file must be an mkv or mp4 file: sample.txt" {
+		t.Fatalf("error = %q, want file must be an mkv or mp4 file: sample.txt", err)
 	}
 }
 
@@ -335,14 +337,17 @@ func TestExtractCommand_RejectsNoSuchID(t *testing.T) {
 	}
 }
 
+// testPackageDir is captured before any test changes the working directory.
+var testPackageDir, _ = os.Getwd()
+
 func resolveTestMkvPath(t *testing.T) string {
 	t.Helper()
 
 	candidates := []string{
-		filepath.Join("resources", "low_quality_with_subtitles_5s.mkv"),
-		filepath.Join("..", "resources", "low_quality_with_subtitles_5s.mkv"),
-		filepath.Join("cmd", "resources", "low_quality_with_subtitles_5s.mkv"),
-		filepath.Join("..", "cmd", "resources", "low_quality_with_subtitles_5s.mkv"),
+		filepath.Join(testPackageDir, "resources", "low_quality_with_subtitles_5s.mkv"),
+		filepath.Join(testPackageDir, "..", "resources", "low_quality_with_subtitles_5s.mkv"),
+		filepath.Join(testPackageDir, "cmd", "resources", "low_quality_with_subtitles_5s.mkv"),
+		filepath.Join(testPackageDir, "..", "cmd", "resources", "low_quality_with_subtitles_5s.mkv"),
 	}
 
 	for _, candidate := range candidates {
@@ -373,3 +378,268 @@ func copyFile(srcPath, dstPath string) error {
 
 	return nil
 }
+
+func TestExtractCommand_UsesContainerExtensionForImageSubtitles(t *testing.T) {
+	target, runner := writeProbedTestFile(t, "movie.mkv", `Input #0, matroska,webm, from 'movie.mkv':
+  Stream #0:0: Video: h264 (High), yuv420p, 1920x1080 (default)
+  Stream #0:1(eng): Subtitle: hdmv_pgs_subtitle (pgssub), 1920x1080 (default)
+  Stream #0:2(ger): Subtitle: webvtt
+`)
+
+	cmd := NewRootCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"info", target})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("info error = %v", err)
+	}
+	if !strings.Contains(out.String(), "0:1 Subtitle eng pgssub (EMPTY) (default) [image]") {
+		t.Fatalf("info output = %q, want image marker for pgs stream", out.String())
+	}
+	if strings.Contains(out.String(), "webvtt (EMPTY) [image]") {
+		t.Fatalf("info output = %q, webvtt is not an image stream", out.String())
+	}
+
+	cmd = NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"extract", target})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("extract error = %v", err)
+	}
+
+	outDir := filepath.Join(filepath.Dir(target), "movie_subs")
+	for _, name := range []string{"movie_0_1_eng.sup", "movie_0_2_ger.vtt"} {
+		if _, err := os.Stat(filepath.Join(outDir, name)); err != nil {
+			t.Fatalf("expected %s: %v (calls %#v)", name, err, runner.calls)
+		}
+	}
+}
+
+const seasonProbeOutput = `Input #0, matroska,webm, from 'episode.mkv':
+  Stream #0:0: Video: h264 (High), yuv420p, 1920x1080 (default)
+  Stream #0:1(chi): Subtitle: subrip (default)
+  Stream #0:2(eng): Subtitle: subrip
+  Stream #0:3(eng): Subtitle: ass
+    Metadata:
+      title           : Signs
+  Stream #0:4(eng): Subtitle: hdmv_pgs_subtitle (pgssub), 1920x1080
+  Stream #0:5(jpn): Subtitle: subrip
+`
+
+func setupSeasonDir(t *testing.T) *probeFFmpegRunner {
+	t.Helper()
+
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(testPackageDir)
+	})
+
+	for _, name := range []string{"Show.S01E01.mkv", "Show.S01E02.mkv", "notes.txt"} {
+		if err := os.WriteFile(name, []byte("not real media"), 0o644); err != nil {
+			t.Fatalf("write %s failed: %v", name, err)
+		}
+	}
+
+	runner := &probeFFmpegRunner{probeOutput: seasonProbeOutput}
+	mkv.SetFFmpegRunner(runner)
+	t.Cleanup(func() { mkv.SetFFmpegRunner(nil) })
+	return runner
+}
+
+func TestExtractCommand_AllWritesNextToVideosAndSkipsExisting(t *testing.T) {
+	setupSeasonDir(t)
+	if err := os.WriteFile("Show.S01E02.chi.srt", []byte("existing"), 0o644); err != nil {
+		t.Fatalf("write existing subtitle failed: %v", err)
+	}
+
+	cmd := NewRootCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"extract", "--all", "--language", "chi,eng", "--format", "text"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	for _, name := range []string{
+		"Show.S01E01.chi.srt",
+		"Show.S01E01.eng.srt",
+		"Show.S01E01.eng.ass",
+		"Show.S01E02.eng.srt",
+		"Show.S01E02.eng.ass",
+	} {
+		if _, err := os.Stat(name); err != nil {
+			t.Fatalf("expected %s: %v", name, err)
+		}
+	}
+	for _, name := range []string{"Show.S01E01.eng.sup", "Show.S01E01.jpn.srt"} {
+		if _, err := os.Stat(name); err == nil {
+			t.Fatalf("%s should not be extracted", name)
+		}
+	}
+	if data, err := os.ReadFile("Show.S01E02.chi.srt"); err != nil || string(data) != "existing" {
+		t.Fatalf("existing subtitle = %q, %v, want it untouched", data, err)
+	}
+
+	output := out.String()
+	for _, want := range []string{
+		"Show.S01E02.chi.srt (exists, skipped)",
+		"Show.S01E01.mkv => " + colorize("extracted", "32") + " 3, skipped 0",
+		"Show.S01E02.mkv => " + colorize("extracted", "32") + " 2, skipped 1",
+		"Extracted subtitles from 2 of 2 video files.",
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("output = %q, want contains %q", output, want)
+		}
+	}
+}
+
+func TestExtractCommand_AllTemplateAndOverwrite(t *testing.T) {
+	runner := setupSeasonDir(t)
+	if err := os.WriteFile("Show.S01E01.jpn.srt", []byte("existing"), 0o644); err != nil {
+		t.Fatalf("write existing subtitle failed: %v", err)
+	}
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"extract", "--all", "--language", "jpn,eng", "--format", "ass", "--template", "{base}.{lang}.{title}.{ext}"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+	if _, err := os.Stat("Show.S01E01.eng.Signs.ass"); err != nil {
+		t.Fatalf("expected templated ass file: %v", err)
+	}
+
+	runner.calls = nil
+	cmd = NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"extract", "--all", "--language", "jpn", "--overwrite"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+	if data, err := os.ReadFile("Show.S01E01.jpn.srt"); err != nil || string(data) != "remuxed" {
+		t.Fatalf("overwritten subtitle = %q, %v, want new extraction", data, err)
+	}
+
+	extractCalls := 0
+	for _, call := range runner.calls {
+		if call[len(call)-1] != "Show.S01E01.jpn.srt" && call[len(call)-1] != "Show.S01E02.jpn.srt" {
+			continue
+		}
+		extractCalls++
+		if len(call) < 2 || call[0] != "-hide_banner" || call[1] != "-y" {
+			t.Fatalf("ffmpeg args = %v, want -y to overwrite", call)
+		}
+	}
+	if extractCalls == 0 {
+		t.Fatalf("ffmpeg calls = %v, want jpn extractions", runner.calls)
+	}
+}
+
+func TestExtractCommand_AllRejectsInvalidUsage(t *testing.T) {
+	setupSeasonDir(t)
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"extract", "--all", "Show.S01E01.mkv"}, want: "unknown command"},
+		{args: []string{"extract", "--all", "--id", "2"}, want: "--id cannot be used with --all"},
+		{args: []string{"extract", "--all", "--template", "{base}.{season}.{ext}"}, want: "unknown placeholder {season}"},
+		{args: []string{"extract", "--all", "--language", "english"}, want: "invalid language tag: english"},
+		{args: []string{"extract", "Show.S01E01.mkv", "--overwrite"}, want: "require --all"},
+	}
+	for _, tt := range tests {
+		cmd := NewRootCmd()
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs(tt.args)
+		err := cmd.Execute()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%v: error = %v, want contains %q", tt.args, err, tt.want)
+		}
+	}
+}
+
+func TestExtractCommand_ConvertTranscodesTextStreams(t *testing.T) {
+	target, runner := writeProbedTestFile(t, "movie.mkv", `Input #0, matroska,webm, from 'movie.mkv':
+  Stream #0:0: Video: h264 (High), yuv420p, 1920x1080 (default)
+  Stream #0:1(eng): Subtitle: ass (default)
+  Stream #0:2(ger): Subtitle: hdmv_pgs_subtitle (pgssub), 1920x1080
+`)
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"extract", "--convert", "srt", target})
+	err := cmd.Execute()
+	if err == nil || err.Error() != "stream 0:2 is an image-based subtitle (pgssub) and cannot be converted to srt" {
+		t.Fatalf("error = %v, want image conversion error", err)
+	}
+	if len(runner.calls) != 1 {
+		t.Fatalf("calls = %#v, want only the stream probe", runner.calls)
+	}
+
+	cmd = NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"extract", "--convert", "SRT", "--id", "1", target})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	outputPath := filepath.Join(filepath.Dir(target), "movie_subs", "movie_0_1_eng.srt")
+	if _, err := os.Stat(outputPath); err != nil {
+		t.Fatalf("expected converted file: %v", err)
+	}
+	lastCall := strings.Join(runner.calls[len(runner.calls)-1], " ")
+	if !strings.Contains(lastCall, "-map 0:1 -c srt "+outputPath) {
+		t.Fatalf("ffmpeg args = %q, want srt transcoding", lastCall)
+	}
+}
+
+func TestExtractCommand_AllConvertRejectsImageStreams(t *testing.T) {
+	setupSeasonDir(t)
+
+	cmd := NewRootCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"extract", "--all", "--convert", "vtt"})
+	if err := cmd.Execute(); err == nil {
+		t.Fatal("expected conversion failure for pgs streams")
+	}
+	if !strings.Contains(out.String(), "cannot be converted to vtt; use --format text to skip image streams") {
+		t.Fatalf("output = %q, want image stream hint", out.String())
+	}
+
+	cmd = NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"extract", "--all", "--convert", "vtt", "--format", "text", "--language", "eng"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+	for _, name := range []string{"Show.S01E01.eng.vtt", "Show.S01E01.eng.2.vtt"} {
+		if _, err := os.Stat(name); err != nil {
+			t.Fatalf("expected %s: %v", name, err)
+		}
+	}
+}
+
+func TestExtractCommand_RejectsUnknownConversion(t *testing.T) {
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"extract", "--convert", "sup", "movie.mkv"})
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "unsupported conversion format: sup") {
+		t.Fatalf("error = %v, want unsupported conversion format", err)
+	}
+}
@@ -23,86 +23,48 @@ func NewFileCmd() *cobra.Command {
 		},
 	}
 
+	var searchPatterns []string
+	var searchMinScore float64
 	fileSearchCmd := &cobra.Command{
 		Use:   "search",
-		Short: "Search for current directory videos that match subtitle episode tags",
+		Short: "Search for current directory videos that match subtitle episode tags or titles",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			subtitleFiles, err := subtitles.ListCurrentDirSubtitleFiles()
-			if err != nil {
+			if err := validateMinScore(searchMinScore); err != nil {
 				return err
 			}
 
-			for _, subtitleFile := range subtitleFiles {
-				ignore := colorize("ignore", "31")
-				notFound := colorize("not found", "31")
-
-				episodeTag, ok := subtitles.ExtractEpisodeTag(subtitleFile)
-				if !ok {
-					if _, err := fmt.Fprintf(cmd.OutOrStdout(), "%s => %s\n", subtitleFile, ignore); err != nil {
-						return err
-					}
-					continue
-				}
-
-				videoFile, err := subtitles.FindVideoFileByEpisodeTag(episodeTag)
-				if err != nil {
-					return err
-				}
-
-				if videoFile == "" {
-					if _, err := fmt.Fprintf(cmd.OutOrStdout(), "%s => %s\n", subtitleFile, notFound); err != nil {
-						return err
-					}
-					continue
-				}
-
-				subtitleBase := strings.TrimSuffix(subtitleFile, filepath.Ext(subtitleFile))
-				videoBase := strings.TrimSuffix(videoFile, filepath.Ext(videoFile))
-				if subtitleBase == videoBase {
-					suffix := colorize("(same)", "32")
-					if _, err := fmt.Fprintf(cmd.OutOrStdout(), "%s => %s %s\n", subtitleFile, videoFile, suffix); err != nil {
-						return err
-					}
-					continue
-				}
-
-				if _, err := fmt.Fprintf(cmd.OutOrStdout(), "%s => %s (found)\n", subtitleFile, videoFile); err != nil {
-					return err
-				}
+			matchers, err := subtitles.DefaultEpisodeMatchers().WithPatterns(searchPatterns)
+			if err != nil {
+				return err
 			}
 
-			return nil
-		},
-	}
-
-	fileRenameCmd := &cobra.Command{
-		Use:   "rename",
-		Short: "Rename subtitle files according to matching video files",
-		Args:  cobra.NoArgs,
-		RunE: func(cmd *cobra.Command, args []string) error {
 			subtitleFiles, err := subtitles.ListCurrentDirSubtitleFiles()
 			if err != nil {
 				return err
 			}
 
+			titleMatches, err := subtitles.MatchCurrentDirTitles(subtitleFiles, matchers, searchMinScore)
+			if err != nil {
+				return err
+			}
+
 			for _, subtitleFile := range subtitleFiles {
 				ignore := colorize("ignore", "31")
 				notFound := colorize("not found", "31")
 
-				episodeTag, ok := subtitles.ExtractEpisodeTag(subtitleFile)
-				if !ok {
+				videoFile, titleMatch, ignored, err := findSubtitleVideo(subtitleFile, matchers, titleMatches)
+				if err != nil {
+					return err
+				}
+
+				if ignored {
 					if _, err := fmt.Fprintf(cmd.OutOrStdout(), "%s => %s\n", subtitleFile, ignore); err != nil {
 						return err
 					}
 					continue
 				}
 
-				videoFile, err := subtitles.FindVideoFileByEpisodeTag(episodeTag)
-				if err != nil {
-					return err
-				}
-
 				if videoFile == "" {
 					if _, err := fmt.Fprintf(cmd.OutOrStdout(), "%s => %s\n", subtitleFile, notFound); err != nil {
 						return err
@@ -120,12 +82,11 @@ func NewFileCmd() *cobra.Command {
 					continue
 				}
 
-				newName := videoBase + filepath.Ext(subtitleFile)
-				if err := os.Rename(subtitleFile, newName); err != nil {
-					return err
+				status := "found"
+				if titleMatch != nil {
+					status = fmt.Sprintf("found, score %.2f", titleMatch.Score)
 				}
-
-				if _, err := fmt.Fprintf(cmd.OutOrStdout(), "%s => %s (renamed)\n", subtitleFile, newName); err != nil {
+				if _, err := fmt.Fprintf(cmd.OutOrStdout(), "%s => %s (%s)\n", subtitleFile, videoFile, status); err != nil {
 					return err
 				}
 			}
@@ -144,6 +105,16 @@ func NewFileCmd() *cobra.Command {
 				return err
 			}
 
+			mode := newRunMode(cmd)
+			if mode.dryRun {
+				for _, subtitleFile := range subtitleFiles {
+					if err := mode.printPlan("# move " + shellQuote(subtitleFile) + " to the trash"); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+
 			confirmed, err := confirmAction(cmd.InOrStdin(), cmd.ErrOrStderr(), "This will remove all subtitle files in current directory (srt/ass). Continue?")
 			if err != nil {
 				return err
@@ -162,13 +133,44 @@ func NewFileCmd() *cobra.Command {
 		},
 	}
 
+	addEpisodePatternFlag(fileSearchCmd, &searchPatterns)
+	fileSearchCmd.Flags().Float64Var(&searchMinScore, "min-score", subtitles.DefaultTitleMinScore, "Lowest title similarity (0-1) for files without an episode tag")
+
 	fileCmd.AddCommand(fileSearchCmd)
-	fileCmd.AddCommand(fileRenameCmd)
+	fileCmd.AddCommand(NewFileRenameCmd())
 	fileCmd.AddCommand(fileRmCmd)
 
 	return fileCmd
 }
 
+func addEpisodePatternFlag(cmd *cobra.Command, patterns *[]string) {
+	cmd.Flags().StringArrayVar(patterns, "pattern", nil, "Regular expression with (?P<episode>...) and optional (?P<season>...) groups, tried before the built-in episode formats (repeatable)")
+}
+
+// findSubtitleVideo returns the video of subtitleFile found by episode, or by
+// title when the name has no episode tag. ignored is set when neither
+// applies.
+func findSubtitleVideo(subtitleFile string, matchers subtitles.EpisodeMatchers, titleMatches map[string]subtitles.TitleMatch) (string, *subtitles.TitleMatch, bool, error) {
+	episodeKey, ok := matchers.Match(subtitleFile)
+	if !ok {
+		match, found := titleMatches[subtitleFile]
+		if !found {
+			return "", nil, true, nil
+		}
+		return match.Video, &match, false, nil
+	}
+
+	videoFile, err := subtitles.FindVideoFileByEpisode(episodeKey, matchers)
+	return videoFile, nil, false, err
+}
+
+func validateMinScore(score float64) error {
+	if score < 0 || score > 1 {
+		return fmt.Errorf("invalid --min-score %v: want a value between 0 and 1", score)
+	}
+	return nil
+}
+
 func colorize(text, color string) string {
 	return "\x1b[" + color + "m" + text + "\x1b[0m"
 }
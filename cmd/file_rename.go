@@ -0,0 +1,329 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cuimingda/subs-cli/internal/journal"
+	"github.com/cuimingda/subs-cli/internal/subtitles"
+
+	"github.com/spf13/cobra"
+)
+
+type fileRenameOptions struct {
+	patterns    []string
+	minScore    float64
+	scheme      string
+	template    string
+	interactive bool
+	exportPlan  string
+	applyPlan   string
+}
+
+func NewFileRenameCmd() *cobra.Command {
+	options := fileRenameOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "rename",
+		Short: "Rename subtitle files according to matching video files",
+		Args:  cobra.NoArgs,
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			mode := newRunMode(cobraCmd)
+
+			var plan []subtitles.RenamePlanEntry
+			var err error
+			if options.applyPlan != "" {
+				plan, err = readRenamePlanFile(options.applyPlan)
+				if err == nil {
+					err = subtitles.CheckRenamePlan(plan)
+				}
+			} else {
+				plan, err = buildRenamePlan(options, cobraCmd.Flags().Changed("min-score"))
+			}
+			if err != nil {
+				return err
+			}
+
+			if options.exportPlan != "" {
+				if err := printRenamePlanTable(mode.out, plan); err != nil {
+					return err
+				}
+				if err := writeRenamePlanFile(options.exportPlan, plan); err != nil {
+					return err
+				}
+				_, err := fmt.Fprintf(mode.out, "Wrote plan of %d file(s) to %s\n", len(plan), options.exportPlan)
+				return err
+			}
+
+			if mode.dryRun || options.interactive {
+				if err := printRenamePlanTable(mode.out, plan); err != nil {
+					return err
+				}
+				if mode.dryRun {
+					return nil
+				}
+			}
+
+			return runJournaled(cobraCmd, func(entry *journal.Journal) error {
+				return applyRenamePlan(cobraCmd, entry, plan, options.interactive)
+			})
+		},
+	}
+
+	addEpisodePatternFlag(cmd, &options.patterns)
+	cmd.Flags().StringVar(&options.scheme, "scheme", string(subtitles.SchemePlex), "Subtitle naming scheme: plex, jellyfin or kodi")
+	cmd.Flags().Float64Var(&options.minScore, "min-score", 0, "Rename files without an episode tag whose title similarity (0-1) is at least this")
+	cmd.Flags().StringVar(&options.template, "template", "", "New name template, e.g. Subs/{video_base}{.lang}{.forced}.{ext}")
+	cmd.Flags().BoolVar(&options.interactive, "interactive", false, "Show the plan and confirm each rename")
+	cmd.Flags().StringVar(&options.exportPlan, "export-plan", "", "Write the plan to a .csv or .json file instead of renaming")
+	cmd.Flags().StringVar(&options.applyPlan, "apply-plan", "", "Rename exactly as in a plan written by --export-plan")
+	for _, flag := range []string{"pattern", "scheme", "min-score", "template", "export-plan"} {
+		cmd.MarkFlagsMutuallyExclusive("apply-plan", flag)
+	}
+
+	return cmd
+}
+
+// buildRenamePlan matches every subtitle in the current directory to a video
+// and picks its new name, without touching any file. renameTitles is set
+// when the user chose a --min-score, which title matches need.
+func buildRenamePlan(options fileRenameOptions, renameTitles bool) ([]subtitles.RenamePlanEntry, error) {
+	scheme, err := subtitles.ParseNamingScheme(options.scheme)
+	if err != nil {
+		return nil, err
+	}
+	if err := subtitles.ValidateSubtitleTemplate(options.template); err != nil {
+		return nil, err
+	}
+
+	minScore := subtitles.DefaultTitleMinScore
+	if renameTitles {
+		if err := validateMinScore(options.minScore); err != nil {
+			return nil, err
+		}
+		minScore = options.minScore
+	}
+
+	matchers, err := subtitles.DefaultEpisodeMatchers().WithPatterns(options.patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	subtitleFiles, err := subtitles.ListCurrentDirSubtitleFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	titleMatches, err := subtitles.MatchCurrentDirTitles(subtitleFiles, matchers, minScore)
+	if err != nil {
+		return nil, err
+	}
+
+	// Names are taken by files that stay in place and by earlier renames.
+	planned := map[string]bool{}
+	vacated := map[string]bool{}
+	taken := func(self string) func(string) bool {
+		return func(name string) bool {
+			if name == self {
+				return false
+			}
+			if planned[name] {
+				return true
+			}
+			_, err := os.Lstat(name)
+			return err == nil && !vacated[name]
+		}
+	}
+
+	var plan []subtitles.RenamePlanEntry
+	for _, subtitleFile := range subtitleFiles {
+		videoFile, titleMatch, ignored, err := findSubtitleVideo(subtitleFile, matchers, titleMatches)
+		if err != nil {
+			return nil, err
+		}
+
+		entry := subtitles.RenamePlanEntry{Subtitle: subtitleFile, Video: videoFile}
+		switch {
+		case ignored:
+			entry.Action = subtitles.RenameActionIgnore
+		case videoFile == "":
+			entry.Action = subtitles.RenameActionNotFound
+		}
+		if entry.Action != "" {
+			plan = append(plan, entry)
+			continue
+		}
+
+		episode := ""
+		if titleMatch != nil {
+			entry.Score = titleMatch.Score
+		} else if key, ok := matchers.Match(subtitleFile); ok {
+			episode = key.String()
+		}
+
+		markers := subtitles.DetectSubtitleMarkers(subtitleFile)
+		schemeName := scheme.SubtitleName(videoFile, subtitleFile, markers)
+		if options.template != "" {
+			values := subtitles.SubtitleTemplateValues(scheme, videoFile, subtitleFile, markers, episode)
+			schemeName, err = subtitles.RenderSubtitleTemplate(options.template, values)
+			if err != nil {
+				return nil, err
+			}
+		}
+		entry.NewName = subtitles.NumberSubtitleName(schemeName, videoFile, taken(subtitleFile))
+
+		switch {
+		case entry.NewName == subtitleFile:
+			entry.Action = subtitles.RenameActionSame
+			entry.NewName = ""
+		case titleMatch != nil && !renameTitles:
+			entry.Action = subtitles.RenameActionNeedsMinScore
+		default:
+			entry.Action = subtitles.RenameActionRename
+			planned[entry.NewName] = true
+			vacated[subtitleFile] = true
+			if entry.NewName != schemeName {
+				entry.Note = schemeName + " exists"
+			}
+		}
+
+		if entry.Action != subtitles.RenameActionSame {
+			if entry.SHA256, err = subtitles.FileSHA256(subtitleFile); err != nil {
+				return nil, err
+			}
+		}
+		plan = append(plan, entry)
+	}
+
+	return plan, nil
+}
+
+// applyRenamePlan makes the renames of plan in order and prints one line per
+// subtitle. With interactive, each rename is confirmed first.
+func applyRenamePlan(cmd *cobra.Command, entry *journal.Journal, plan []subtitles.RenamePlanEntry, interactive bool) error {
+	out := cmd.OutOrStdout()
+	in := bufio.NewReader(cmd.InOrStdin())
+
+	for _, planned := range plan {
+		var line string
+		switch planned.Action {
+		case subtitles.RenameActionIgnore:
+			line = fmt.Sprintf("%s => %s", planned.Subtitle, colorize("ignore", "31"))
+		case subtitles.RenameActionNotFound:
+			line = fmt.Sprintf("%s => %s", planned.Subtitle, colorize("not found", "31"))
+		case subtitles.RenameActionSame:
+			line = fmt.Sprintf("%s => %s %s", planned.Subtitle, planned.Video, colorize("(same)", "32"))
+		case subtitles.RenameActionNeedsMinScore:
+			line = fmt.Sprintf("%s => %s (score %.2f, pass --min-score to rename)", planned.Subtitle, planned.Video, planned.Score)
+		case subtitles.RenameActionRename:
+			renamed, err := applyPlannedRename(cmd, in, entry, planned, interactive)
+			if err != nil {
+				return err
+			}
+			status := "skipped"
+			if renamed {
+				status = renameStatus(planned)
+			}
+			line = fmt.Sprintf("%s => %s (%s)", planned.Subtitle, planned.NewName, status)
+		}
+
+		if _, err := fmt.Fprintln(out, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func applyPlannedRename(cmd *cobra.Command, in io.Reader, entry *journal.Journal, planned subtitles.RenamePlanEntry, interactive bool) (bool, error) {
+	if interactive {
+		confirmed, err := confirmAction(in, cmd.ErrOrStderr(), fmt.Sprintf("Rename %s to %s?", planned.Subtitle, planned.NewName))
+		if err != nil || !confirmed {
+			return false, err
+		}
+	}
+
+	if _, err := os.Lstat(planned.NewName); err == nil {
+		return false, fmt.Errorf("%s already exists", planned.NewName)
+	}
+	if dir := filepath.Dir(planned.NewName); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return false, err
+		}
+	}
+	if err := os.Rename(planned.Subtitle, planned.NewName); err != nil {
+		return false, err
+	}
+	return true, entry.RecordRename(planned.Subtitle, planned.NewName)
+}
+
+func renameStatus(planned subtitles.RenamePlanEntry) string {
+	parts := []string{"renamed"}
+	if planned.Score > 0 {
+		parts = append(parts, fmt.Sprintf("score %.2f", planned.Score))
+	}
+	if planned.Note != "" {
+		parts = append(parts, planned.Note)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func printRenamePlanTable(out io.Writer, plan []subtitles.RenamePlanEntry) error {
+	table := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(table, "ACTION\tSUBTITLE\tNEW NAME\tNOTE"); err != nil {
+		return err
+	}
+	for _, planned := range plan {
+		newName, note := planned.NewName, ""
+		switch planned.Action {
+		case subtitles.RenameActionSame:
+			note = planned.Video
+		case subtitles.RenameActionNeedsMinScore:
+			newName = "-"
+			note = fmt.Sprintf("score %.2f, pass --min-score to rename", planned.Score)
+		case subtitles.RenameActionRename:
+			note = strings.TrimPrefix(strings.TrimPrefix(renameStatus(planned), "renamed"), ", ")
+		}
+		if newName == "" {
+			newName = "-"
+		}
+		if _, err := fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", planned.Action, planned.Subtitle, newName, note); err != nil {
+			return err
+		}
+	}
+	return table.Flush()
+}
+
+func writeRenamePlanFile(path string, plan []subtitles.RenamePlanEntry) error {
+	format, err := subtitles.RenamePlanFormat(path)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := subtitles.WriteRenamePlan(file, format, plan); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func readRenamePlanFile(path string) ([]subtitles.RenamePlanEntry, error) {
+	format, err := subtitles.RenamePlanFormat(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return subtitles.ReadRenamePlan(file, format)
+}
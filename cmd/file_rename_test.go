@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -105,3 +106,449 @@ func TestFileRenameCommand_NoSubtitleFiles(t *testing.T) {
 		t.Fatalf("expected ErrNoSubtitleFiles, got %v", err)
 	}
 }
+
+func TestFileRenameCommand_TitleMatchesNeedMinScore(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(testPackageDir)
+	})
+
+	for _, name := range []string{"Arrival.chs.srt", "Arrival.2016.1080p.WEB-DL.mkv"} {
+		if err := os.WriteFile(name, []byte("x"), 0o644); err != nil {
+			t.Fatalf("write %s failed: %v", name, err)
+		}
+	}
+
+	var out bytes.Buffer
+	cmd := NewRootCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"file", "rename"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "Arrival.chs.srt => Arrival.2016.1080p.WEB-DL.mkv (score 1.00, pass --min-score to rename)") {
+		t.Fatalf("output = %q, want min-score hint", out.String())
+	}
+	if _, err := os.Stat("Arrival.chs.srt"); err != nil {
+		t.Fatalf("subtitle should not be renamed without --min-score: %v", err)
+	}
+
+	out.Reset()
+	cmd = NewRootCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"file", "rename", "--min-score", "0.8"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "Arrival.chs.srt => Arrival.2016.1080p.WEB-DL.zh-Hans.srt (renamed, score 1.00)") {
+		t.Fatalf("output = %q, want title rename", out.String())
+	}
+	if _, err := os.Stat("Arrival.2016.1080p.WEB-DL.zh-Hans.srt"); err != nil {
+		t.Fatalf("expected renamed subtitle: %v", err)
+	}
+}
+
+func TestFileRenameCommand_RejectsInvalidMinScore(t *testing.T) {
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"file", "rename", "--min-score", "1.5"})
+
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "invalid --min-score") {
+		t.Fatalf("cmd.Execute() error = %v, want invalid --min-score", err)
+	}
+}
+
+func TestFileRenameCommand_KeepsMarkersAndNumbersCollisions(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(testPackageDir)
+	})
+
+	files := map[string]string{
+		"Show.S01E02.mkv":         "x",
+		"Show.S01E02.en.srt":      "existing",
+		"a.S01E02.eng.srt":        "first",
+		"b.S01E02.en.srt":         "second",
+		"c.S01E02.chs.forced.srt": "third",
+		"d.S01E02.srt":            "1\n00:00:01,000 --> 00:00:02,000\n这是什么\n",
+		"Show.S01E02.zh-Hant.srt": "already named",
+		"e.S01E02.kodi.sdh.ass":   "unknown marker",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s failed: %v", name, err)
+		}
+	}
+
+	var out bytes.Buffer
+	cmd := NewRootCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"file", "rename"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	output := out.String()
+	for _, want := range []string{
+		"Show.S01E02.en.srt => Show.S01E02.mkv \x1b[32m(same)\x1b[0m",
+		"a.S01E02.eng.srt => Show.S01E02.2.en.srt (renamed, Show.S01E02.en.srt exists)",
+		"b.S01E02.en.srt => Show.S01E02.3.en.srt (renamed, Show.S01E02.en.srt exists)",
+		"c.S01E02.chs.forced.srt => Show.S01E02.zh-Hans.forced.srt (renamed)",
+		"d.S01E02.srt => Show.S01E02.zh-Hans.srt (renamed)",
+		"e.S01E02.kodi.sdh.ass => Show.S01E02.sdh.ass (renamed)",
+		"Show.S01E02.zh-Hant.srt => Show.S01E02.mkv \x1b[32m(same)\x1b[0m",
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("output = %q, want contains %q", output, want)
+		}
+	}
+
+	for name, want := range map[string]string{
+		"Show.S01E02.en.srt":   "existing",
+		"Show.S01E02.2.en.srt": "first",
+		"Show.S01E02.3.en.srt": "second",
+	} {
+		content, err := os.ReadFile(name)
+		if err != nil || string(content) != want {
+			t.Fatalf("%s = %q, %v; want %q", name, content, err, want)
+		}
+	}
+}
+
+func TestFileRenameCommand_JellyfinAndKodiSchemes(t *testing.T) {
+	for scheme, want := range map[string]string{
+		"jellyfin": "Show.S01E02.eng.sdh.srt",
+		"kodi":     "Show.S01E02.English.sdh.srt",
+	} {
+		tmpDir := t.TempDir()
+		if err := os.Chdir(tmpDir); err != nil {
+			t.Fatalf("chdir failed: %v", err)
+		}
+		t.Cleanup(func() {
+			_ = os.Chdir(testPackageDir)
+		})
+
+		for _, name := range []string{"Show.S01E02.mkv", "sub.S01E02.en.hi.srt"} {
+			if err := os.WriteFile(name, []byte("x"), 0o644); err != nil {
+				t.Fatalf("write %s failed: %v", name, err)
+			}
+		}
+
+		cmd := NewRootCmd()
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs([]string{"file", "rename", "--scheme", scheme})
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("cmd.Execute() error = %v", err)
+		}
+		if _, err := os.Stat(want); err != nil {
+			t.Fatalf("%s: expected %s: %v", scheme, want, err)
+		}
+	}
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"file", "rename", "--scheme", "emby"})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "invalid naming scheme") {
+		t.Fatalf("cmd.Execute() error = %v, want invalid naming scheme", err)
+	}
+}
+
+func TestFileRenameCommand_TemplateMovesIntoFolder(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(testPackageDir)
+	})
+
+	for _, name := range []string{"Show.S01E02.mkv", "x.S01E02.chs.forced.srt", "y.S01E02.ass"} {
+		if err := os.WriteFile(name, []byte("x"), 0o644); err != nil {
+			t.Fatalf("write %s failed: %v", name, err)
+		}
+	}
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"file", "rename", "--template", "Subs/{video_base}.{lang}{.forced}.{ext}"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	for _, want := range []string{
+		filepath.Join("Subs", "Show.S01E02.zh-Hans.forced.srt"),
+		filepath.Join("Subs", "Show.S01E02.ass"),
+	} {
+		if _, err := os.Stat(want); err != nil {
+			t.Fatalf("expected %s: %v", want, err)
+		}
+	}
+}
+
+func TestFileRenameCommand_RejectsUnknownTemplateField(t *testing.T) {
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"file", "rename", "--template", "{video_base}.{language}.{ext}"})
+
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "unknown template field {language}") {
+		t.Fatalf("cmd.Execute() error = %v, want unknown template field", err)
+	}
+}
+
+func TestFileRenameCommand_DryRunPrintsPlanTable(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(testPackageDir)
+	})
+
+	for _, name := range []string{"Show.S01E02.mkv", "sub.S01E02.en.srt", "notes.srt"} {
+		if err := os.WriteFile(name, []byte("x"), 0o644); err != nil {
+			t.Fatalf("write %s failed: %v", name, err)
+		}
+	}
+
+	var out bytes.Buffer
+	cmd := NewRootCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"--dry-run", "file", "rename"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	want := "ACTION  SUBTITLE           NEW NAME            NOTE\n" +
+		"ignore  notes.srt          -                   \n" +
+		"rename  sub.S01E02.en.srt  Show.S01E02.en.srt  \n"
+	if out.String() != want {
+		t.Fatalf("output = %q, want %q", out.String(), want)
+	}
+	if _, err := os.Stat("sub.S01E02.en.srt"); err != nil {
+		t.Fatalf("dry run should not rename: %v", err)
+	}
+}
+
+func TestFileRenameCommand_InteractiveConfirmsEachRename(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(testPackageDir)
+	})
+
+	for _, name := range []string{"Show.S01E01.mkv", "Show.S01E02.mkv", "a.S01E01.srt", "b.S01E02.srt"} {
+		if err := os.WriteFile(name, []byte("x"), 0o644); err != nil {
+			t.Fatalf("write %s failed: %v", name, err)
+		}
+	}
+
+	var out bytes.Buffer
+	cmd := NewRootCmd()
+	cmd.SetIn(strings.NewReader("y\nn\n"))
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"file", "rename", "--interactive"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	output := out.String()
+	for _, want := range []string{
+		"ACTION  SUBTITLE",
+		"a.S01E01.srt => Show.S01E01.srt (renamed)",
+		"b.S01E02.srt => Show.S01E02.srt (skipped)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("output = %q, want contains %q", output, want)
+		}
+	}
+	if _, err := os.Stat("b.S01E02.srt"); err != nil {
+		t.Fatalf("declined rename should keep the file: %v", err)
+	}
+}
+
+func TestFileRenameCommand_ExportAndApplyPlan(t *testing.T) {
+	for _, planFile := range []string{"plan.csv", "plan.json"} {
+		tmpDir := t.TempDir()
+		if err := os.Chdir(tmpDir); err != nil {
+			t.Fatalf("chdir failed: %v", err)
+		}
+		t.Cleanup(func() {
+			_ = os.Chdir(testPackageDir)
+		})
+
+		for _, name := range []string{"Show.S01E01.mkv", "Show.S01E02.mkv", "a.S01E01.srt", "b.S01E02.srt"} {
+			if err := os.WriteFile(name, []byte(name), 0o644); err != nil {
+				t.Fatalf("write %s failed: %v", name, err)
+			}
+		}
+
+		var out bytes.Buffer
+		cmd := NewRootCmd()
+		cmd.SetOut(&out)
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs([]string{"file", "rename", "--template", "{video_base}.reviewed.{ext}", "--export-plan", planFile})
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("%s: export error = %v", planFile, err)
+		}
+		if !strings.Contains(out.String(), "Wrote plan of 2 file(s) to "+planFile) {
+			t.Fatalf("%s: output = %q, want export summary", planFile, out.String())
+		}
+		if _, err := os.Stat("a.S01E01.srt"); err != nil {
+			t.Fatalf("%s: export should not rename: %v", planFile, err)
+		}
+
+		// A video added after the review must not change what is applied.
+		if err := os.WriteFile("Show.S01E01.extended.mkv", []byte("x"), 0o644); err != nil {
+			t.Fatalf("write video failed: %v", err)
+		}
+
+		cmd = NewRootCmd()
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs([]string{"file", "rename", "--apply-plan", planFile})
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("%s: apply error = %v", planFile, err)
+		}
+		for _, want := range []string{"Show.S01E01.reviewed.srt", "Show.S01E02.reviewed.srt"} {
+			if _, err := os.Stat(want); err != nil {
+				t.Fatalf("%s: expected %s: %v", planFile, want, err)
+			}
+		}
+	}
+}
+
+func TestFileRenameCommand_ApplyPlanRefusesChangedSubtitle(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(testPackageDir)
+	})
+
+	for _, name := range []string{"Show.S01E01.mkv", "Show.S01E02.mkv", "a.S01E01.srt", "b.S01E02.srt"} {
+		if err := os.WriteFile(name, []byte(name), 0o644); err != nil {
+			t.Fatalf("write %s failed: %v", name, err)
+		}
+	}
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"file", "rename", "--export-plan", "plan.csv"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("export error = %v", err)
+	}
+
+	if err := os.WriteFile("b.S01E02.srt", []byte("edited"), 0o644); err != nil {
+		t.Fatalf("edit subtitle failed: %v", err)
+	}
+
+	cmd = NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"file", "rename", "--apply-plan", "plan.csv"})
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "b.S01E02.srt changed since the plan was made") {
+		t.Fatalf("apply error = %v, want changed subtitle refused", err)
+	}
+	if _, err := os.Stat("a.S01E01.srt"); err != nil {
+		t.Fatalf("nothing should be renamed when the plan is refused: %v", err)
+	}
+}
+
+func TestFileRenameCommand_ApplyPlanWithChainedRenames(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	tmpDir := t.TempDir()
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(testPackageDir)
+	})
+
+	// Show.S01E01.srt moves to its zh-Hans name, then b.S01E01.srt takes
+	// the name it frees.
+	files := map[string]string{
+		"Show.S01E01.mkv": "x",
+		"Show.S01E01.srt": "1\n00:00:01,000 --> 00:00:02,000\n我们走吧，这是什么？\n",
+		"b.S01E01.srt":    "1\n00:00:01,000 --> 00:00:02,000\nHello.\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s failed: %v", name, err)
+		}
+	}
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"file", "rename", "--export-plan", "plan.csv"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("export error = %v", err)
+	}
+	plan, err := os.ReadFile("plan.csv")
+	if err != nil {
+		t.Fatalf("read plan failed: %v", err)
+	}
+	for _, want := range []string{"rename,Show.S01E01.srt,Show.S01E01.mkv,Show.S01E01.zh-Hans.srt", "rename,b.S01E01.srt,Show.S01E01.mkv,Show.S01E01.srt"} {
+		if !strings.Contains(string(plan), want) {
+			t.Fatalf("plan = %q, want contains %q", plan, want)
+		}
+	}
+
+	cmd = NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"file", "rename", "--apply-plan", "plan.csv"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("apply error = %v", err)
+	}
+	for name, want := range map[string]string{
+		"Show.S01E01.zh-Hans.srt": files["Show.S01E01.srt"],
+		"Show.S01E01.srt":         files["b.S01E01.srt"],
+	} {
+		got, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("read %s failed: %v", name, err)
+		}
+		if string(got) != want {
+			t.Fatalf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	cmd = NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"undo"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("undo error = %v", err)
+	}
+	for name, want := range files {
+		got, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("read %s after undo failed: %v", name, err)
+		}
+		if string(got) != want {
+			t.Fatalf("%s after undo = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -148,3 +148,41 @@ func TestFileRmCommand_NoSubtitleFiles(t *testing.T) {
 		t.Fatalf("expected ErrNoSubtitleFiles, got %v", err)
 	}
 }
+
+func TestFileRmCommand_DryRunKeepsFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(testPackageDir)
+	})
+
+	home := filepath.Join(tmpDir, "home")
+	if err := os.Mkdir(home, 0o755); err != nil {
+		t.Fatalf("mkdir home failed: %v", err)
+	}
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+
+	if err := os.WriteFile("a.srt", []byte("x"), 0o644); err != nil {
+		t.Fatalf("write a.srt failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	cmd := NewRootCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetIn(strings.NewReader("y\n"))
+	cmd.SetArgs([]string{"--dry-run", "file", "rm"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	if out.String() != "# move a.srt to the trash\n" {
+		t.Fatalf("output = %q, want the planned move", out.String())
+	}
+	if _, err := os.Stat("a.srt"); err != nil {
+		t.Fatalf("a.srt should still exist: %v", err)
+	}
+}
@@ -100,3 +100,96 @@ func TestFileSearchCommand_NoSubtitleFiles(t *testing.T) {
 		t.Fatalf("expected ErrNoSubtitleFiles, got %v", err)
 	}
 }
+
+func TestFileSearchCommand_EpisodeFormatsAndPattern(t *testing.T) {
+	cmd := NewRootCmd()
+	tmpDir := t.TempDir()
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(testPackageDir)
+	})
+
+	for _, name := range []string{
+		"show.s01e02.srt",
+		"Show.S01E02.mkv",
+		"[Group] Anime - 05.ass",
+		"[Group] Anime [05][1080p].mkv",
+		"Doc Part 3.srt",
+		"Doc.S01E03.mkv",
+	} {
+		if err := os.WriteFile(name, []byte("x"), 0o644); err != nil {
+			t.Fatalf("write %s failed: %v", name, err)
+		}
+	}
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"file", "search", "--pattern", `Part (?P<episode>\d+)`})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	output := out.String()
+	for _, want := range []string{
+		"Doc Part 3.srt => Doc.S01E03.mkv (found)",
+		"[Group] Anime - 05.ass => [Group] Anime [05][1080p].mkv (found)",
+		"show.s01e02.srt => Show.S01E02.mkv (found)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("output = %q, want contains %q", output, want)
+		}
+	}
+}
+
+func TestFileSearchCommand_RejectsPatternWithoutEpisodeGroup(t *testing.T) {
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"file", "search", "--pattern", `Part (\d+)`})
+
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "no (?P<episode>...) group") {
+		t.Fatalf("cmd.Execute() error = %v, want missing episode group", err)
+	}
+}
+
+func TestFileSearchCommand_MatchesMoviesByTitle(t *testing.T) {
+	cmd := NewRootCmd()
+	tmpDir := t.TempDir()
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(testPackageDir)
+	})
+
+	for _, name := range []string{
+		"The Matrix (1999).srt",
+		"The.Matrix.1999.1080p.BluRay.x264-GROUP.mkv",
+		"Unrelated Notes.srt",
+	} {
+		if err := os.WriteFile(name, []byte("x"), 0o644); err != nil {
+			t.Fatalf("write %s failed: %v", name, err)
+		}
+	}
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"file", "search"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	output := out.String()
+	if !strings.Contains(output, "The Matrix (1999).srt => The.Matrix.1999.1080p.BluRay.x264-GROUP.mkv (found, score 1.00)") {
+		t.Fatalf("output = %q, want scored title match", output)
+	}
+	if !strings.Contains(output, "Unrelated Notes.srt => \x1b[31mignore\x1b[0m") {
+		t.Fatalf("output = %q, want unmatched title ignored", output)
+	}
+}
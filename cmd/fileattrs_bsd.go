@@ -0,0 +1,24 @@
+//go:build darwin || freebsd
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+func fileAccessTime(info os.FileInfo) time.Time {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(stat.Atimespec.Unix())
+	}
+	return info.ModTime()
+}
+
+func copyFileOwnership(info os.FileInfo, targetFile string) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	return os.Lchown(targetFile, int(stat.Uid), int(stat.Gid))
+}
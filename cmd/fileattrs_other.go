@@ -0,0 +1,20 @@
+//go:build !(linux || darwin || freebsd)
+
+package cmd
+
+import (
+	"os"
+	"time"
+)
+
+func fileAccessTime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
+
+func copyFileOwnership(info os.FileInfo, targetFile string) error {
+	return nil
+}
+
+func copyExtendedAttributes(sourceFile, targetFile string) error {
+	return nil
+}
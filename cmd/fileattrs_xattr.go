@@ -0,0 +1,43 @@
+//go:build linux || darwin || freebsd
+
+package cmd
+
+import (
+	"bytes"
+
+	"golang.org/x/sys/unix"
+)
+
+func copyExtendedAttributes(sourceFile, targetFile string) error {
+	size, err := unix.Listxattr(sourceFile, nil)
+	if err != nil || size == 0 {
+		return err
+	}
+	names := make([]byte, size)
+	if size, err = unix.Listxattr(sourceFile, names); err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, name := range bytes.Split(names[:size], []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		if err := copyExtendedAttribute(sourceFile, targetFile, string(name)); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func copyExtendedAttribute(sourceFile, targetFile, name string) error {
+	size, err := unix.Getxattr(sourceFile, name, nil)
+	if err != nil {
+		return err
+	}
+	value := make([]byte, size)
+	if size, err = unix.Getxattr(sourceFile, name, value); err != nil {
+		return err
+	}
+	return unix.Setxattr(targetFile, name, value[:size], 0)
+}
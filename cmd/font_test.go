@@ -155,3 +155,34 @@ func TestFontDownloadCommand_UnsupportedFont(t *testing.T) {
 		t.Fatalf("error = %q, want contains unsupported font", err)
 	}
 }
+
+func TestFontDownloadCommand_DryRunDownloadsNothing(t *testing.T) {
+	originFontURLs := fontURLs
+	t.Cleanup(func() {
+		fontURLs = originFontURLs
+	})
+	fontURLs = map[string]string{"yahei": "http://127.0.0.1:1/mock-font.ttf"}
+
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(testPackageDir)
+	})
+
+	var out bytes.Buffer
+	cmd := NewRootCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"--dry-run", "font", "download", "yahei"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	if want := "# download http://127.0.0.1:1/mock-font.ttf to mock-font.ttf\n"; out.String() != want {
+		t.Fatalf("output = %q, want %q", out.String(), want)
+	}
+	if _, err := os.Stat("mock-font.ttf"); !os.IsNotExist(err) {
+		t.Fatalf("dry run should not write the font: %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -14,6 +15,7 @@ import (
 
 func NewForceCmd() *cobra.Command {
 	var streamID string
+	var backup string
 
 	cmd := &cobra.Command{
 		Use:   "force <mkv_filename>",
@@ -21,6 +23,11 @@ func NewForceCmd() *cobra.Command {
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cobraCmd *cobra.Command, args []string) error {
 			targetFile := args[0]
+			mode := newRunMode(cobraCmd)
+
+			if err := validateBackupMode(backup); err != nil {
+				return err
+			}
 
 			if filepath.Ext(targetFile) != ".mkv" && filepath.Ext(targetFile) != ".MKV" {
 				return fmt.Errorf("file must be an mkv file: %s", targetFile)
@@ -34,46 +41,72 @@ func NewForceCmd() *cobra.Command {
 				return fmt.Errorf("invalid stream id: %s", streamID)
 			}
 
-			if err := mkv.RequireFFmpegInstalled(); err != nil {
+			streams, err := probeMKVStreams(targetFile)
+			if err != nil {
 				return err
 			}
 
-			streams, err := getMKVStreams(targetFile)
+			targetStream, err := findStreamForSubtitleRemoval(streams, streamID)
 			if err != nil {
 				return err
 			}
 
-			targetStream, err := findStreamForSubtitleRemoval(streams, streamID)
+			trackEdits, err := mkvForceToggleTrackEdits(streams, targetStream)
 			if err != nil {
 				return err
 			}
+			err = editTracksInPlace(mode, targetFile, trackEdits, backup)
+			if err == nil {
+				if mode.dryRun {
+					return nil
+				}
+				_, err = fmt.Fprintf(cobraCmd.OutOrStdout(), "Toggled forced for stream %s\n", targetStream.ID)
+				return err
+			}
+			if !errors.Is(err, mkv.ErrInPlaceEditUnavailable) {
+				return err
+			}
 
-			outputFile := mkvMergeOutputPath(targetFile)
-			if err := mkv.RemoveTempOutputIfExists(outputFile); err != nil {
+			if err := mkv.RequireFFmpegInstalled(); err != nil {
 				return err
 			}
 
+			outputFile := mkvMergeOutputPath(targetFile)
 			ffmpegArgs := mkvForceToggleFFmpegArgs(targetFile, streams, targetStream)
 			if len(ffmpegArgs) == 0 {
 				return fmt.Errorf("failed to build ffmpeg args for stream %s", streamID)
 			}
 			ffmpegArgs = append(ffmpegArgs, outputFile)
 
-			forceOutput, err := mkv.RunFFmpeg(ffmpegArgs...)
+			if mode.dryRun {
+				return mode.printRemuxPlan(ffmpegArgs, targetFile, outputFile, backup)
+			}
+			if err := mkv.RemoveTempOutputIfExists(outputFile); err != nil {
+				return err
+			}
+			if err := checkRemuxDiskSpace(targetFile); err != nil {
+				return err
+			}
+
+			forceOutput, err := runFFmpegRemux(mode, targetFile, outputFile, ffmpegArgs)
 			if err != nil {
 				return fmt.Errorf("failed to set forced for stream %s: %w: %s", streamID, err, bytes.TrimSpace(forceOutput))
 			}
 
-			if _, err := fmt.Fprintf(cobraCmd.OutOrStdout(), "Toggled forced for stream %s\n", targetStream.ID); err != nil {
+			expected := mkv.ExpectStreamsAfterToggle(streams, targetStream, "forced", targetStream.IsForced)
+			if err := replaceWithVerifiedOutput(targetFile, outputFile, expected, backup); err != nil {
 				return err
 			}
 
-			return os.Rename(outputFile, targetFile)
+			_, err = fmt.Fprintf(cobraCmd.OutOrStdout(), "Toggled forced for stream %s\n", targetStream.ID)
+			return err
 		},
 	}
 
 	cmd.Flags().StringVar(&streamID, "id", "", "Target subtitle stream id (pure number)")
 	_ = cmd.MarkFlagRequired("id")
 
+	addBackupFlag(cmd, &backup)
+
 	return cmd
 }
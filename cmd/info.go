@@ -3,33 +3,29 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 
 	"github.com/cuimingda/subs-cli/internal/mkv"
+
 	"github.com/spf13/cobra"
 )
 
 func NewInfoCmd() *cobra.Command {
 	return &cobra.Command{
-		Use:   "info <mkv-file>",
-		Short: "List stream ID, type, and title for a mkv file",
+		Use:   "info <mkv-or-mp4-file>",
+		Short: "List stream ID, type, and title for an mkv or mp4 file",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			fileName := args[0]
 
-			if filepath.Ext(fileName) != ".mkv" && filepath.Ext(fileName) != ".MKV" {
-				return fmt.Errorf("file must be an mkv file: %s", fileName)
+			if !mkv.IsVideoContainerFile(fileName) {
+				return fmt.Errorf("file must be an mkv or mp4 file: %s", fileName)
 			}
 
 			if _, err := os.Stat(fileName); err != nil {
 				return err
 			}
 
-			if err := mkv.RequireFFmpegInstalled(); err != nil {
-				return err
-			}
-
-			streams, err := getMKVStreams(fileName)
+			streams, err := probeMKVStreams(fileName)
 			if err != nil {
 				return err
 			}
@@ -61,10 +57,18 @@ func NewInfoCmd() *cobra.Command {
 					if stream.IsForced {
 						forcedMark = " (forced)"
 					}
+					hearingImpairedMark := ""
+					if stream.IsHearingImpaired {
+						hearingImpairedMark = " (hearing_impaired)"
+					}
+					imageMark := ""
+					if mkv.IsImageSubtitle(stream) {
+						imageMark = " [image]"
+					}
 
 					if _, err := fmt.Fprintf(
 						cmd.OutOrStdout(),
-						"%s %s %s %s %s%s%s\n",
+						"%s %s %s %s %s%s%s%s%s\n",
 						stream.ID,
 						stream.Type,
 						language,
@@ -72,6 +76,8 @@ func NewInfoCmd() *cobra.Command {
 						title,
 						defaultMark,
 						forcedMark,
+						hearingImpairedMark,
+						imageMark,
 					); err != nil {
 						return err
 					}
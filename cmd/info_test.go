@@ -2,10 +2,10 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"errors"
-	"path/filepath"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -13,10 +13,6 @@ import (
 )
 
 func TestInfoCommand_Success(t *testing.T) {
-	if _, err := exec.LookPath("ffmpeg"); err != nil {
-		t.Skip("skip info command test: ffmpeg is not available")
-	}
-
 	candidates := []string{
 		filepath.Join("resources", "low_quality_with_subtitles_5s.mkv"),
 		filepath.Join("..", "resources", "low_quality_with_subtitles_5s.mkv"),
@@ -52,7 +48,7 @@ func TestInfoCommand_Success(t *testing.T) {
 	want := []string{
 		"0:0 Video (EMPTY)",
 		"0:1 Audio (EMPTY)",
-		"0:2 Subtitle eng srt (EMPTY)",
+		"0:2 Subtitle eng srt (EMPTY) (default)",
 		"0:3 Subtitle hun srt (EMPTY)",
 		"0:4 Subtitle ger srt (EMPTY)",
 		"0:5 Subtitle fre srt (EMPTY)",
@@ -86,6 +82,10 @@ func (f *fakeFFmpegRunner) Run(args ...string) ([]byte, error) {
 	return []byte(f.output), f.runErr
 }
 
+func (f *fakeFFmpegRunner) RunContext(_ context.Context, _ func(mkv.FFmpegProgress), args ...string) ([]byte, error) {
+	return f.Run(args...)
+}
+
 func TestInfoCommand_PreservesAssSsaFormat(t *testing.T) {
 	cmd := NewRootCmd()
 	tmpDir := t.TempDir()
@@ -96,7 +96,7 @@ func TestInfoCommand_PreservesAssSsaFormat(t *testing.T) {
 
 	runner := &fakeFFmpegRunner{
 		installed: true,
-		output: "Stream #0:0: Video: h264\nStream #0:2(eng): Subtitle: ass (ssa) (default)\n",
+		output:    "Stream #0:0: Video: h264\nStream #0:2(eng): Subtitle: ass (ssa) (default)\n",
 	}
 	oldRunnerCleanup := func() { mkv.SetFFmpegRunner(nil) }
 	mkv.SetFFmpegRunner(runner)
@@ -184,8 +184,8 @@ func TestInfoCommand_RejectsNonMkvFile(t *testing.T) {
 	if err == nil {
 		t.Fatalf("expected non-mkv validation error, got nil")
 	}
-	if err.Error() != "file must be an mkv file: sample.txt" {
-		t.Fatalf("error = %q, want file must be an mkv file: sample.txt", err)
+	if err.Error() != "file must be an mkv or mp4 file: sample.txt" {
+		t.Fatalf("error = %q, want file must be an mkv or mp4 file: sample.txt", err)
 	}
 }
 
@@ -215,7 +215,7 @@ func TestInfoCommand_RejectsArgs(t *testing.T) {
 	}
 }
 
-func TestInfoCommand_RejectsWhenFFmpegMissing(t *testing.T) {
+func TestInfoCommand_ReadsMKVWithoutFFmpeg(t *testing.T) {
 	samplePath := resolveTestMkvPath(t)
 	if samplePath == "" {
 		t.Skip("skip info command test: test mkv not found")
@@ -227,13 +227,36 @@ func TestInfoCommand_RejectsWhenFFmpegMissing(t *testing.T) {
 	if err := copyFile(samplePath, target); err != nil {
 		t.Fatalf("copy target failed: %v", err)
 	}
-	if err := os.Chdir(tmpDir); err != nil {
-		t.Fatalf("chdir failed: %v", err)
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"info", target})
+	t.Setenv("PATH", "/tmp/no-path-for-test")
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 11 {
+		t.Fatalf("line count = %d, want 11, output=%q", len(lines), out.String())
+	}
+	if lines[2] != "0:2 Subtitle eng srt (EMPTY) (default)" {
+		t.Fatalf("line 2 = %q, want default english subtitle", lines[2])
+	}
+}
+
+func TestInfoCommand_RejectsNonMKVContentWhenFFmpegMissing(t *testing.T) {
+	cmd := NewRootCmd()
+	target := filepath.Join(t.TempDir(), "bad.mkv")
+	if err := os.WriteFile(target, []byte("not mkv"), 0o644); err != nil {
+		t.Fatalf("write bad mkv failed: %v", err)
 	}
 
 	cmd.SetOut(&bytes.Buffer{})
 	cmd.SetErr(&bytes.Buffer{})
-	cmd.SetArgs([]string{"info", filepath.Base(target)})
+	cmd.SetArgs([]string{"info", target})
 	t.Setenv("PATH", "/tmp/no-path-for-test")
 
 	err := cmd.Execute()
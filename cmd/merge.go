@@ -2,10 +2,13 @@ package cmd
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/cuimingda/subs-cli/internal/mkv"
 	"github.com/cuimingda/subs-cli/internal/subtitles"
@@ -17,88 +20,364 @@ func NewMergeCmd() *cobra.Command {
 	var targetFile string
 	var languageTag string
 	var subtitleTitle string
+	var autoMerge bool
+	var delayValue string
+	var subCharEnc string
+	var defaultPolicy string
+	var forced bool
+	var hearingImpaired bool
+	var position int
+	var backup string
 
 	cmd := &cobra.Command{
-		Use:   "merge <subtitle_filename>",
-		Short: "Merge a subtitle file into mkv as a new stream",
-		Args:  cobra.ExactArgs(1),
+		Use:   "merge <subtitle_filename[:lang=xxx][:title=xxx][:default][:forced][:sdh][:delay=xxx][:charenc=xxx]>...",
+		Short: "Merge subtitle files into mkv or mp4 as new streams",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if autoMerge {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.MinimumNArgs(1)(cmd, args)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			subtitleFile := args[0]
-			subtitleExt := strings.ToLower(filepath.Ext(subtitleFile))
-
-			if subtitleExt != ".srt" && subtitleExt != ".ass" && subtitleExt != ".ssa" {
-				return fmt.Errorf("unsupported subtitle format: %s", subtitleFile)
+			var delay time.Duration
+			if delayValue != "" {
+				var err error
+				if delay, err = mkv.ParseSubtitleDelay(delayValue); err != nil {
+					return err
+				}
 			}
 
-			if _, err := os.Stat(subtitleFile); err != nil {
+			if err := mkv.ValidateMergeDefaultPolicy(defaultPolicy); err != nil {
 				return err
 			}
-
-			if filepath.Ext(targetFile) != ".mkv" && filepath.Ext(targetFile) != ".MKV" {
-				return fmt.Errorf("target must be an mkv file: %s", targetFile)
+			if err := validateBackupMode(backup); err != nil {
+				return err
+			}
+			options := mergeOptions{defaultPolicy: defaultPolicy, forced: forced, hearingImpaired: hearingImpaired, position: -1, backup: backup, mode: newRunMode(cmd)}
+			if cmd.Flags().Changed("position") {
+				if position < 0 {
+					return fmt.Errorf("invalid position: %d", position)
+				}
+				options.position = position
 			}
 
-			if _, err := os.Stat(targetFile); err != nil {
-				return err
+			if autoMerge {
+				return runAutoMerge(cmd, languageTag, subtitleTitle, delay, subCharEnc, options)
 			}
 
-			if subtitles.IsLikelyChineseEnglishBilingual(subtitleFile) {
-				if subtitleTitle == "" {
-					subtitleTitle = "Chinese-English"
+			mergeSubtitles := make([]mkv.MergeSubtitle, 0, len(args))
+			for _, arg := range args {
+				subtitle, err := mkv.ParseMergeSubtitleSpec(arg)
+				if err != nil {
+					return err
+				}
+
+				if err := validateMergeSubtitleFile(subtitle.Path); err != nil {
+					return err
+				}
+
+				if subtitle.Language == "" {
+					subtitle.Language = languageTag
 				}
-				if languageTag == "" {
-					languageTag = "zho"
+				if subtitle.Title == "" {
+					subtitle.Title = subtitleTitle
 				}
+				if err := applyMergeSubtitleTiming(&subtitle, delay, subCharEnc); err != nil {
+					return err
+				}
+				options.applyFlags(&subtitle)
+				mergeSubtitles = append(mergeSubtitles, subtitle)
 			}
 
-			if languageTag != "" && !validLanguageTag(languageTag) {
-				return fmt.Errorf("invalid language tag: %s", languageTag)
+			if !mkv.IsVideoContainerFile(targetFile) {
+				return fmt.Errorf("target must be an mkv or mp4 file: %s", targetFile)
 			}
 
-			if err := mkv.RequireFFmpegInstalled(); err != nil {
+			if _, err := os.Stat(targetFile); err != nil {
 				return err
 			}
 
-			streams, err := getMKVStreams(targetFile)
-			if err != nil {
+			for i := range mergeSubtitles {
+				subtitle := &mergeSubtitles[i]
+				if subtitles.IsLikelyChineseEnglishBilingual(subtitle.Path) {
+					if subtitle.Title == "" {
+						subtitle.Title = "Chinese-English"
+					}
+					if subtitle.Language == "" {
+						subtitle.Language = "zho"
+					}
+				}
+			}
+
+			if err := validateMergeSubtitleLanguages(mergeSubtitles); err != nil {
 				return err
 			}
 
-			if _, err := cmd.OutOrStdout().Write([]byte(fmt.Sprintf("Found %d existing streams in target.\n", len(streams)))); err != nil {
+			if err := mkv.RequireFFmpegInstalled(); err != nil {
 				return err
 			}
 
-			targetSubtitleCount := 0
-			for _, stream := range streams {
-				if stream.Type == "Subtitle" {
-					targetSubtitleCount++
-				}
+			return mergeSubtitlesIntoMKV(cmd.OutOrStdout(), targetFile, mergeSubtitles, options)
+		},
+	}
+
+	cmd.Flags().StringVar(&targetFile, "target", "", "Target mkv or mp4 file")
+	cmd.Flags().BoolVar(&autoMerge, "auto", false, "Merge every subtitle in the current directory into its mkv by episode tag or base name")
+	cmd.MarkFlagsOneRequired("target", "auto")
+	cmd.MarkFlagsMutuallyExclusive("target", "auto")
+	cmd.Flags().StringVar(&languageTag, "language", "", "Subtitle language tag (lowercase, 3 letters) for files without lang=")
+	cmd.Flags().StringVar(&subtitleTitle, "title", "", "Subtitle title for files without title=")
+	cmd.Flags().StringVar(&defaultPolicy, "default", mkv.MergeDefaultAuto, "Make the new subtitle default: auto (unless forced or hearing impaired), yes or no")
+	cmd.Flags().BoolVar(&forced, "forced", false, "Mark the new subtitles as forced")
+	cmd.Flags().BoolVar(&hearingImpaired, "hearing-impaired", false, "Mark the new subtitles as hearing impaired (SDH)")
+	cmd.Flags().IntVar(&position, "position", 0, "Insert the new subtitles at this subtitle index instead of last")
+	cmd.Flags().StringVar(&delayValue, "delay", "", "Shift subtitles by a duration such as 1.5s or -500ms for files without delay=")
+	cmd.Flags().StringVar(&subCharEnc, "sub-charenc", "", "Character encoding of subtitles without charenc= (detected when omitted)")
+	addBackupFlag(cmd, &backup)
+	return cmd
+}
+
+func validateMergeSubtitleFile(subtitleFile string) error {
+	subtitleExt := strings.ToLower(filepath.Ext(subtitleFile))
+	if subtitleExt != ".srt" && subtitleExt != ".ass" && subtitleExt != ".ssa" {
+		return fmt.Errorf("unsupported subtitle format: %s", subtitleFile)
+	}
+
+	_, err := os.Stat(subtitleFile)
+	return err
+}
+
+// applyMergeSubtitleTiming fills in the delay and character encoding of a
+// subtitle that did not set them per file. Without an explicit encoding,
+// non-UTF-8 files are decoded with the detected one.
+func applyMergeSubtitleTiming(subtitle *mkv.MergeSubtitle, delay time.Duration, charEnc string) error {
+	if subtitle.Delay == 0 {
+		subtitle.Delay = delay
+	}
+	if subtitle.CharEnc == "" {
+		subtitle.CharEnc = charEnc
+	}
+	if subtitle.CharEnc != "" {
+		subtitle.CharEnc = strings.ToUpper(subtitle.CharEnc)
+		if subtitle.CharEnc == "UTF-8" || subtitle.CharEnc == "UTF8" {
+			subtitle.CharEnc = ""
+		}
+		return nil
+	}
+
+	encoding, err := subtitles.SubtitleCharacterEncoding(subtitle.Path)
+	if err != nil {
+		return fmt.Errorf("%w; set it with --sub-charenc", err)
+	}
+	subtitle.CharEnc = encoding
+	return nil
+}
+
+func validateMergeSubtitleLanguages(mergeSubtitles []mkv.MergeSubtitle) error {
+	for _, subtitle := range mergeSubtitles {
+		if subtitle.Language != "" && !validLanguageTag(subtitle.Language) {
+			return fmt.Errorf("invalid language tag: %s", subtitle.Language)
+		}
+	}
+	return nil
+}
+
+type mergeOptions struct {
+	defaultPolicy   string
+	forced          bool
+	hearingImpaired bool
+	// position is the subtitle index to insert at, or -1 to append.
+	position int
+	backup   string
+	mode     runMode
+}
+
+func (options mergeOptions) applyFlags(subtitle *mkv.MergeSubtitle) {
+	subtitle.Forced = subtitle.Forced || options.forced
+	subtitle.HearingImpaired = subtitle.HearingImpaired || options.hearingImpaired
+}
+
+// mergeSubtitlesIntoMKV merges mergeSubtitles into targetFile in one ffmpeg
+// pass, choosing the default subtitle by options.defaultPolicy.
+func mergeSubtitlesIntoMKV(out io.Writer, targetFile string, mergeSubtitles []mkv.MergeSubtitle, options mergeOptions) error {
+	if err := mkv.ApplyMergeDefaultPolicy(mergeSubtitles, options.defaultPolicy); err != nil {
+		return err
+	}
+
+	streams, err := probeMKVStreams(targetFile)
+	if err != nil {
+		return err
+	}
+
+	if _, err := out.Write([]byte(fmt.Sprintf("Found %d existing streams in target.\n", len(streams)))); err != nil {
+		return err
+	}
+
+	var mergeArgs []string
+	if options.position >= 0 {
+		mergeArgs, err = mkv.BuildMergeAtPositionFFmpegArgs(targetFile, streams, mergeSubtitles, options.position)
+		if err != nil {
+			return err
+		}
+	} else {
+		targetSubtitleCount := 0
+		for _, stream := range streams {
+			if stream.Type == "Subtitle" {
+				targetSubtitleCount++
 			}
-			outputFile := mkvMergeOutputPath(targetFile)
+		}
+		mergeArgs = mkv.BuildMergeFFmpegArgs(targetFile, targetSubtitleCount, mergeSubtitles)
+	}
+
+	outputFile := mkvMergeOutputPath(targetFile)
+	mergeArgs = append(mergeArgs, outputFile)
+	if options.mode.dryRun {
+		return options.mode.printRemuxPlan(mergeArgs, targetFile, outputFile, options.backup)
+	}
+	if err := mkv.RemoveTempOutputIfExists(outputFile); err != nil {
+		return err
+	}
+	var subtitleFiles []string
+	for _, subtitle := range mergeSubtitles {
+		subtitleFiles = append(subtitleFiles, subtitle.Path)
+	}
+	if err := checkRemuxDiskSpace(targetFile, subtitleFiles...); err != nil {
+		return err
+	}
+
+	mergeOutput, err := runFFmpegRemux(options.mode, targetFile, outputFile, mergeArgs)
+	if err != nil {
+		return fmt.Errorf("failed to merge subtitle: %w: %s", err, bytes.TrimSpace(mergeOutput))
+	}
+
+	if _, err := out.Write([]byte(fmt.Sprintf("Exported subtitle stream to %s\n", outputFile))); err != nil {
+		return err
+	}
+
+	expected := mkv.ExpectStreamsAfterMerge(targetFile, streams, mergeSubtitles, options.position)
+	return replaceWithVerifiedOutput(targetFile, outputFile, expected, options.backup)
+}
 
-			if err := mkv.RemoveTempOutputIfExists(outputFile); err != nil {
+type autoMergeEpisode struct {
+	targetFile string
+	subtitles  []mkv.MergeSubtitle
+	// err is why the episode cannot be merged; it is reported with the
+	// merge failures instead of stopping the other episodes.
+	err error
+}
+
+func runAutoMerge(cmd *cobra.Command, languageTag, subtitleTitle string, delay time.Duration, charEnc string, options mergeOptions) error {
+	if languageTag != "" && !validLanguageTag(languageTag) {
+		return fmt.Errorf("invalid language tag: %s", languageTag)
+	}
+
+	subtitleFiles, err := subtitles.ListCurrentDirSubtitleFiles()
+	if err != nil {
+		return err
+	}
+
+	var episodes []*autoMergeEpisode
+	episodeByTarget := map[string]*autoMergeEpisode{}
+	for _, subtitleFile := range subtitleFiles {
+		targetFile, err := subtitles.FindMKVFileForSubtitle(subtitleFile)
+		if err != nil {
+			return err
+		}
+		if targetFile == "" {
+			if _, err := fmt.Fprintf(cmd.OutOrStdout(), "%s => %s\n", subtitleFile, colorize("not found", "31")); err != nil {
 				return err
 			}
+			continue
+		}
 
-			mergeArgs := mkv.BuildMergeFFmpegArgs(targetFile, subtitleFile, targetSubtitleCount, languageTag, subtitleTitle)
-			mergeArgs = append(mergeArgs, outputFile)
+		language, title := subtitles.DetectSubtitleLanguage(subtitleFile)
+		if languageTag != "" {
+			language = languageTag
+		}
+		if subtitleTitle != "" {
+			title = subtitleTitle
+		}
+		if language != "" && !validLanguageTag(language) {
+			language = ""
+		}
 
-			mergeOutput, err := mkv.RunFFmpeg(mergeArgs...)
-			if err != nil {
-				return fmt.Errorf("failed to merge subtitle: %w: %s", err, bytes.TrimSpace(mergeOutput))
+		episode, ok := episodeByTarget[targetFile]
+		if !ok {
+			episode = &autoMergeEpisode{targetFile: targetFile}
+			episodeByTarget[targetFile] = episode
+			episodes = append(episodes, episode)
+		}
+		subtitle := mkv.MergeSubtitle{Path: subtitleFile, Language: language, Title: title}
+		if err := applyMergeSubtitleTiming(&subtitle, delay, charEnc); err != nil {
+			if episode.err == nil {
+				episode.err = err
 			}
+			continue
+		}
+		options.applyFlags(&subtitle)
+		episode.subtitles = append(episode.subtitles, subtitle)
 
-			if _, err := cmd.OutOrStdout().Write([]byte(fmt.Sprintf("Exported subtitle stream to %s\n", outputFile))); err != nil {
+		if _, err := fmt.Fprintf(cmd.OutOrStdout(), "%s => %s (language=%s, title=%s)\n", subtitleFile, targetFile, displayOrEmpty(language), displayOrEmpty(title)); err != nil {
+			return err
+		}
+	}
+
+	if len(episodes) == 0 {
+		return errors.New("no subtitle files match an mkv file in current directory")
+	}
+
+	if err := mkv.RequireFFmpegInstalled(); err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, episode := range episodes {
+		if episode.err == nil {
+			pending++
+		}
+	}
+	if !options.mode.dryRun && pending > 0 {
+		confirmed, err := confirmAction(cmd.InOrStdin(), cmd.ErrOrStderr(), fmt.Sprintf("This will merge subtitles into %d mkv files. Continue?", pending))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return nil
+		}
+	}
+
+	failures := 0
+	var summary []string
+	for _, episode := range episodes {
+		err := episode.err
+		if err == nil {
+			err = mergeSubtitlesIntoMKV(io.Discard, episode.targetFile, episode.subtitles, options)
+		}
+		if err != nil {
+			if errors.Is(err, errInterrupted) {
 				return err
 			}
-
-			return os.Rename(outputFile, targetFile)
-		},
+			failures++
+			summary = append(summary, fmt.Sprintf("%s => %s: %v", episode.targetFile, colorize("failed", "31"), err))
+			continue
+		}
+		status := colorize("merged", "32")
+		if options.mode.dryRun {
+			status = "planned"
+		}
+		summary = append(summary, fmt.Sprintf("%s => %s (%d subtitles)", episode.targetFile, status, len(episode.subtitles)))
 	}
 
-	cmd.Flags().StringVar(&targetFile, "target", "", "Target mkv file")
-	_ = cmd.MarkFlagRequired("target")
-	cmd.Flags().StringVar(&languageTag, "language", "", "Subtitle language tag (lowercase, 3 letters)")
-	cmd.Flags().StringVar(&subtitleTitle, "title", "", "Subtitle title")
-	return cmd
+	for _, line := range summary {
+		if _, err := fmt.Fprintln(cmd.OutOrStdout(), line); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(cmd.OutOrStdout(), "Merged %d of %d mkv files.\n", len(episodes)-failures, len(episodes)); err != nil {
+		return err
+	}
+	if failures > 0 {
+		return fmt.Errorf("failed to merge subtitles into %d mkv files", failures)
+	}
+	return nil
 }
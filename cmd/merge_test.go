@@ -2,11 +2,15 @@ package cmd
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/cuimingda/subs-cli/internal/mkv"
 )
 
 func TestMergeCommand_SrtSuccess(t *testing.T) {
@@ -318,8 +322,8 @@ func TestMergeCommand_InvalidTargetFileType(t *testing.T) {
 	if err == nil {
 		t.Fatalf("expected invalid target type error, got nil")
 	}
-	if !strings.Contains(err.Error(), "target must be an mkv file") {
-		t.Fatalf("error = %q, want target must be an mkv file", err)
+	if !strings.Contains(err.Error(), "target must be an mkv or mp4 file") {
+		t.Fatalf("error = %q, want target must be an mkv or mp4 file", err)
 	}
 }
 
@@ -469,3 +473,453 @@ func TestMergeCommand_RejectsWhenFFmpegMissing(t *testing.T) {
 		t.Fatalf("error = %q, want ffmpeg missing message", err)
 	}
 }
+
+func TestMergeCommand_MergesMultipleSubtitlesInSinglePass(t *testing.T) {
+	skipRemuxVerification(t)
+	samplePath := resolveTestMkvPath(t)
+	if samplePath == "" {
+		t.Skip("skip merge command test: test mkv not found")
+	}
+
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, filepath.Base(samplePath))
+	if err := copyFile(samplePath, target); err != nil {
+		t.Fatalf("copy target failed: %v", err)
+	}
+	chineseSubtitle := filepath.Join(tmpDir, "movie.chs.srt")
+	englishSubtitle := filepath.Join(tmpDir, "movie.eng.srt")
+	for _, path := range []string{chineseSubtitle, englishSubtitle} {
+		if err := os.WriteFile(path, []byte("1\n00:00:00,000 --> 00:00:01,000\nhello\n"), 0o644); err != nil {
+			t.Fatalf("write subtitle failed: %v", err)
+		}
+	}
+
+	runner := &remuxFFmpegRunner{}
+	mkv.SetFFmpegRunner(runner)
+	t.Cleanup(func() { mkv.SetFFmpegRunner(nil) })
+
+	cmd := NewRootCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{
+		"merge", "--target", target,
+		chineseSubtitle + ":lang=chi:title=简体:default",
+		englishSubtitle + ":lang=eng:forced",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	for _, pair := range [][2]string{
+		{"-i", chineseSubtitle},
+		{"-i", englishSubtitle},
+		{"-disposition:s:0", "-default"},
+		{"-disposition:s:8", "default"},
+		{"-disposition:s:9", "forced"},
+		{"-metadata:s:s:8", "language=chi"},
+		{"-metadata:s:s:8", "title=简体"},
+		{"-metadata:s:s:9", "language=eng"},
+	} {
+		if !containsRemoveArgPair(runner.args, pair[0], pair[1]) {
+			t.Fatalf("expected %s %s in args: %#v", pair[0], pair[1], runner.args)
+		}
+	}
+
+	content, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("read target failed: %v", err)
+	}
+	if string(content) != "remuxed" {
+		t.Fatalf("target was not replaced by the merged output")
+	}
+}
+
+func TestMergeCommand_RejectsUnknownSubtitleOption(t *testing.T) {
+	tmpDir := t.TempDir()
+	targetPath := filepath.Join(tmpDir, "target.mkv")
+	subtitlePath := filepath.Join(tmpDir, "subtitle.srt")
+	if err := os.WriteFile(targetPath, []byte("not real mkv"), 0o644); err != nil {
+		t.Fatalf("write target.mkv failed: %v", err)
+	}
+	if err := os.WriteFile(subtitlePath, []byte("1\n00:00:00,000 --> 00:00:01,000\nhello\n"), 0o644); err != nil {
+		t.Fatalf("write subtitle.srt failed: %v", err)
+	}
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"merge", "--target", targetPath, subtitlePath + ":lang=chi:speed=2"})
+
+	err := cmd.Execute()
+	if err == nil {
+		t.Fatalf("expected unknown option error, got nil")
+	}
+	if !strings.Contains(err.Error(), "unknown merge option") {
+		t.Fatalf("error = %q, want unknown merge option", err)
+	}
+}
+
+func TestMergeCommand_RejectsInvalidPerFileLanguage(t *testing.T) {
+	tmpDir := t.TempDir()
+	targetPath := filepath.Join(tmpDir, "target.mkv")
+	subtitlePath := filepath.Join(tmpDir, "subtitle.srt")
+	if err := os.WriteFile(targetPath, []byte("not real mkv"), 0o644); err != nil {
+		t.Fatalf("write target.mkv failed: %v", err)
+	}
+	if err := os.WriteFile(subtitlePath, []byte("1\n00:00:00,000 --> 00:00:01,000\nhello\n"), 0o644); err != nil {
+		t.Fatalf("write subtitle.srt failed: %v", err)
+	}
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"merge", "--target", targetPath, subtitlePath + ":lang=zh-CN"})
+
+	err := cmd.Execute()
+	if err == nil {
+		t.Fatalf("expected invalid language error, got nil")
+	}
+	if !strings.Contains(err.Error(), "invalid language tag: zh-CN") {
+		t.Fatalf("error = %q, want invalid language tag", err)
+	}
+}
+
+type autoMergeFFmpegRunner struct {
+	failFor string
+	calls   [][]string
+}
+
+func (r *autoMergeFFmpegRunner) IsInstalled() error {
+	return nil
+}
+
+func (r *autoMergeFFmpegRunner) Run(args ...string) ([]byte, error) {
+	r.calls = append(r.calls, args)
+	for _, arg := range args {
+		if r.failFor != "" && arg == r.failFor {
+			return []byte("boom"), errors.New("exit status 1")
+		}
+	}
+	return nil, os.WriteFile(args[len(args)-1], []byte("remuxed"), 0o644)
+}
+
+func (r *autoMergeFFmpegRunner) RunContext(_ context.Context, _ func(mkv.FFmpegProgress), args ...string) ([]byte, error) {
+	return r.Run(args...)
+}
+
+func TestMergeCommand_AutoMergesSeasonAndReportsFailures(t *testing.T) {
+	skipRemuxVerification(t)
+	samplePath := resolveTestMkvPath(t)
+	if samplePath == "" {
+		t.Skip("skip merge command test: test mkv not found")
+	}
+
+	tmpDir := t.TempDir()
+	for _, name := range []string{"Show.S01E01.1080p.mkv", "Show.S01E02.1080p.mkv"} {
+		if err := copyFile(samplePath, filepath.Join(tmpDir, name)); err != nil {
+			t.Fatalf("copy target failed: %v", err)
+		}
+	}
+	subtitleFiles := map[string]string{
+		"Show.S01E01.chs.srt": "1\n00:00:00,000 --> 00:00:01,000\n你好\n",
+		"Show.S01E01.eng.srt": "1\n00:00:00,000 --> 00:00:01,000\nhello\n",
+		"Show.S01E02.srt":     "1\n00:00:00,000 --> 00:00:01,000\n你好 hello\n",
+		"Show.S01E03.srt":     "1\n00:00:00,000 --> 00:00:01,000\nhello\n",
+	}
+	for name, content := range subtitleFiles {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write subtitle failed: %v", err)
+		}
+	}
+
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(testPackageDir)
+	})
+
+	runner := &autoMergeFFmpegRunner{failFor: "Show.S01E02.srt"}
+	mkv.SetFFmpegRunner(runner)
+	t.Cleanup(func() { mkv.SetFFmpegRunner(nil) })
+
+	cmd := NewRootCmd()
+	var out bytes.Buffer
+	var errOut bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&errOut)
+	cmd.SetIn(strings.NewReader("y\n"))
+	cmd.SetArgs([]string{"merge", "--auto"})
+
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "failed to merge subtitles into 1 mkv files") {
+		t.Fatalf("error = %v, want one failed episode", err)
+	}
+
+	if len(runner.calls) != 2 {
+		t.Fatalf("ffmpeg calls = %d, want one per episode", len(runner.calls))
+	}
+	first := runner.calls[0]
+	for _, pair := range [][2]string{
+		{"-i", "Show.S01E01.chs.srt"},
+		{"-i", "Show.S01E01.eng.srt"},
+		{"-metadata:s:s:8", "language=zho"},
+		{"-metadata:s:s:8", "title=简体中文"},
+		{"-metadata:s:s:9", "language=eng"},
+		{"-disposition:s:8", "default"},
+	} {
+		if !containsRemoveArgPair(first, pair[0], pair[1]) {
+			t.Fatalf("expected %s %s in first episode args: %#v", pair[0], pair[1], first)
+		}
+	}
+
+	output := out.String()
+	for _, want := range []string{
+		"Show.S01E02.srt => Show.S01E02.1080p.mkv (language=zho, title=Chinese-English)",
+		"Show.S01E03.srt => \x1b[31mnot found\x1b[0m",
+		"Show.S01E01.1080p.mkv => \x1b[32mmerged\x1b[0m (2 subtitles)",
+		"Show.S01E02.1080p.mkv => \x1b[31mfailed\x1b[0m",
+		"Merged 1 of 2 mkv files.",
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("output = %q, want %q", output, want)
+		}
+	}
+	if !strings.Contains(errOut.String(), "This will merge subtitles into 2 mkv files. Continue?") {
+		t.Fatalf("prompt = %q, want merge plan confirmation", errOut.String())
+	}
+
+	if _, err := os.Stat("Show.S01E02.1080p.mkv.tmp_subs.mkv"); err == nil {
+		t.Fatalf("temporary output of the failed episode should be removed")
+	}
+}
+
+func TestMergeCommand_AutoMergeContinuesAfterUndetectableEncoding(t *testing.T) {
+	skipRemuxVerification(t)
+	samplePath := resolveTestMkvPath(t)
+	if samplePath == "" {
+		t.Skip("skip merge command test: test mkv not found")
+	}
+
+	tmpDir := t.TempDir()
+	for _, name := range []string{"Show.S01E01.mkv", "Show.S01E02.mkv"} {
+		if err := copyFile(samplePath, filepath.Join(tmpDir, name)); err != nil {
+			t.Fatalf("copy target failed: %v", err)
+		}
+	}
+	subtitleFiles := map[string]string{
+		"Show.S01E01.srt": "1\n00:00:00,000 --> 00:00:01,000\nhello\n",
+		"Show.S01E02.srt": "\xff",
+	}
+	for name, content := range subtitleFiles {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write subtitle failed: %v", err)
+		}
+	}
+
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(testPackageDir)
+	})
+
+	runner := &autoMergeFFmpegRunner{}
+	mkv.SetFFmpegRunner(runner)
+	t.Cleanup(func() { mkv.SetFFmpegRunner(nil) })
+
+	cmd := NewRootCmd()
+	var out bytes.Buffer
+	var errOut bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&errOut)
+	cmd.SetIn(strings.NewReader("y\n"))
+	cmd.SetArgs([]string{"merge", "--auto"})
+
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "failed to merge subtitles into 1 mkv files") {
+		t.Fatalf("error = %v, want one failed episode", err)
+	}
+	if len(runner.calls) != 1 || !containsRemoveArgPair(runner.calls[0], "-i", "Show.S01E01.srt") {
+		t.Fatalf("ffmpeg calls = %#v, want only the first episode", runner.calls)
+	}
+
+	output := out.String()
+	for _, want := range []string{
+		"Show.S01E01.mkv => \x1b[32mmerged\x1b[0m (1 subtitles)",
+		"Show.S01E02.mkv => \x1b[31mfailed\x1b[0m: cannot detect the encoding of Show.S01E02.srt",
+		"Merged 1 of 2 mkv files.",
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("output = %q, want %q", output, want)
+		}
+	}
+	if !strings.Contains(errOut.String(), "This will merge subtitles into 1 mkv files. Continue?") {
+		t.Fatalf("prompt = %q, want only the mergeable episode confirmed", errOut.String())
+	}
+}
+
+func TestMergeCommand_RejectsAutoWithTarget(t *testing.T) {
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"merge", "--auto", "--target", "movie.mkv"})
+
+	if err := cmd.Execute(); err == nil {
+		t.Fatalf("expected mutually exclusive flags error, got nil")
+	}
+}
+
+func TestMergeCommand_AppliesDelayAndCharEncWithoutRewritingSource(t *testing.T) {
+	skipRemuxVerification(t)
+	samplePath := resolveTestMkvPath(t)
+	if samplePath == "" {
+		t.Skip("skip merge command test: test mkv not found")
+	}
+
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, filepath.Base(samplePath))
+	if err := copyFile(samplePath, target); err != nil {
+		t.Fatalf("copy target failed: %v", err)
+	}
+	gbkSubtitle := filepath.Join(tmpDir, "movie.chs.srt")
+	englishSubtitle := filepath.Join(tmpDir, "movie.eng.srt")
+	gbkContent := []byte("1\n00:00:00,000 --> 00:00:01,000\n\xc4\xe3\xba\xc3\n")
+	if err := os.WriteFile(gbkSubtitle, gbkContent, 0o644); err != nil {
+		t.Fatalf("write subtitle failed: %v", err)
+	}
+	if err := os.WriteFile(englishSubtitle, []byte("1\n00:00:00,000 --> 00:00:01,000\nhello\n"), 0o644); err != nil {
+		t.Fatalf("write subtitle failed: %v", err)
+	}
+
+	runner := &remuxFFmpegRunner{}
+	mkv.SetFFmpegRunner(runner)
+	t.Cleanup(func() { mkv.SetFFmpegRunner(nil) })
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{
+		"merge", "--target", target, "--delay", "1.5s", "--sub-charenc", "gbk",
+		gbkSubtitle,
+		englishSubtitle + ":delay=-500ms:charenc=UTF-8",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	for _, pair := range [][2]string{
+		{"-itsoffset", "1.5"},
+		{"-sub_charenc", "GBK"},
+		{"-itsoffset", "-0.5"},
+		{"-c:s:8", "srt"},
+	} {
+		if !containsRemoveArgPair(runner.args, pair[0], pair[1]) {
+			t.Fatalf("expected %s %s in args: %#v", pair[0], pair[1], runner.args)
+		}
+	}
+	if containsRemoveArgPair(runner.args, "-c:s:9", "srt") {
+		t.Fatalf("UTF-8 subtitle should be stream copied: %#v", runner.args)
+	}
+
+	content, err := os.ReadFile(gbkSubtitle)
+	if err != nil {
+		t.Fatalf("read subtitle failed: %v", err)
+	}
+	if !bytes.Equal(content, gbkContent) {
+		t.Fatal("source subtitle must not be rewritten")
+	}
+}
+
+func TestMergeCommand_RejectsInvalidDelay(t *testing.T) {
+	tmpDir := t.TempDir()
+	targetPath := filepath.Join(tmpDir, "target.mkv")
+	subtitlePath := filepath.Join(tmpDir, "subtitle.srt")
+	if err := os.WriteFile(targetPath, []byte("not real mkv"), 0o644); err != nil {
+		t.Fatalf("write target.mkv failed: %v", err)
+	}
+	if err := os.WriteFile(subtitlePath, []byte("1\n00:00:00,000 --> 00:00:01,000\nhello\n"), 0o644); err != nil {
+		t.Fatalf("write subtitle.srt failed: %v", err)
+	}
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"merge", "--target", targetPath, "--delay", "later", subtitlePath})
+
+	err := cmd.Execute()
+	if err == nil || err.Error() != "invalid subtitle delay: later" {
+		t.Fatalf("error = %v, want invalid subtitle delay", err)
+	}
+}
+
+func TestMergeCommand_KeepsExistingDefaultAtPosition(t *testing.T) {
+	skipRemuxVerification(t)
+	samplePath := resolveTestMkvPath(t)
+	if samplePath == "" {
+		t.Skip("skip merge command test: test mkv not found")
+	}
+
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, filepath.Base(samplePath))
+	if err := copyFile(samplePath, target); err != nil {
+		t.Fatalf("copy target failed: %v", err)
+	}
+	subtitle := filepath.Join(tmpDir, "commentary.srt")
+	if err := os.WriteFile(subtitle, []byte("1\n00:00:00,000 --> 00:00:01,000\nhello\n"), 0o644); err != nil {
+		t.Fatalf("write subtitle failed: %v", err)
+	}
+
+	runner := &remuxFFmpegRunner{}
+	mkv.SetFFmpegRunner(runner)
+	t.Cleanup(func() { mkv.SetFFmpegRunner(nil) })
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"merge", "--target", target, "--default=no", "--hearing-impaired", "--position", "1", subtitle + ":lang=eng"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	if !containsRemoveArgPair(runner.args, "-disposition:s:1", "hearing_impaired") {
+		t.Fatalf("expected hearing impaired disposition at s:1: %#v", runner.args)
+	}
+	if !containsRemoveArgPair(runner.args, "-metadata:s:s:1", "language=eng") {
+		t.Fatalf("expected language at s:1: %#v", runner.args)
+	}
+	if !strings.Contains(strings.Join(runner.args, " "), "-map 0:2 -map 1 -map 0:3") {
+		t.Fatalf("expected new subtitle mapped after the first subtitle: %#v", runner.args)
+	}
+	for _, arg := range runner.args {
+		if arg == "-default" {
+			t.Fatalf("existing default must be kept with --default=no: %#v", runner.args)
+		}
+	}
+}
+
+func TestMergeCommand_RejectsInvalidDefaultPolicy(t *testing.T) {
+	tmpDir := t.TempDir()
+	targetPath := filepath.Join(tmpDir, "target.mkv")
+	subtitlePath := filepath.Join(tmpDir, "subtitle.srt")
+	if err := os.WriteFile(targetPath, []byte("not real mkv"), 0o644); err != nil {
+		t.Fatalf("write target.mkv failed: %v", err)
+	}
+	if err := os.WriteFile(subtitlePath, []byte("1\n00:00:00,000 --> 00:00:01,000\nhello\n"), 0o644); err != nil {
+		t.Fatalf("write subtitle.srt failed: %v", err)
+	}
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"merge", "--target", targetPath, "--default", "maybe", subtitlePath})
+
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "invalid default policy") {
+		t.Fatalf("error = %v, want invalid default policy", err)
+	}
+}
@@ -8,6 +8,10 @@ func getMKVStreams(fileName string) ([]mkvStreamInfo, error) {
 	return mkv.ListStreams(fileName)
 }
 
+func probeMKVStreams(fileName string) ([]mkvStreamInfo, error) {
+	return mkv.ProbeStreams(fileName)
+}
+
 func parseMKVStreams(output string) ([]mkvStreamInfo, error) {
 	return mkv.ParseMKVStreams(output)
 }
@@ -78,3 +82,27 @@ func mkvDefaultToggleFFmpegArgs(sourceFile string, allStreams []mkvStreamInfo, t
 func mkvForceToggleFFmpegArgs(sourceFile string, allStreams []mkvStreamInfo, targetStream mkvStreamInfo) []string {
 	return mkv.BuildForceToggleFFmpegArgs(sourceFile, allStreams, targetStream)
 }
+
+func mkvDefaultToggleTrackEdits(allStreams []mkvStreamInfo, targetStream mkvStreamInfo) ([]mkv.TrackEdit, error) {
+	return mkv.DefaultToggleTrackEdits(allStreams, targetStream)
+}
+
+func mkvForceToggleTrackEdits(allStreams []mkvStreamInfo, targetStream mkvStreamInfo) ([]mkv.TrackEdit, error) {
+	return mkv.ForceToggleTrackEdits(allStreams, targetStream)
+}
+
+func findStreamForTrackEdit(allStreams []mkvStreamInfo, targetID string) (mkvStreamInfo, error) {
+	return mkv.FindStreamForTrackEdit(allStreams, targetID)
+}
+
+func mkvTrackMetadataFFmpegArgs(sourceFile string, targetStream mkvStreamInfo, languageTag, title *string) []string {
+	return mkv.BuildTrackMetadataFFmpegArgs(sourceFile, targetStream, languageTag, title)
+}
+
+func mkvDispositionFFmpegArgs(sourceFile string, allStreams []mkvStreamInfo, targetStream mkvStreamInfo, setFlags, clearFlags []string, exclusive bool) []string {
+	return mkv.BuildDispositionFFmpegArgs(sourceFile, allStreams, targetStream, setFlags, clearFlags, exclusive)
+}
+
+func mkvDispositionTrackEdits(allStreams []mkvStreamInfo, targetStream mkvStreamInfo, setFlags, clearFlags []string, exclusive bool) ([]mkv.TrackEdit, error) {
+	return mkv.DispositionTrackEdits(allStreams, targetStream, setFlags, clearFlags, exclusive)
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cuimingda/subs-cli/internal/mkv"
+)
+
+const mp4ProbeOutput = `Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'movie.mp4':
+  Stream #0:0[0x1](und): Video: h264 (High) (avc1 / 0x31637661), yuv420p, 320x240, 25 fps (default)
+  Stream #0:1[0x2](eng): Audio: aac (LC) (mp4a / 0x6134706D), 48000 Hz, stereo (default)
+  Stream #0:2[0x3](eng): Subtitle: mov_text (tx3g / 0x67337874), 0 kb/s (default)
+At least one output file must be specified
+`
+
+// probeFFmpegRunner answers stream listing with probeOutput and writes the
+// output file of every other ffmpeg run.
+type probeFFmpegRunner struct {
+	probeOutput string
+	calls       [][]string
+}
+
+func (r *probeFFmpegRunner) IsInstalled() error {
+	return nil
+}
+
+func (r *probeFFmpegRunner) Run(args ...string) ([]byte, error) {
+	r.calls = append(r.calls, args)
+	if len(args) == 3 && args[1] == "-i" {
+		return []byte(r.probeOutput), nil
+	}
+	return nil, os.WriteFile(args[len(args)-1], []byte("remuxed"), 0o644)
+}
+
+func (r *probeFFmpegRunner) RunContext(_ context.Context, _ func(mkv.FFmpegProgress), args ...string) ([]byte, error) {
+	return r.Run(args...)
+}
+
+// writeProbedTestFile creates a file that is not Matroska, so its streams
+// are listed by the runner with probeOutput.
+func writeProbedTestFile(t *testing.T, name, probeOutput string) (string, *probeFFmpegRunner) {
+	t.Helper()
+
+	target := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(target, []byte("not real media"), 0o644); err != nil {
+		t.Fatalf("write %s failed: %v", name, err)
+	}
+
+	runner := &probeFFmpegRunner{probeOutput: probeOutput}
+	mkv.SetFFmpegRunner(runner)
+	t.Cleanup(func() { mkv.SetFFmpegRunner(nil) })
+	return target, runner
+}
+
+func writeTestMP4(t *testing.T) (string, *probeFFmpegRunner) {
+	t.Helper()
+	return writeProbedTestFile(t, "movie.mp4", mp4ProbeOutput)
+}
+
+func TestInfoCommand_ListsMP4Streams(t *testing.T) {
+	target, _ := writeTestMP4(t)
+
+	cmd := NewRootCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"info", target})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "0:2 Subtitle eng mov_text (EMPTY) (default)") {
+		t.Fatalf("output = %q, want mov_text subtitle line", out.String())
+	}
+}
+
+func TestExtractCommand_ConvertsMovTextToSrt(t *testing.T) {
+	target, _ := writeTestMP4(t)
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"extract", target})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	want := filepath.Join(filepath.Dir(target), "movie_subs", "movie_0_2_eng.srt")
+	if _, err := os.Stat(want); err != nil {
+		t.Fatalf("expected extracted srt %s: %v", want, err)
+	}
+}
+
+func TestMergeCommand_ConvertsToMovTextForMP4(t *testing.T) {
+	skipRemuxVerification(t)
+	target, runner := writeTestMP4(t)
+	subtitle := filepath.Join(filepath.Dir(target), "movie.chs.srt")
+	if err := os.WriteFile(subtitle, []byte("1\n00:00:00,000 --> 00:00:01,000\nhello\n"), 0o644); err != nil {
+		t.Fatalf("write subtitle failed: %v", err)
+	}
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"merge", "--target", target, subtitle + ":lang=chi"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	mergeArgs := runner.calls[len(runner.calls)-1]
+	for _, pair := range [][2]string{
+		{"-c:s:1", "mov_text"},
+		{"-metadata:s:s:1", "language=zho"},
+		{"-disposition:s:0", "-default"},
+	} {
+		if !containsRemoveArgPair(mergeArgs, pair[0], pair[1]) {
+			t.Fatalf("expected %s %s in args: %#v", pair[0], pair[1], mergeArgs)
+		}
+	}
+	if output := mergeArgs[len(mergeArgs)-1]; output != target+".tmp_subs.mp4" {
+		t.Fatalf("merge output = %q, want an mp4 temp file", output)
+	}
+
+	content, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("read target failed: %v", err)
+	}
+	if string(content) != "remuxed" {
+		t.Fatal("target was not replaced by the merged output")
+	}
+}
+
+func TestDefaultCommand_RejectsClearingOnlyMP4Default(t *testing.T) {
+	target, runner := writeTestMP4(t)
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"default", target, "--id", "2"})
+
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "mp4 files always keep one subtitle enabled") {
+		t.Fatalf("error = %v, want mp4 default error", err)
+	}
+	if len(runner.calls) != 1 {
+		t.Fatalf("only the stream listing should run, got %d ffmpeg calls", len(runner.calls))
+	}
+}
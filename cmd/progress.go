@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cuimingda/subs-cli/internal/mkv"
+)
+
+var errInterrupted = errors.New("interrupted")
+
+// runFFmpegRemux runs an ffmpeg remux of inputFile into outputFile and shows
+// a progress line on mode.errOut when it is a terminal. On SIGINT or SIGTERM,
+// or when ffmpeg fails, the unfinished outputFile is removed.
+func runFFmpegRemux(mode runMode, inputFile, outputFile string, ffmpegArgs []string) ([]byte, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	mode.echoCommand(ffmpegArgs)
+	printer := newProgressPrinter(mode.errOut)
+	output, err := mkv.RunFFmpegContext(ctx, mkv.ProbeDuration(inputFile), printer.print, ffmpegArgs...)
+	printer.finish()
+	mode.echoOutput(output)
+
+	if ctx.Err() != nil {
+		if removeErr := mkv.RemoveTempOutputIfExists(outputFile); removeErr != nil {
+			return output, fmt.Errorf("%w; failed to remove unfinished output %s: %v", errInterrupted, outputFile, removeErr)
+		}
+		return output, fmt.Errorf("%w; removed unfinished output %s", errInterrupted, outputFile)
+	}
+	if err != nil {
+		_ = mkv.RemoveTempOutputIfExists(outputFile)
+	}
+	return output, err
+}
+
+type progressPrinter struct {
+	w       io.Writer
+	enabled bool
+	width   int
+}
+
+func newProgressPrinter(w io.Writer) *progressPrinter {
+	return &progressPrinter{w: w, enabled: isTerminal(w)}
+}
+
+func (p *progressPrinter) print(progress mkv.FFmpegProgress) {
+	if !p.enabled {
+		return
+	}
+	line := formatFFmpegProgress(progress)
+	padding := max(p.width-len(line), 0)
+	p.width = len(line)
+	_, _ = fmt.Fprintf(p.w, "\r%s%s", line, strings.Repeat(" ", padding))
+}
+
+func (p *progressPrinter) finish() {
+	if p.enabled && p.width > 0 {
+		_, _ = fmt.Fprintln(p.w)
+	}
+}
+
+func formatFFmpegProgress(progress mkv.FFmpegProgress) string {
+	var line strings.Builder
+	if percent := progress.Percent(); percent >= 0 {
+		fmt.Fprintf(&line, "%5.1f%% %s / %s", percent, formatProgressDuration(progress.OutTime), formatProgressDuration(progress.Duration))
+	} else {
+		fmt.Fprintf(&line, "%s", formatProgressDuration(progress.OutTime))
+	}
+	if progress.Speed > 0 {
+		fmt.Fprintf(&line, " at %.1fx", progress.Speed)
+	}
+	if eta := progress.ETA(); eta >= 0 && !progress.Done {
+		fmt.Fprintf(&line, ", ETA %s", formatProgressDuration(eta))
+	}
+	return line.String()
+}
+
+func formatProgressDuration(duration time.Duration) string {
+	seconds := int64(duration.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
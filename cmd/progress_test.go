@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cuimingda/subs-cli/internal/mkv"
+)
+
+func TestFormatFFmpegProgress(t *testing.T) {
+	tests := []struct {
+		progress mkv.FFmpegProgress
+		want     string
+	}{
+		{
+			progress: mkv.FFmpegProgress{OutTime: 30 * time.Second, Duration: 2 * time.Minute, Speed: 3},
+			want:     " 25.0% 00:00:30 / 00:02:00 at 3.0x, ETA 00:00:30",
+		},
+		{
+			progress: mkv.FFmpegProgress{OutTime: 90 * time.Second},
+			want:     "00:01:30",
+		},
+		{
+			progress: mkv.FFmpegProgress{OutTime: 2 * time.Minute, Duration: 2 * time.Minute, Speed: 3, Done: true},
+			want:     "100.0% 00:02:00 / 00:02:00 at 3.0x",
+		},
+	}
+	for _, tt := range tests {
+		if got := formatFFmpegProgress(tt.progress); got != tt.want {
+			t.Fatalf("formatFFmpegProgress(%+v) = %q, want %q", tt.progress, got, tt.want)
+		}
+	}
+}
+
+// interruptingFFmpegRunner writes part of the output, then sends SIGINT to
+// the test process and waits for the remux to be cancelled.
+type interruptingFFmpegRunner struct{}
+
+func (interruptingFFmpegRunner) IsInstalled() error {
+	return nil
+}
+
+func (interruptingFFmpegRunner) Run(args ...string) ([]byte, error) {
+	return nil, nil
+}
+
+func (interruptingFFmpegRunner) RunContext(ctx context.Context, _ func(mkv.FFmpegProgress), args ...string) ([]byte, error) {
+	if err := os.WriteFile(args[len(args)-1], []byte("partial"), 0o644); err != nil {
+		return nil, err
+	}
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		return nil, err
+	}
+	if err := process.Signal(os.Interrupt); err != nil {
+		return nil, err
+	}
+	select {
+	case <-ctx.Done():
+		return nil, errors.New("signal: killed")
+	case <-time.After(5 * time.Second):
+		return nil, errors.New("remux was not cancelled")
+	}
+}
+
+func TestRunFFmpegRemux_RemovesTempOutputOnInterrupt(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("interrupt signals cannot be sent to a process on windows")
+	}
+	mkv.SetFFmpegRunner(interruptingFFmpegRunner{})
+	t.Cleanup(func() { mkv.SetFFmpegRunner(nil) })
+
+	dir := t.TempDir()
+	targetFile := filepath.Join(dir, "movie.mkv")
+	outputFile := mkvMergeOutputPath(targetFile)
+
+	var errOut bytes.Buffer
+	_, err := runFFmpegRemux(runMode{errOut: &errOut}, targetFile, outputFile, []string{"-i", targetFile, outputFile})
+	if !errors.Is(err, errInterrupted) || !strings.Contains(err.Error(), "removed unfinished output "+outputFile) {
+		t.Fatalf("error = %v, want interrupted error", err)
+	}
+	if _, err := os.Stat(outputFile); !os.IsNotExist(err) {
+		t.Fatalf("temp output still exists: %v", err)
+	}
+	if errOut.Len() != 0 {
+		t.Fatalf("progress output = %q, want none when stderr is not a terminal", errOut.String())
+	}
+}
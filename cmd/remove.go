@@ -4,8 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"os"
-	"path/filepath"
-	"strconv"
+	"strings"
 
 	"github.com/cuimingda/subs-cli/internal/mkv"
 
@@ -13,52 +12,73 @@ import (
 )
 
 func NewRemoveCmd() *cobra.Command {
-	var streamID string
+	var streamIDs string
+	var languages string
+	var formats string
+	var removeAll bool
+	var backup string
 
 	cmd := &cobra.Command{
-		Use:   "remove <mkv_filename>",
-		Short: "Remove a subtitle stream from an mkv file",
+		Use:   "remove <mkv_or_mp4_filename>",
+		Short: "Remove subtitle streams from an mkv or mp4 file",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cobraCmd *cobra.Command, args []string) error {
 			targetFile := args[0]
+			mode := newRunMode(cobraCmd)
 
-			if filepath.Ext(targetFile) != ".mkv" && filepath.Ext(targetFile) != ".MKV" {
-				return fmt.Errorf("file must be an mkv file: %s", targetFile)
+			if err := validateBackupMode(backup); err != nil {
+				return err
+			}
+
+			if !mkv.IsVideoContainerFile(targetFile) {
+				return fmt.Errorf("file must be an mkv or mp4 file: %s", targetFile)
 			}
 
 			if _, err := os.Stat(targetFile); err != nil {
 				return err
 			}
 
-			if _, err := strconv.Atoi(streamID); err != nil {
-				return fmt.Errorf("invalid stream id: %s", streamID)
+			selection := mkv.RemovalSelection{
+				IDs:       splitCommaList(streamIDs),
+				Languages: splitCommaList(languages),
+				Formats:   splitCommaList(formats),
+				All:       removeAll,
+			}
+			for _, streamID := range selection.IDs {
+				if !isNumericStreamID(streamID) {
+					return fmt.Errorf("invalid stream id: %s", streamID)
+				}
 			}
 
-			if err := mkv.RequireFFmpegInstalled(); err != nil {
+			streams, err := probeMKVStreams(targetFile)
+			if err != nil {
 				return err
 			}
 
-			streams, err := getMKVStreams(targetFile)
+			targetStreams, err := mkv.SelectSubtitleStreamsForRemoval(streams, selection)
 			if err != nil {
 				return err
 			}
+			if len(targetStreams) == 0 {
+				_, err := fmt.Fprintln(cobraCmd.OutOrStdout(), "No subtitle streams match the selection.")
+				return err
+			}
 
-			targetStream, err := findStreamForSubtitleRemoval(streams, streamID)
-			if err != nil {
+			if err := mkv.RequireFFmpegInstalled(); err != nil {
 				return err
 			}
 
+			outputFile := mkvMergeOutputPath(targetFile)
+			ffmpegArgs := mkv.BuildRemoveStreamsFFmpegArgs(targetFile, targetStreams)
+			ffmpegArgs = append(ffmpegArgs, outputFile)
+			if mode.dryRun {
+				return mode.printRemuxPlan(ffmpegArgs, targetFile, outputFile, backup)
+			}
+
 			confirmed, err := confirmAction(
 				cobraCmd.InOrStdin(),
 				cobraCmd.ErrOrStderr(),
-				fmt.Sprintf(
-					"This will remove stream id=%s, type=%s, language=%s, format=%s, title=%s",
-					targetStream.ID,
-					targetStream.Type,
-					displayOrEmpty(targetStream.Language),
-					displayOrEmpty(targetStream.SubtitleFormat),
-					displayOrEmpty(targetStream.Title),
-				),
+				removalPrompt(targetStreams),
 			)
 			if err != nil {
 				return err
@@ -67,28 +87,87 @@ func NewRemoveCmd() *cobra.Command {
 				return nil
 			}
 
-			outputFile := mkvMergeOutputPath(targetFile)
 			if err := mkv.RemoveTempOutputIfExists(outputFile); err != nil {
 				return err
 			}
+			if err := checkRemuxDiskSpace(targetFile); err != nil {
+				return err
+			}
 
-			ffmpegArgs := mkv.BuildRemoveFFmpegArgs(targetFile, targetStream)
-			ffmpegArgs = append(ffmpegArgs, outputFile)
-			mergeOutput, err := mkv.RunFFmpeg(ffmpegArgs...)
+			mergeOutput, err := runFFmpegRemux(mode, targetFile, outputFile, ffmpegArgs)
 			if err != nil {
-				return fmt.Errorf("failed to remove stream %s: %w: %s", streamID, err, bytes.TrimSpace(mergeOutput))
+				return fmt.Errorf("failed to remove %s: %w: %s", removalSubject(targetStreams), err, bytes.TrimSpace(mergeOutput))
 			}
 
-			if _, err := fmt.Fprintf(cobraCmd.OutOrStdout(), "Removed stream %s\n", targetStream.ID); err != nil {
+			expected := mkv.ExpectStreamsAfterRemoval(streams, targetStreams)
+			if err := replaceWithVerifiedOutput(targetFile, outputFile, expected, backup); err != nil {
 				return err
 			}
 
-			return os.Rename(outputFile, targetFile)
+			for _, targetStream := range targetStreams {
+				if _, err := fmt.Fprintf(cobraCmd.OutOrStdout(), "Removed stream %s\n", targetStream.ID); err != nil {
+					return err
+				}
+			}
+			return nil
 		},
 	}
 
-	cmd.Flags().StringVar(&streamID, "id", "", "Target subtitle stream id (pure number)")
-	_ = cmd.MarkFlagRequired("id")
+	cmd.Flags().StringVar(&streamIDs, "id", "", "Comma separated subtitle stream ids (pure numbers)")
+	cmd.Flags().StringVar(&languages, "language", "", "Comma separated language tags to remove, or !tag to keep only those languages")
+	cmd.Flags().StringVar(&formats, "format", "", "Comma separated subtitle codecs or formats to remove (e.g. hdmv_pgs_subtitle, srt)")
+	cmd.Flags().BoolVar(&removeAll, "all", false, "Remove all subtitle streams")
+	cmd.MarkFlagsOneRequired("id", "language", "format", "all")
+
+	addBackupFlag(cmd, &backup)
 
 	return cmd
 }
+
+func isNumericStreamID(streamID string) bool {
+	if streamID == "" {
+		return false
+	}
+	for _, r := range streamID {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func removalSubject(streams []mkvStreamInfo) string {
+	if len(streams) == 1 {
+		return "stream " + streams[0].ID
+	}
+	ids := make([]string, 0, len(streams))
+	for _, stream := range streams {
+		ids = append(ids, stream.ID)
+	}
+	return "streams " + strings.Join(ids, ", ")
+}
+
+func removalPrompt(streams []mkvStreamInfo) string {
+	describe := func(stream mkvStreamInfo) string {
+		return fmt.Sprintf(
+			"id=%s, type=%s, language=%s, format=%s, title=%s",
+			stream.ID,
+			stream.Type,
+			displayOrEmpty(stream.Language),
+			displayOrEmpty(stream.SubtitleFormat),
+			displayOrEmpty(stream.Title),
+		)
+	}
+
+	if len(streams) == 1 {
+		return "This will remove stream " + describe(streams[0])
+	}
+
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "This will remove %d subtitle streams:\n", len(streams))
+	for _, stream := range streams {
+		prompt.WriteString("  " + describe(stream) + "\n")
+	}
+	prompt.WriteString("Continue?")
+	return prompt.String()
+}
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/cuimingda/subs-cli/internal/mkv"
 )
 
 func TestRemoveCommand_DeletesSubtitleAfterConfirm(t *testing.T) {
@@ -168,8 +170,8 @@ func TestRemoveCommand_RejectsNonMkvTarget(t *testing.T) {
 	if err == nil {
 		t.Fatalf("expected non-mkv target error, got nil")
 	}
-	if !strings.Contains(err.Error(), "file must be an mkv file") {
-		t.Fatalf("error = %q, want file must be an mkv file", err)
+	if !strings.Contains(err.Error(), "file must be an mkv or mp4 file") {
+		t.Fatalf("error = %q, want file must be an mkv or mp4 file", err)
 	}
 }
 
@@ -269,7 +271,7 @@ func TestRemoveCommand_RejectsWhenFFmpegMissing(t *testing.T) {
 	}
 }
 
-func TestRemoveCommand_RejectsMissingIDFlag(t *testing.T) {
+func TestRemoveCommand_RejectsMissingSelection(t *testing.T) {
 	cmd := NewRootCmd()
 	tmpDir := t.TempDir()
 	target := filepath.Join(tmpDir, "sample.mkv")
@@ -283,11 +285,156 @@ func TestRemoveCommand_RejectsMissingIDFlag(t *testing.T) {
 
 	err := cmd.Execute()
 	if err == nil {
-		t.Fatalf("expected missing id flag error, got nil")
+		t.Fatalf("expected missing selection error, got nil")
+	}
+	if !strings.Contains(err.Error(), "at least one of the flags in the group [id language format all] is required") {
+		t.Fatalf("error = %q, want missing selection flags", err)
+	}
+}
+
+func TestRemoveCommand_RemovesMultipleStreamsInSinglePass(t *testing.T) {
+	skipRemuxVerification(t)
+	samplePath := resolveTestMkvPath(t)
+	if samplePath == "" {
+		t.Skip("skip remove command test: test mkv not found")
+	}
+
+	target := filepath.Join(t.TempDir(), filepath.Base(samplePath))
+	if err := copyFile(samplePath, target); err != nil {
+		t.Fatalf("copy target failed: %v", err)
+	}
+
+	runner := &remuxFFmpegRunner{}
+	mkv.SetFFmpegRunner(runner)
+	t.Cleanup(func() { mkv.SetFFmpegRunner(nil) })
+
+	cmd := NewRootCmd()
+	var out bytes.Buffer
+	var errOut bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&errOut)
+	cmd.SetIn(strings.NewReader("y\n"))
+	cmd.SetArgs([]string{"remove", target, "--id", "3,5", "--language", "spa"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	for _, id := range []string{"0:3", "0:5", "0:6"} {
+		if !containsRemoveArgPair(runner.args, "-map", "-"+id) {
+			t.Fatalf("expected %s to be removed in the same pass: %#v", id, runner.args)
+		}
+		if !strings.Contains(out.String(), "Removed stream "+id) {
+			t.Fatalf("output = %q, want removal of %s", out.String(), id)
+		}
+	}
+	if !strings.Contains(errOut.String(), "This will remove 3 subtitle streams:") {
+		t.Fatalf("prompt = %q, want removal preview", errOut.String())
+	}
+	if !strings.Contains(errOut.String(), "id=0:6, type=Subtitle, language=spa") {
+		t.Fatalf("prompt = %q, want stream details", errOut.String())
+	}
+}
+
+func TestRemoveCommand_KeepsOnlySelectedLanguages(t *testing.T) {
+	skipRemuxVerification(t)
+	samplePath := resolveTestMkvPath(t)
+	if samplePath == "" {
+		t.Skip("skip remove command test: test mkv not found")
+	}
+
+	target := filepath.Join(t.TempDir(), filepath.Base(samplePath))
+	if err := copyFile(samplePath, target); err != nil {
+		t.Fatalf("copy target failed: %v", err)
+	}
+
+	runner := &remuxFFmpegRunner{}
+	mkv.SetFFmpegRunner(runner)
+	t.Cleanup(func() { mkv.SetFFmpegRunner(nil) })
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetIn(strings.NewReader("y\n"))
+	cmd.SetArgs([]string{"remove", target, "--language", "!eng,!ger"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	for _, kept := range []string{"0:2", "0:4", "0:1", "0:8"} {
+		if containsRemoveArgPair(runner.args, "-map", "-"+kept) {
+			t.Fatalf("stream %s should be kept: %#v", kept, runner.args)
+		}
+	}
+	for _, removed := range []string{"0:3", "0:5", "0:6", "0:7", "0:10"} {
+		if !containsRemoveArgPair(runner.args, "-map", "-"+removed) {
+			t.Fatalf("stream %s should be removed: %#v", removed, runner.args)
+		}
+	}
+}
+
+func TestRemoveCommand_DeclineKeepsFileUntouched(t *testing.T) {
+	samplePath := resolveTestMkvPath(t)
+	if samplePath == "" {
+		t.Skip("skip remove command test: test mkv not found")
+	}
+
+	target := filepath.Join(t.TempDir(), filepath.Base(samplePath))
+	if err := copyFile(samplePath, target); err != nil {
+		t.Fatalf("copy target failed: %v", err)
+	}
+
+	runner := &remuxFFmpegRunner{}
+	mkv.SetFFmpegRunner(runner)
+	t.Cleanup(func() { mkv.SetFFmpegRunner(nil) })
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetIn(strings.NewReader("n\n"))
+	cmd.SetArgs([]string{"remove", target, "--all"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
 	}
-	if !strings.Contains(err.Error(), "required flag(s) \"id\" not set") {
-		t.Fatalf("error = %q, want required flag(s) \"id\" not set", err)
+	if runner.args != nil {
+		t.Fatalf("ffmpeg should not run after declining: %#v", runner.args)
+	}
+}
+
+func TestRemoveCommand_ReportsNoMatchingStreams(t *testing.T) {
+	samplePath := resolveTestMkvPath(t)
+	if samplePath == "" {
+		t.Skip("skip remove command test: test mkv not found")
+	}
+
+	target := filepath.Join(t.TempDir(), filepath.Base(samplePath))
+	if err := copyFile(samplePath, target); err != nil {
+		t.Fatalf("copy target failed: %v", err)
+	}
+
+	cmd := NewRootCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"remove", target, "--format", "hdmv_pgs_subtitle"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "No subtitle streams match the selection.") {
+		t.Fatalf("output = %q, want no match message", out.String())
+	}
+}
+
+func containsRemoveArgPair(args []string, key, value string) bool {
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == key && args[i+1] == value {
+			return true
+		}
 	}
+	return false
 }
 
 func TestRemoveCommand_UppercaseMkvExtension(t *testing.T) {
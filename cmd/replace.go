@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cuimingda/subs-cli/internal/mkv"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	backupOrig  = "orig"
+	backupTrash = "trash"
+)
+
+func addBackupFlag(cmd *cobra.Command, backup *string) {
+	cmd.Flags().StringVar(backup, "backup", "", "Keep the original file as <file>.orig, or move it to the system trash with --backup=trash")
+	cmd.Flags().Lookup("backup").NoOptDefVal = backupOrig
+}
+
+func validateBackupMode(backup string) error {
+	switch backup {
+	case "", backupOrig, backupTrash:
+		return nil
+	}
+	return fmt.Errorf("invalid backup mode: %s (want orig or trash)", backup)
+}
+
+// replaceOriginal renames outputFile over targetFile. The new file gets the
+// times, permissions and, where possible, the ownership and extended
+// attributes of targetFile, so media servers do not see it as changed
+// content. With a backup mode the original is kept as <file>.orig or moved
+// to the trash first.
+func replaceOriginal(targetFile, outputFile, backup string) error {
+	info, err := os.Stat(targetFile)
+	if err != nil {
+		return err
+	}
+	if err := preserveFileAttributes(targetFile, info, outputFile); err != nil {
+		return err
+	}
+
+	switch backup {
+	case backupOrig:
+		backupFile := resolveBackupPath(targetFile)
+		if err := os.Rename(targetFile, backupFile); err != nil {
+			return err
+		}
+		if err := os.Rename(outputFile, targetFile); err != nil {
+			_ = os.Rename(backupFile, targetFile)
+			return err
+		}
+		return nil
+	case backupTrash:
+		if err := moveToTrash(targetFile); err != nil {
+			_ = mkv.RemoveTempOutputIfExists(outputFile)
+			return fmt.Errorf("failed to move %s to trash, original left untouched: %w", targetFile, err)
+		}
+	}
+	return os.Rename(outputFile, targetFile)
+}
+
+// resolveBackupPath returns <file>.orig, or <file>.N.orig when older backups
+// exist, so the first original is never overwritten.
+func resolveBackupPath(fileName string) string {
+	candidate := fileName + ".orig"
+	for counter := 2; ; counter++ {
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s.%d.orig", fileName, counter)
+	}
+}
+
+func preserveFileAttributes(sourceFile string, info os.FileInfo, targetFile string) error {
+	// Ownership and extended attributes are best effort: they often need
+	// privileges or file system support the user does not have.
+	_ = copyFileOwnership(info, targetFile)
+	_ = copyExtendedAttributes(sourceFile, targetFile)
+	if err := os.Chmod(targetFile, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(targetFile, fileAccessTime(info), info.ModTime())
+}
+
+// editTracksInPlace edits targetFile without a remux and keeps its times,
+// or only plans the edit in a dry run. With a backup mode the original has to
+// survive, so it reports mkv.ErrInPlaceEditUnavailable and the caller remuxes
+// instead.
+func editTracksInPlace(mode runMode, targetFile string, edits []mkv.TrackEdit, backup string) error {
+	if backup != "" {
+		return fmt.Errorf("%w: --backup keeps the original file", mkv.ErrInPlaceEditUnavailable)
+	}
+	if mode.dryRun {
+		if err := mkv.CheckTracksEditInPlace(targetFile, edits); err != nil {
+			return err
+		}
+		return mode.printPlan("# edit the track headers of " + shellQuote(targetFile) + " in place")
+	}
+	info, err := os.Stat(targetFile)
+	if err != nil {
+		return err
+	}
+	if err := mkv.EditTracksInPlace(targetFile, edits); err != nil {
+		return err
+	}
+	return os.Chtimes(targetFile, fileAccessTime(info), info.ModTime())
+}
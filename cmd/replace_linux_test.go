@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestReplaceOriginal_CopiesExtendedAttributes(t *testing.T) {
+	target, output := writeReplaceTestFiles(t)
+	if err := syscall.Setxattr(target, "user.subs.test", []byte("kept"), 0); err != nil {
+		t.Skipf("file system does not support user xattrs: %v", err)
+	}
+
+	if err := replaceOriginal(target, output, ""); err != nil {
+		t.Fatalf("replaceOriginal() error = %v", err)
+	}
+
+	value := make([]byte, 16)
+	size, err := syscall.Getxattr(target, "user.subs.test", value)
+	if err != nil || string(value[:size]) != "kept" {
+		t.Fatalf("xattr = %q, %v, want kept", value[:max(size, 0)], err)
+	}
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cuimingda/subs-cli/internal/mkv"
+)
+
+func writeReplaceTestFiles(t *testing.T) (string, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	target := filepath.Join(dir, "movie.mkv")
+	output := mkvMergeOutputPath(target)
+	if err := os.WriteFile(target, []byte("original"), 0o640); err != nil {
+		t.Fatalf("write target failed: %v", err)
+	}
+	if err := os.Chmod(target, 0o640); err != nil {
+		t.Fatalf("chmod target failed: %v", err)
+	}
+	if err := os.WriteFile(output, []byte("remuxed"), 0o644); err != nil {
+		t.Fatalf("write output failed: %v", err)
+	}
+	return target, output
+}
+
+func TestReplaceOriginal_PreservesTimesAndPermissions(t *testing.T) {
+	target, output := writeReplaceTestFiles(t)
+	modTime := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	accessTime := time.Date(2021, 6, 2, 8, 30, 0, 0, time.UTC)
+	if err := os.Chtimes(target, accessTime, modTime); err != nil {
+		t.Fatalf("chtimes failed: %v", err)
+	}
+
+	if err := replaceOriginal(target, output, ""); err != nil {
+		t.Fatalf("replaceOriginal() error = %v", err)
+	}
+
+	// Stat before reading, which updates the access time.
+	info, err := os.Stat(target)
+	if err != nil {
+		t.Fatalf("stat target failed: %v", err)
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != "remuxed" {
+		t.Fatalf("target = %q, %v, want remuxed", data, err)
+	}
+	if !info.ModTime().Equal(modTime) {
+		t.Fatalf("mtime = %v, want %v", info.ModTime(), modTime)
+	}
+	if runtime.GOOS != "windows" {
+		if info.Mode().Perm() != 0o640 {
+			t.Fatalf("mode = %v, want 0640", info.Mode().Perm())
+		}
+		if got := fileAccessTime(info); !got.Equal(accessTime) {
+			t.Fatalf("atime = %v, want %v", got, accessTime)
+		}
+	}
+	if _, err := os.Stat(target + ".orig"); !os.IsNotExist(err) {
+		t.Fatalf("unexpected backup: %v", err)
+	}
+}
+
+func TestReplaceOriginal_KeepsOrigBackups(t *testing.T) {
+	target, output := writeReplaceTestFiles(t)
+
+	if err := replaceOriginal(target, output, backupOrig); err != nil {
+		t.Fatalf("replaceOriginal() error = %v", err)
+	}
+	if err := os.WriteFile(output, []byte("remuxed again"), 0o644); err != nil {
+		t.Fatalf("write output failed: %v", err)
+	}
+	if err := replaceOriginal(target, output, backupOrig); err != nil {
+		t.Fatalf("second replaceOriginal() error = %v", err)
+	}
+
+	for fileName, want := range map[string]string{
+		target:             "remuxed again",
+		target + ".orig":   "original",
+		target + ".2.orig": "remuxed",
+	} {
+		if data, err := os.ReadFile(fileName); err != nil || string(data) != want {
+			t.Fatalf("%s = %q, %v, want %q", fileName, data, err, want)
+		}
+	}
+}
+
+func TestReplaceOriginal_MovesOriginalToTrash(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("trash location is only redirected on linux")
+	}
+	target, output := writeReplaceTestFiles(t)
+	dataHome := filepath.Join(filepath.Dir(target), "data")
+	t.Setenv("XDG_DATA_HOME", dataHome)
+
+	if err := replaceOriginal(target, output, backupTrash); err != nil {
+		t.Fatalf("replaceOriginal() error = %v", err)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dataHome, "Trash", "files", "movie.mkv")); err != nil || string(data) != "original" {
+		t.Fatalf("trashed original = %q, %v", data, err)
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != "remuxed" {
+		t.Fatalf("target = %q, %v, want remuxed", data, err)
+	}
+}
+
+func TestEditTracksInPlace_RemuxesWithBackup(t *testing.T) {
+	err := editTracksInPlace(runMode{}, "movie.mkv", nil, backupOrig)
+	if !errors.Is(err, mkv.ErrInPlaceEditUnavailable) {
+		t.Fatalf("editTracksInPlace() error = %v, want ErrInPlaceEditUnavailable", err)
+	}
+}
+
+func TestTrackSetCommand_BackupKeepsOriginal(t *testing.T) {
+	samplePath := resolveTestMkvPath(t)
+	if samplePath == "" {
+		t.Skip("skip track set command test: test mkv not found")
+	}
+
+	target := filepath.Join(t.TempDir(), filepath.Base(samplePath))
+	if err := copyFile(samplePath, target); err != nil {
+		t.Fatalf("copy target failed: %v", err)
+	}
+	original, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("read target failed: %v", err)
+	}
+
+	mkv.SetFFmpegRunner(&remuxFFmpegRunner{})
+	t.Cleanup(func() { mkv.SetFFmpegRunner(nil) })
+	skipRemuxVerification(t)
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"track", "set", target, "--id", "3", "--title", "Commentary", "--backup"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	if data, err := os.ReadFile(target + ".orig"); err != nil || !bytes.Equal(data, original) {
+		t.Fatalf("backup does not hold the original: %v", err)
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != "remuxed" {
+		t.Fatalf("target = %q, %v, want remuxed output", data, err)
+	}
+}
+
+func TestRemoveCommand_RejectsInvalidBackupMode(t *testing.T) {
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"remove", "movie.mkv", "--id", "2", "--backup=zip"})
+
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "invalid backup mode: zip") {
+		t.Fatalf("cmd.Execute() error = %v, want invalid backup mode", err)
+	}
+}
@@ -33,10 +33,16 @@ to quickly create a Cobra application.`,
 	rootCmd.AddCommand(NewFontCmd())
 	rootCmd.AddCommand(NewInfoCmd())
 	rootCmd.AddCommand(NewExtractCmd())
+	rootCmd.AddCommand(NewAttachmentsCmd())
+	rootCmd.AddCommand(NewChaptersCmd())
 	rootCmd.AddCommand(NewMergeCmd())
 	rootCmd.AddCommand(NewRemoveCmd())
 	rootCmd.AddCommand(NewDefaultCmd())
 	rootCmd.AddCommand(NewForceCmd())
+	rootCmd.AddCommand(NewDispositionCmd())
+	rootCmd.AddCommand(NewTrackCmd())
+	rootCmd.AddCommand(NewUndoCmd())
+	addRunModeFlags(rootCmd)
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 
 	return rootCmd
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cuimingda/subs-cli/internal/mkv"
+	"github.com/cuimingda/subs-cli/internal/subtitles"
+
+	"github.com/spf13/cobra"
+)
+
+// runMode carries the global --dry-run and --verbose flags. Dry-run plans
+// are printed to out; verbose command echoes and ffmpeg output go to errOut.
+type runMode struct {
+	dryRun  bool
+	verbose bool
+	out     io.Writer
+	errOut  io.Writer
+}
+
+func addRunModeFlags(rootCmd *cobra.Command) {
+	rootCmd.PersistentFlags().Bool("dry-run", false, "Print the ffmpeg commands and file changes instead of running them")
+	rootCmd.PersistentFlags().Bool("verbose", false, "Echo ffmpeg commands as they run, along with ffmpeg's output")
+}
+
+func newRunMode(cmd *cobra.Command) runMode {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	return runMode{dryRun: dryRun, verbose: verbose, out: cmd.OutOrStdout(), errOut: cmd.ErrOrStderr()}
+}
+
+// runFFmpeg runs ffmpeg, echoing the command and its output in verbose mode.
+func (mode runMode) runFFmpeg(args ...string) ([]byte, error) {
+	mode.echoCommand(args)
+	output, err := mkv.RunFFmpeg(args...)
+	mode.echoOutput(output)
+	return output, err
+}
+
+func (mode runMode) echoCommand(ffmpegArgs []string) {
+	if mode.verbose {
+		_, _ = fmt.Fprintf(mode.errOut, "+ %s\n", shellCommand("ffmpeg", ffmpegArgs...))
+	}
+}
+
+func (mode runMode) echoOutput(output []byte) {
+	if mode.verbose && len(output) > 0 {
+		_, _ = mode.errOut.Write(output)
+		if output[len(output)-1] != '\n' {
+			_, _ = fmt.Fprintln(mode.errOut)
+		}
+	}
+}
+
+// printPlan prints one planned shell command or comment of a dry run.
+func (mode runMode) printPlan(line string) error {
+	_, err := fmt.Fprintln(mode.out, line)
+	return err
+}
+
+// printRemuxPlan prints the ffmpeg command of a remux into outputFile and the
+// renames that would replace targetFile with it.
+func (mode runMode) printRemuxPlan(ffmpegArgs []string, targetFile, outputFile, backup string) error {
+	lines := []string{shellCommand("ffmpeg", ffmpegArgs...)}
+	switch backup {
+	case backupOrig:
+		lines = append(lines, shellCommand("mv", targetFile, resolveBackupPath(targetFile)))
+	case backupTrash:
+		lines = append(lines, "# move "+shellQuote(targetFile)+" to the trash")
+	}
+	lines = append(lines, shellCommand("mv", outputFile, targetFile))
+
+	for _, line := range lines {
+		if err := mode.printPlan(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func shellCommand(name string, args ...string) string {
+	quoted := make([]string, 0, len(args)+1)
+	quoted = append(quoted, shellQuote(name))
+	for _, arg := range args {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote quotes arg for a POSIX shell when it contains anything beyond
+// characters that are always safe.
+func shellQuote(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	safe := true
+	for _, r := range arg {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("@%+=:,./_-", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+func addCheckFlag(cmd *cobra.Command, check *bool) {
+	cmd.Flags().BoolVar(check, "check", false, "Change nothing and exit with an error if any file would change")
+}
+
+// reportFileChanges prints planned text rewrites instead of writing them: a
+// unified diff per file in a dry run, otherwise the file names. With check
+// it fails when any file would change.
+func reportFileChanges(mode runMode, check bool, changes []subtitles.FileChange) error {
+	for _, change := range changes {
+		line := "Would update " + change.FileName + "\n"
+		if mode.dryRun {
+			line = change.UnifiedDiff()
+		}
+		if _, err := io.WriteString(mode.out, line); err != nil {
+			return err
+		}
+	}
+
+	if check && len(changes) > 0 {
+		return fmt.Errorf("%d file(s) would change", len(changes))
+	}
+	_, err := fmt.Fprintf(mode.out, "%d file(s) would change\n", len(changes))
+	return err
+}
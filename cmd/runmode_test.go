@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cuimingda/subs-cli/internal/mkv"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"":                   "''",
+		"-map":               "-map",
+		"0:s:1":              "0:s:1",
+		"/tmp/movie.mkv":     "/tmp/movie.mkv",
+		"My Show S01E01.mkv": "'My Show S01E01.mkv'",
+		"language=chi":       "language=chi",
+		"Director's Cut.mkv": `'Director'\''s Cut.mkv'`,
+		"title=简体中文":         "'title=简体中文'",
+		"default+forced":     "default+forced",
+		"$HOME/movie.mkv":    "'$HOME/movie.mkv'",
+	}
+	for arg, want := range tests {
+		if got := shellQuote(arg); got != want {
+			t.Fatalf("shellQuote(%q) = %s, want %s", arg, got, want)
+		}
+	}
+}
+
+func copySampleForDryRun(t *testing.T) (string, []byte) {
+	t.Helper()
+
+	samplePath := resolveTestMkvPath(t)
+	if samplePath == "" {
+		t.Skip("skip dry-run test: test mkv not found")
+	}
+	target := filepath.Join(t.TempDir(), filepath.Base(samplePath))
+	if err := copyFile(samplePath, target); err != nil {
+		t.Fatalf("copy target failed: %v", err)
+	}
+	original, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("read target failed: %v", err)
+	}
+	return target, original
+}
+
+func TestRemoveCommand_DryRunPrintsPlan(t *testing.T) {
+	target, original := copySampleForDryRun(t)
+	runner := &remuxFFmpegRunner{}
+	mkv.SetFFmpegRunner(runner)
+	t.Cleanup(func() { mkv.SetFFmpegRunner(nil) })
+
+	cmd := NewRootCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetIn(strings.NewReader(""))
+	cmd.SetArgs([]string{"remove", target, "--id", "3", "--dry-run"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	outputFile := mkvMergeOutputPath(target)
+	want := "ffmpeg -hide_banner -y -i " + target + " -map 0 -map -0:3 -c copy " + outputFile + "\n" +
+		"mv " + outputFile + " " + target + "\n"
+	if out.String() != want {
+		t.Fatalf("output = %q, want %q", out.String(), want)
+	}
+	if runner.args != nil {
+		t.Fatalf("ffmpeg ran in dry run: %#v", runner.args)
+	}
+	if data, err := os.ReadFile(target); err != nil || !bytes.Equal(data, original) {
+		t.Fatalf("target was modified: %v", err)
+	}
+}
+
+func TestDefaultCommand_DryRunPlansInPlaceEdit(t *testing.T) {
+	target, original := copySampleForDryRun(t)
+
+	cmd := NewRootCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"default", target, "--id", "3", "--dry-run"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	if out.String() != "# edit the track headers of "+target+" in place\n" {
+		t.Fatalf("output = %q, want in-place edit plan", out.String())
+	}
+	if data, err := os.ReadFile(target); err != nil || !bytes.Equal(data, original) {
+		t.Fatalf("target was modified: %v", err)
+	}
+}
+
+func TestMergeCommand_DryRunPrintsBackupRename(t *testing.T) {
+	target, runner := writeTestMP4(t)
+	subtitle := filepath.Join(filepath.Dir(target), "movie.chs.srt")
+	if err := os.WriteFile(subtitle, []byte("1\n00:00:00,000 --> 00:00:01,000\nhello\n"), 0o644); err != nil {
+		t.Fatalf("write subtitle failed: %v", err)
+	}
+
+	cmd := NewRootCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"merge", "--target", target, subtitle, "--backup", "--dry-run"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	outputFile := mkvMergeOutputPath(target)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) < 3 || !strings.HasPrefix(lines[len(lines)-3], "ffmpeg -hide_banner -y -i "+target+" -i "+subtitle+" ") {
+		t.Fatalf("output = %q, want ffmpeg merge command", out.String())
+	}
+	wantRenames := []string{"mv " + target + " " + target + ".orig", "mv " + outputFile + " " + target}
+	if strings.Join(lines[len(lines)-2:], "\n") != strings.Join(wantRenames, "\n") {
+		t.Fatalf("renames = %q, want %q", lines[len(lines)-2:], wantRenames)
+	}
+	for _, call := range runner.calls {
+		if len(call) != 3 {
+			t.Fatalf("ffmpeg ran in dry run: %#v", call)
+		}
+	}
+	if _, err := os.Stat(target + ".orig"); !os.IsNotExist(err) {
+		t.Fatalf("backup created in dry run: %v", err)
+	}
+}
+
+func TestExtractCommand_DryRunPrintsCommands(t *testing.T) {
+	target, _ := copySampleForDryRun(t)
+	mkv.SetFFmpegRunner(&remuxFFmpegRunner{})
+	t.Cleanup(func() { mkv.SetFFmpegRunner(nil) })
+
+	cmd := NewRootCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"extract", target, "--id", "3", "--dry-run"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	outDir := mkvSubtitleOutputDir(target, filepath.Dir(target))
+	if !strings.Contains(out.String(), "mkdir -p "+outDir+"\n") {
+		t.Fatalf("output = %q, want mkdir plan", out.String())
+	}
+	if !strings.Contains(out.String(), "ffmpeg -hide_banner -i "+target+" -map 0:3 -c copy ") {
+		t.Fatalf("output = %q, want ffmpeg extract command", out.String())
+	}
+	if _, err := os.Stat(outDir); !os.IsNotExist(err) {
+		t.Fatalf("output directory created in dry run: %v", err)
+	}
+}
+
+func TestRunFFmpegRemux_VerboseEchoesCommandAndOutput(t *testing.T) {
+	mkv.SetFFmpegRunner(&fakeFFmpegRunner{installed: true, output: "Output #0, matroska"})
+	t.Cleanup(func() { mkv.SetFFmpegRunner(nil) })
+
+	dir := t.TempDir()
+	targetFile := filepath.Join(dir, "My Movie.mkv")
+	outputFile := mkvMergeOutputPath(targetFile)
+
+	var errOut bytes.Buffer
+	if _, err := runFFmpegRemux(runMode{verbose: true, errOut: &errOut}, targetFile, outputFile, []string{"-i", targetFile, outputFile}); err != nil {
+		t.Fatalf("runFFmpegRemux() error = %v", err)
+	}
+
+	want := "+ ffmpeg -i '" + targetFile + "' '" + outputFile + "'\nOutput #0, matroska\n"
+	if errOut.String() != want {
+		t.Fatalf("verbose output = %q, want %q", errOut.String(), want)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/cuimingda/subs-cli/internal/journal"
 	"github.com/cuimingda/subs-cli/internal/subtitles"
 	"github.com/spf13/cobra"
 )
@@ -52,12 +53,26 @@ func NewStyleCmd() *cobra.Command {
 		},
 	}
 
+	var resetCheck bool
 	styleFontResetCmd := &cobra.Command{
 		Use:   "reset",
 		Short: "Reset [V4+ Styles] font names to Microsoft YaHei in ASS files",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			result, err := subtitles.ResetCurrentDirAssStyleFontsToMicrosoftYaHei()
+			if mode := newRunMode(cmd); mode.dryRun || resetCheck {
+				changes, err := subtitles.PlanCurrentDirAssStyleFontReset()
+				if err != nil {
+					return err
+				}
+				return reportFileChanges(mode, resetCheck, changes)
+			}
+
+			var result subtitles.AssStyleFontResetResult
+			err := runJournaled(cmd, func(*journal.Journal) error {
+				var err error
+				result, err = subtitles.ResetCurrentDirAssStyleFontsToMicrosoftYaHei()
+				return err
+			})
 			if err != nil {
 				return err
 			}
@@ -72,6 +87,8 @@ func NewStyleCmd() *cobra.Command {
 		},
 	}
 
+	addCheckFlag(styleFontResetCmd, &resetCheck)
+
 	styleCmd.AddCommand(styleFontCmd)
 	styleFontCmd.AddCommand(styleFontListCmd)
 	styleFontCmd.AddCommand(styleFontResetCmd)
@@ -242,3 +242,70 @@ func TestStyleFontResetCommand_NoArgsForParent(t *testing.T) {
 		t.Fatalf("expected args validation error, got nil")
 	}
 }
+
+func TestStyleFontResetCommand_CheckFailsWhenFilesWouldChange(t *testing.T) {
+	cmd := NewRootCmd()
+	tmpDir := t.TempDir()
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(testPackageDir)
+	})
+
+	original := "[V4+ Styles]\nFormat: Name, Fontname, Fontsize\nStyle: Default,Arial,22\n"
+	if err := os.WriteFile("font-reset.ass", []byte(original), 0o644); err != nil {
+		t.Fatalf("write font-reset.ass failed: %v", err)
+	}
+	if err := os.WriteFile("done.ass", []byte("[V4+ Styles]\nFormat: Name, Fontname, Fontsize\nStyle: Default,Microsoft YaHei,22\n"), 0o644); err != nil {
+		t.Fatalf("write done.ass failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"style", "font", "reset", "--check"})
+
+	err := cmd.Execute()
+	if err == nil || err.Error() != "1 file(s) would change" {
+		t.Fatalf("cmd.Execute() error = %v, want 1 file(s) would change", err)
+	}
+	if got := out.String(); got != "Would update font-reset.ass\n" {
+		t.Fatalf("output = %q, want file list", got)
+	}
+
+	content, err := os.ReadFile("font-reset.ass")
+	if err != nil {
+		t.Fatalf("read file failed: %v", err)
+	}
+	if string(content) != original {
+		t.Fatalf("content = %q, want unchanged", string(content))
+	}
+}
+
+func TestStyleFontResetCommand_CheckPassesWhenNothingWouldChange(t *testing.T) {
+	cmd := NewRootCmd()
+	tmpDir := t.TempDir()
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(testPackageDir)
+	})
+
+	if err := os.WriteFile("done.ass", []byte("[V4+ Styles]\nFormat: Name, Fontname, Fontsize\nStyle: Default,Microsoft YaHei,22\n"), 0o644); err != nil {
+		t.Fatalf("write done.ass failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"style", "font", "reset", "--check"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+	if got := out.String(); got != "0 file(s) would change\n" {
+		t.Fatalf("output = %q, want empty plan", got)
+	}
+}
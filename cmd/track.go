@@ -0,0 +1,19 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+func NewTrackCmd() *cobra.Command {
+	trackCmd := &cobra.Command{
+		Use:   "track",
+		Short: "Mkv track metadata commands",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	trackCmd.AddCommand(NewTrackSetCmd())
+	trackCmd.AddCommand(NewTrackReorderCmd())
+
+	return trackCmd
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cuimingda/subs-cli/internal/mkv"
+
+	"github.com/spf13/cobra"
+)
+
+func NewTrackReorderCmd() *cobra.Command {
+	var orderValue string
+	var languagesValue string
+	var backup string
+
+	cmd := &cobra.Command{
+		Use:   "reorder <mkv_filename>",
+		Short: "Reorder subtitle streams in an mkv file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			targetFile := args[0]
+			mode := newRunMode(cobraCmd)
+
+			if err := validateBackupMode(backup); err != nil {
+				return err
+			}
+
+			if filepath.Ext(targetFile) != ".mkv" && filepath.Ext(targetFile) != ".MKV" {
+				return fmt.Errorf("file must be an mkv file: %s", targetFile)
+			}
+
+			if _, err := os.Stat(targetFile); err != nil {
+				return err
+			}
+
+			streams, err := probeMKVStreams(targetFile)
+			if err != nil {
+				return err
+			}
+
+			var orderedSubtitles []mkvStreamInfo
+			if orderValue != "" {
+				orderedSubtitles, err = mkv.OrderSubtitleStreamsByID(streams, splitCommaList(orderValue))
+			} else {
+				orderedSubtitles, err = mkv.OrderSubtitleStreamsByLanguage(streams, splitCommaList(languagesValue))
+			}
+			if err != nil {
+				return err
+			}
+
+			if !mkv.SubtitleOrderChanged(streams, orderedSubtitles) {
+				_, err := fmt.Fprintln(cobraCmd.OutOrStdout(), "Subtitle streams are already in the requested order.")
+				return err
+			}
+
+			if err := mkv.RequireFFmpegInstalled(); err != nil {
+				return err
+			}
+
+			outputFile := mkvMergeOutputPath(targetFile)
+			ffmpegArgs := mkv.BuildReorderFFmpegArgs(targetFile, streams, orderedSubtitles)
+			if len(ffmpegArgs) == 0 {
+				return fmt.Errorf("failed to build ffmpeg args for reordering %s", targetFile)
+			}
+			ffmpegArgs = append(ffmpegArgs, outputFile)
+
+			if mode.dryRun {
+				return mode.printRemuxPlan(ffmpegArgs, targetFile, outputFile, backup)
+			}
+			if err := mkv.RemoveTempOutputIfExists(outputFile); err != nil {
+				return err
+			}
+			if err := checkRemuxDiskSpace(targetFile); err != nil {
+				return err
+			}
+
+			reorderOutput, err := runFFmpegRemux(mode, targetFile, outputFile, ffmpegArgs)
+			if err != nil {
+				return fmt.Errorf("failed to reorder subtitle streams: %w: %s", err, bytes.TrimSpace(reorderOutput))
+			}
+
+			expected := mkv.ExpectStreamsAfterReorder(streams, orderedSubtitles)
+			if err := replaceWithVerifiedOutput(targetFile, outputFile, expected, backup); err != nil {
+				return err
+			}
+
+			orderedIDs := make([]string, 0, len(orderedSubtitles))
+			for _, stream := range orderedSubtitles {
+				orderedIDs = append(orderedIDs, stream.ID)
+			}
+			_, err = fmt.Fprintf(cobraCmd.OutOrStdout(), "Reordered subtitle streams: %s\n", strings.Join(orderedIDs, ", "))
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&orderValue, "order", "", "Comma separated subtitle stream ids in the new order")
+	cmd.Flags().StringVar(&languagesValue, "languages", "", "Comma separated language tags in priority order")
+	cmd.MarkFlagsMutuallyExclusive("order", "languages")
+	cmd.MarkFlagsOneRequired("order", "languages")
+
+	addBackupFlag(cmd, &backup)
+
+	return cmd
+}
+
+func splitCommaList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
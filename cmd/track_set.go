@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cuimingda/subs-cli/internal/mkv"
+
+	"github.com/spf13/cobra"
+)
+
+func NewTrackSetCmd() *cobra.Command {
+	var streamID string
+	var languageTag string
+	var trackTitle string
+	var backup string
+
+	cmd := &cobra.Command{
+		Use:   "set <mkv_filename>",
+		Short: "Set language and title of a subtitle or audio stream in an mkv file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			targetFile := args[0]
+			mode := newRunMode(cobraCmd)
+
+			if err := validateBackupMode(backup); err != nil {
+				return err
+			}
+
+			if filepath.Ext(targetFile) != ".mkv" && filepath.Ext(targetFile) != ".MKV" {
+				return fmt.Errorf("file must be an mkv file: %s", targetFile)
+			}
+
+			if _, err := os.Stat(targetFile); err != nil {
+				return err
+			}
+
+			var language, title *string
+			if cobraCmd.Flags().Changed("language") {
+				if !validLanguageTag(languageTag) {
+					return fmt.Errorf("invalid language tag: %s", languageTag)
+				}
+				language = &languageTag
+			}
+			if cobraCmd.Flags().Changed("title") {
+				title = &trackTitle
+			}
+			if language == nil && title == nil {
+				return fmt.Errorf("at least one of --language or --title is required")
+			}
+
+			streams, err := probeMKVStreams(targetFile)
+			if err != nil {
+				return err
+			}
+
+			targetStream, err := findStreamForTrackEdit(streams, streamID)
+			if err != nil {
+				return err
+			}
+
+			trackIndex, err := mkv.StreamIndex(targetStream.ID)
+			if err != nil {
+				return err
+			}
+			err = editTracksInPlace(mode, targetFile, []mkv.TrackEdit{{Index: trackIndex, Language: language, Name: title}}, backup)
+			if err == nil {
+				if mode.dryRun {
+					return nil
+				}
+				_, err = fmt.Fprintf(cobraCmd.OutOrStdout(), "Updated metadata for stream %s\n", targetStream.ID)
+				return err
+			}
+			if !errors.Is(err, mkv.ErrInPlaceEditUnavailable) {
+				return err
+			}
+
+			if err := mkv.RequireFFmpegInstalled(); err != nil {
+				return err
+			}
+
+			outputFile := mkvMergeOutputPath(targetFile)
+			ffmpegArgs := mkvTrackMetadataFFmpegArgs(targetFile, targetStream, language, title)
+			ffmpegArgs = append(ffmpegArgs, outputFile)
+
+			if mode.dryRun {
+				return mode.printRemuxPlan(ffmpegArgs, targetFile, outputFile, backup)
+			}
+			if err := mkv.RemoveTempOutputIfExists(outputFile); err != nil {
+				return err
+			}
+			if err := checkRemuxDiskSpace(targetFile); err != nil {
+				return err
+			}
+
+			setOutput, err := runFFmpegRemux(mode, targetFile, outputFile, ffmpegArgs)
+			if err != nil {
+				return fmt.Errorf("failed to set metadata for stream %s: %w: %s", streamID, err, bytes.TrimSpace(setOutput))
+			}
+
+			if err := replaceWithVerifiedOutput(targetFile, outputFile, mkv.ExpectSameStreams(streams), backup); err != nil {
+				return err
+			}
+
+			_, err = fmt.Fprintf(cobraCmd.OutOrStdout(), "Updated metadata for stream %s\n", targetStream.ID)
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&streamID, "id", "", "Target subtitle or audio stream id (pure number)")
+	_ = cmd.MarkFlagRequired("id")
+	cmd.Flags().StringVar(&languageTag, "language", "", "Language tag (lowercase, 3 letters)")
+	cmd.Flags().StringVar(&trackTitle, "title", "", "Track title (empty removes the title)")
+
+	addBackupFlag(cmd, &backup)
+
+	return cmd
+}
@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cuimingda/subs-cli/internal/mkv"
+)
+
+func TestTrackSetCommand_SetsSubtitleLanguageAndTitle(t *testing.T) {
+	samplePath := resolveTestMkvPath(t)
+	if samplePath == "" {
+		t.Skip("skip track set command test: test mkv not found")
+	}
+
+	target := filepath.Join(t.TempDir(), filepath.Base(samplePath))
+	if err := copyFile(samplePath, target); err != nil {
+		t.Fatalf("copy target failed: %v", err)
+	}
+
+	cmd := NewRootCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"track", "set", target, "--id", "3", "--language", "chi", "--title", "简体中文"})
+	t.Setenv("PATH", "/tmp/no-path-for-test")
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	streams, err := probeMKVStreams(target)
+	if err != nil {
+		t.Fatalf("probeMKVStreams() error = %v", err)
+	}
+	var found bool
+	for _, stream := range streams {
+		if stream.ID != "0:3" {
+			continue
+		}
+		found = true
+		if stream.Language != "chi" || stream.Title != "简体中文" {
+			t.Fatalf("stream 0:3 = %+v, want chi/简体中文", stream)
+		}
+	}
+	if !found {
+		t.Fatalf("stream 0:3 not found after edit")
+	}
+	if !strings.Contains(out.String(), "Updated metadata for stream 0:3") {
+		t.Fatalf("output = %q, want update message", out.String())
+	}
+}
+
+func TestTrackSetCommand_SetsAudioTitleOnly(t *testing.T) {
+	samplePath := resolveTestMkvPath(t)
+	if samplePath == "" {
+		t.Skip("skip track set command test: test mkv not found")
+	}
+
+	target := filepath.Join(t.TempDir(), filepath.Base(samplePath))
+	if err := copyFile(samplePath, target); err != nil {
+		t.Fatalf("copy target failed: %v", err)
+	}
+
+	before, err := probeMKVStreams(target)
+	if err != nil {
+		t.Fatalf("probeMKVStreams() before error = %v", err)
+	}
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"track", "set", target, "--id", "1", "--title", "Stereo"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	after, err := probeMKVStreams(target)
+	if err != nil {
+		t.Fatalf("probeMKVStreams() after error = %v", err)
+	}
+	if after[1].Title != "Stereo" {
+		t.Fatalf("audio title = %q, want Stereo", after[1].Title)
+	}
+	if after[1].Language != before[1].Language {
+		t.Fatalf("audio language changed from %q to %q", before[1].Language, after[1].Language)
+	}
+}
+
+func TestTrackSetCommand_RejectsVideoStream(t *testing.T) {
+	samplePath := resolveTestMkvPath(t)
+	if samplePath == "" {
+		t.Skip("skip track set command test: test mkv not found")
+	}
+
+	target := filepath.Join(t.TempDir(), filepath.Base(samplePath))
+	if err := copyFile(samplePath, target); err != nil {
+		t.Fatalf("copy target failed: %v", err)
+	}
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"track", "set", target, "--id", "0", "--language", "chi"})
+
+	err := cmd.Execute()
+	if err == nil {
+		t.Fatalf("expected video stream error, got nil")
+	}
+	if !strings.Contains(err.Error(), "is not a subtitle or audio stream") {
+		t.Fatalf("error = %q, want not subtitle or audio stream", err)
+	}
+}
+
+func TestTrackSetCommand_RejectsInvalidLanguageTag(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "target.mkv")
+	if err := os.WriteFile(tmpFile, []byte("mock mkv"), 0o644); err != nil {
+		t.Fatalf("write target.mkv failed: %v", err)
+	}
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"track", "set", tmpFile, "--id", "2", "--language", "Chinese"})
+
+	err := cmd.Execute()
+	if err == nil {
+		t.Fatalf("expected invalid language tag error, got nil")
+	}
+	if err.Error() != "invalid language tag: Chinese" {
+		t.Fatalf("error = %q, want invalid language tag", err)
+	}
+}
+
+func TestTrackSetCommand_RequiresLanguageOrTitle(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "target.mkv")
+	if err := os.WriteFile(tmpFile, []byte("mock mkv"), 0o644); err != nil {
+		t.Fatalf("write target.mkv failed: %v", err)
+	}
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"track", "set", tmpFile, "--id", "2"})
+
+	err := cmd.Execute()
+	if err == nil {
+		t.Fatalf("expected missing metadata flags error, got nil")
+	}
+	if !strings.Contains(err.Error(), "--language or --title") {
+		t.Fatalf("error = %q, want missing metadata flags error", err)
+	}
+}
+
+type remuxFFmpegRunner struct {
+	args []string
+}
+
+func (r *remuxFFmpegRunner) IsInstalled() error {
+	return nil
+}
+
+func (r *remuxFFmpegRunner) Run(args ...string) ([]byte, error) {
+	r.args = args
+	return nil, os.WriteFile(args[len(args)-1], []byte("remuxed"), 0o644)
+}
+
+func (r *remuxFFmpegRunner) RunContext(_ context.Context, _ func(mkv.FFmpegProgress), args ...string) ([]byte, error) {
+	return r.Run(args...)
+}
+
+func TestTrackReorderCommand_ReordersByLanguage(t *testing.T) {
+	samplePath := resolveTestMkvPath(t)
+	if samplePath == "" {
+		t.Skip("skip track reorder command test: test mkv not found")
+	}
+
+	target := filepath.Join(t.TempDir(), filepath.Base(samplePath))
+	if err := copyFile(samplePath, target); err != nil {
+		t.Fatalf("copy target failed: %v", err)
+	}
+
+	runner := &remuxFFmpegRunner{}
+	mkv.SetFFmpegRunner(runner)
+	t.Cleanup(func() { mkv.SetFFmpegRunner(nil) })
+	skipRemuxVerification(t)
+
+	cmd := NewRootCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"track", "reorder", target, "--languages", "ita,ger"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+
+	var maps []string
+	for i := 0; i+1 < len(runner.args); i++ {
+		if runner.args[i] == "-map" {
+			maps = append(maps, runner.args[i+1])
+		}
+	}
+	want := "0:0,0:1,0:7,0:4,0:2,0:3,0:5,0:6,0:8,0:9,0:10"
+	if got := strings.Join(maps, ","); got != want {
+		t.Fatalf("maps = %s, want %s", got, want)
+	}
+
+	content, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("read target failed: %v", err)
+	}
+	if string(content) != "remuxed" {
+		t.Fatalf("target was not replaced by the remuxed output")
+	}
+	if _, err := os.Stat(target + ".tmp_subs.mkv"); err == nil {
+		t.Fatalf("temporary output should not remain")
+	}
+	if !strings.Contains(out.String(), "Reordered subtitle streams: 0:7, 0:4, 0:2") {
+		t.Fatalf("output = %q, want reorder summary", out.String())
+	}
+}
+
+func TestTrackReorderCommand_SkipsWhenOrderUnchanged(t *testing.T) {
+	samplePath := resolveTestMkvPath(t)
+	if samplePath == "" {
+		t.Skip("skip track reorder command test: test mkv not found")
+	}
+
+	target := filepath.Join(t.TempDir(), filepath.Base(samplePath))
+	if err := copyFile(samplePath, target); err != nil {
+		t.Fatalf("copy target failed: %v", err)
+	}
+
+	cmd := NewRootCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"track", "reorder", target, "--order", "2,3"})
+	t.Setenv("PATH", "/tmp/no-path-for-test")
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cmd.Execute() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "already in the requested order") {
+		t.Fatalf("output = %q, want unchanged order message", out.String())
+	}
+}
+
+func TestTrackReorderCommand_RejectsBothOrderAndLanguages(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "target.mkv")
+	if err := os.WriteFile(tmpFile, []byte("mock mkv"), 0o644); err != nil {
+		t.Fatalf("write target.mkv failed: %v", err)
+	}
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"track", "reorder", tmpFile, "--order", "3", "--languages", "chi"})
+
+	if err := cmd.Execute(); err == nil {
+		t.Fatalf("expected mutually exclusive flags error, got nil")
+	}
+}
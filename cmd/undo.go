@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cuimingda/subs-cli/internal/journal"
+	"github.com/cuimingda/subs-cli/internal/subtitles"
+
+	"github.com/spf13/cobra"
+)
+
+func NewUndoCmd() *cobra.Command {
+	var list bool
+
+	cmd := &cobra.Command{
+		Use:   "undo [<id>]",
+		Short: "Revert the file changes of an earlier command",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			out := cobraCmd.OutOrStdout()
+
+			if list {
+				if len(args) > 0 {
+					return fmt.Errorf("--list does not take an id")
+				}
+				entries, err := journal.List()
+				if err != nil {
+					return err
+				}
+				if len(entries) == 0 {
+					_, err := fmt.Fprintln(out, "No journal entries.")
+					return err
+				}
+				for _, entry := range entries {
+					writes, renames := entry.Counts()
+					if _, err := fmt.Fprintf(out, "%s  %s  %s  (%d file(s), %d rename(s) in %s)\n", entry.ID, entry.Time.Format("2006-01-02 15:04:05"), entry.Command, writes, renames, entry.Dir); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+
+			id := ""
+			if len(args) > 0 {
+				id = args[0]
+			}
+			entry, err := journal.Load(id)
+			if err != nil {
+				return err
+			}
+
+			if mode := newRunMode(cobraCmd); mode.dryRun {
+				pending, err := journal.Plan(entry)
+				if err != nil {
+					return err
+				}
+				for _, op := range pending {
+					line := "# restore " + op.Path
+					if op.Kind == journal.OpRename {
+						line = shellCommand("mv", op.To, op.From)
+					} else if op.Saved == "" {
+						line = shellCommand("rm", op.Path)
+					}
+					if err := mode.printPlan(line); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+
+			pending, err := journal.Undo(entry)
+			if err != nil {
+				return err
+			}
+			restored, reverted := 0, 0
+			for _, op := range pending {
+				if op.Kind == journal.OpRename {
+					reverted++
+				} else {
+					restored++
+				}
+			}
+			_, err = fmt.Fprintf(out, "Undid %s (%s): restored %d file(s), reverted %d rename(s).\n", entry.ID, entry.Command, restored, reverted)
+			return err
+		},
+	}
+
+	cmd.Flags().BoolVar(&list, "list", false, "List journal entries, newest first")
+
+	return cmd
+}
+
+// runJournaled runs run with every file rewrite and rename recorded in a new
+// journal entry, and tells the user how to undo it.
+func runJournaled(cmd *cobra.Command, run func(entry *journal.Journal) error) error {
+	entry, err := journal.Begin(cmd.CommandPath())
+	if err != nil {
+		return fmt.Errorf("cannot open journal: %w", err)
+	}
+
+	subtitles.SetJournal(entry)
+	runErr := run(entry)
+	subtitles.SetJournal(nil)
+
+	if entry.ID() != "" {
+		if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "Recorded as %s; run `subs undo %s` to revert.\n", entry.ID(), entry.ID()); err != nil {
+			return errors.Join(runErr, err)
+		}
+	}
+	return runErr
+}
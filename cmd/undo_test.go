@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cuimingda/subs-cli/internal/journal"
+)
+
+// TestMain keeps the journal of every command run by the tests out of the
+// user's state directory.
+func TestMain(m *testing.M) {
+	stateHome, err := os.MkdirTemp("", "subs-state-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	_ = os.Setenv("XDG_STATE_HOME", stateHome)
+
+	code := m.Run()
+	_ = os.RemoveAll(stateHome)
+	os.Exit(code)
+}
+
+func TestUndoCommand_RevertsStyleFontReset(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	tmpDir := t.TempDir()
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(testPackageDir)
+	})
+
+	original := "[V4+ Styles]\nFormat: Name, Fontname, Fontsize\nStyle: Default,Arial,22\n"
+	if err := os.WriteFile("a.ass", []byte(original), 0o644); err != nil {
+		t.Fatalf("write a.ass failed: %v", err)
+	}
+
+	cmd := NewRootCmd()
+	var errOut bytes.Buffer
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&errOut)
+	cmd.SetArgs([]string{"style", "font", "reset"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("reset error = %v", err)
+	}
+	if !strings.Contains(errOut.String(), "run `subs undo ") {
+		t.Fatalf("stderr = %q, want undo hint", errOut.String())
+	}
+
+	var listOut bytes.Buffer
+	cmd = NewRootCmd()
+	cmd.SetOut(&listOut)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"undo", "--list"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("undo --list error = %v", err)
+	}
+	if !strings.Contains(listOut.String(), "subs style font reset  (1 file(s), 0 rename(s) in ") {
+		t.Fatalf("list output = %q, want reset entry", listOut.String())
+	}
+
+	var out bytes.Buffer
+	cmd = NewRootCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"undo"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("undo error = %v", err)
+	}
+	if !strings.Contains(out.String(), "restored 1 file(s), reverted 0 rename(s).") {
+		t.Fatalf("output = %q, want undo summary", out.String())
+	}
+
+	content, err := os.ReadFile("a.ass")
+	if err != nil {
+		t.Fatalf("read a.ass failed: %v", err)
+	}
+	if string(content) != original {
+		t.Fatalf("content = %q, want %q", content, original)
+	}
+}
+
+func TestUndoCommand_RevertsFileRename(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	tmpDir := t.TempDir()
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(testPackageDir)
+	})
+
+	for _, name := range []string{"sub S02E04.srt", "Show.S02E04.mkv"} {
+		if err := os.WriteFile(name, []byte("x"), 0o644); err != nil {
+			t.Fatalf("write %s failed: %v", name, err)
+		}
+	}
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"file", "rename"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("rename error = %v", err)
+	}
+	if _, err := os.Stat("Show.S02E04.srt"); err != nil {
+		t.Fatalf("expected renamed subtitle: %v", err)
+	}
+
+	entries, err := journal.List()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("journal.List() = %v, %v, want one entry", entries, err)
+	}
+
+	var out bytes.Buffer
+	cmd = NewRootCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"undo", "--dry-run", entries[0].ID})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("undo --dry-run error = %v", err)
+	}
+	if !strings.Contains(out.String(), "mv ") || !strings.Contains(out.String(), "/Show.S02E04.srt") {
+		t.Fatalf("dry-run output = %q, want mv plan", out.String())
+	}
+	if _, err := os.Stat("Show.S02E04.srt"); err != nil {
+		t.Fatalf("dry run should not revert the rename: %v", err)
+	}
+
+	cmd = NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"undo", entries[0].ID})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("undo error = %v", err)
+	}
+	if _, err := os.Stat("sub S02E04.srt"); err != nil {
+		t.Fatalf("expected original subtitle name back: %v", err)
+	}
+}
+
+func TestUndoCommand_RefusesWhenFileChangedSince(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	tmpDir := t.TempDir()
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(testPackageDir)
+	})
+
+	if err := os.WriteFile("a.ass", []byte("Dialogue: 0,0:00:00.00,0:00:01.00,Default,,0,0,0,,{\\fnArial}Hi\n"), 0o644); err != nil {
+		t.Fatalf("write a.ass failed: %v", err)
+	}
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"dialogue", "font", "prune"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("prune error = %v", err)
+	}
+
+	edited := []byte("edited by hand\n")
+	if err := os.WriteFile("a.ass", edited, 0o644); err != nil {
+		t.Fatalf("edit a.ass failed: %v", err)
+	}
+
+	cmd = NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"undo"})
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "a.ass changed since") {
+		t.Fatalf("undo error = %v, want changed since", err)
+	}
+
+	content, err := os.ReadFile("a.ass")
+	if err != nil {
+		t.Fatalf("read a.ass failed: %v", err)
+	}
+	if !bytes.Equal(content, edited) {
+		t.Fatalf("content = %q, want the hand edit kept", content)
+	}
+}
+
+func TestUndoCommand_NothingToUndo(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"undo"})
+	if err := cmd.Execute(); !errors.Is(err, journal.ErrNoEntries) {
+		t.Fatalf("undo error = %v, want ErrNoEntries", err)
+	}
+}
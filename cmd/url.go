@@ -73,6 +73,10 @@ func NewFontCmd() *cobra.Command {
 				return err
 			}
 
+			if mode := newRunMode(cmd); mode.dryRun {
+				return mode.printPlan(fmt.Sprintf("# download %s to %s", rawURL, shellQuote(fileName)))
+			}
+
 			if _, err := fmt.Fprintf(cmd.OutOrStdout(), "Downloading %s from URL...\n", fileName); err != nil {
 				return err
 			}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cuimingda/subs-cli/internal/mkv"
+)
+
+// verifyRemuxOutput is swapped out in tests whose fake ffmpeg writes
+// placeholder output.
+var verifyRemuxOutput = mkv.VerifyRemuxOutput
+
+// checkRemuxDiskSpace fails when the directory of targetFile cannot hold a
+// remuxed copy of targetFile plus extraFiles.
+func checkRemuxDiskSpace(targetFile string, extraFiles ...string) error {
+	var required int64
+	for _, fileName := range append([]string{targetFile}, extraFiles...) {
+		info, err := os.Stat(fileName)
+		if err != nil {
+			return err
+		}
+		required += info.Size()
+	}
+	return mkv.CheckFreeDiskSpace(filepath.Dir(targetFile), required)
+}
+
+// replaceWithVerifiedOutput replaces targetFile with outputFile once it
+// matches expected. Otherwise outputFile is removed and targetFile is left
+// untouched.
+func replaceWithVerifiedOutput(targetFile, outputFile string, expected []mkv.ExpectedStream, backup string) error {
+	if err := verifyRemuxOutput(targetFile, outputFile, expected); err != nil {
+		_ = mkv.RemoveTempOutputIfExists(outputFile)
+		return fmt.Errorf("verification of %s failed, original left untouched: %w", outputFile, err)
+	}
+	return replaceOriginal(targetFile, outputFile, backup)
+}
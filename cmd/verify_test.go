@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cuimingda/subs-cli/internal/mkv"
+)
+
+// skipRemuxVerification disables output verification for tests whose fake
+// ffmpeg writes placeholder output instead of a real remux.
+func skipRemuxVerification(t *testing.T) {
+	t.Helper()
+	verifyRemuxOutput = func(string, string, []mkv.ExpectedStream) error { return nil }
+	t.Cleanup(func() { verifyRemuxOutput = mkv.VerifyRemuxOutput })
+}
+
+func TestRemoveCommand_LeavesOriginalWhenVerificationFails(t *testing.T) {
+	samplePath := resolveTestMkvPath(t)
+	if samplePath == "" {
+		t.Skip("skip remove command test: test mkv not found")
+	}
+
+	target := filepath.Join(t.TempDir(), filepath.Base(samplePath))
+	if err := copyFile(samplePath, target); err != nil {
+		t.Fatalf("copy target failed: %v", err)
+	}
+	original, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("read target failed: %v", err)
+	}
+
+	mkv.SetFFmpegRunner(&remuxFFmpegRunner{})
+	t.Cleanup(func() { mkv.SetFFmpegRunner(nil) })
+
+	cmd := NewRootCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetIn(strings.NewReader("y\n"))
+	cmd.SetArgs([]string{"remove", target, "--id", "3"})
+
+	err = cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "failed, original left untouched") {
+		t.Fatalf("cmd.Execute() error = %v, want verification failure", err)
+	}
+	if strings.Contains(out.String(), "Removed stream") {
+		t.Fatalf("output = %q, want no removal report", out.String())
+	}
+	if data, err := os.ReadFile(target); err != nil || !bytes.Equal(data, original) {
+		t.Fatalf("target was modified: %v", err)
+	}
+	if _, err := os.Stat(mkvMergeOutputPath(target)); !os.IsNotExist(err) {
+		t.Fatalf("temp output still exists: %v", err)
+	}
+}
+
+func TestRemuxCommands_LeaveOriginalWhenVerificationFails(t *testing.T) {
+	samplePath := resolveTestMkvPath(t)
+	if samplePath == "" {
+		t.Skip("skip remux command test: test mkv not found")
+	}
+
+	tests := []struct {
+		command []string
+		flags   []string
+	}{
+		{command: []string{"track", "set"}, flags: []string{"--id", "3", "--language", "chi", "--backup"}},
+		{command: []string{"track", "reorder"}, flags: []string{"--languages", "ita,ger"}},
+		{command: []string{"disposition"}, flags: []string{"--id", "4", "--set", "captions"}},
+	}
+	for _, tt := range tests {
+		target := filepath.Join(t.TempDir(), filepath.Base(samplePath))
+		if err := copyFile(samplePath, target); err != nil {
+			t.Fatalf("copy target failed: %v", err)
+		}
+		original, err := os.ReadFile(target)
+		if err != nil {
+			t.Fatalf("read target failed: %v", err)
+		}
+
+		mkv.SetFFmpegRunner(&remuxFFmpegRunner{})
+		t.Cleanup(func() { mkv.SetFFmpegRunner(nil) })
+
+		cmd := NewRootCmd()
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		args := append(append(append([]string{}, tt.command...), target), tt.flags...)
+		cmd.SetArgs(args)
+
+		err = cmd.Execute()
+		if err == nil || !strings.Contains(err.Error(), "failed, original left untouched") {
+			t.Fatalf("%v: cmd.Execute() error = %v, want verification failure", args, err)
+		}
+		if data, err := os.ReadFile(target); err != nil || !bytes.Equal(data, original) {
+			t.Fatalf("%v: target was modified: %v", args, err)
+		}
+		if _, err := os.Stat(target + ".orig"); !os.IsNotExist(err) {
+			t.Fatalf("%v: backup was made: %v", args, err)
+		}
+	}
+}
+
+func TestReplaceWithVerifiedOutput_RenamesMatchingOutput(t *testing.T) {
+	samplePath := resolveTestMkvPath(t)
+	if samplePath == "" {
+		t.Skip("skip verification test: test mkv not found")
+	}
+
+	target := filepath.Join(t.TempDir(), filepath.Base(samplePath))
+	if err := copyFile(samplePath, target); err != nil {
+		t.Fatalf("copy target failed: %v", err)
+	}
+	outputFile := mkvMergeOutputPath(target)
+	if err := copyFile(samplePath, outputFile); err != nil {
+		t.Fatalf("copy output failed: %v", err)
+	}
+	streams, err := mkv.ProbeStreams(target)
+	if err != nil {
+		t.Fatalf("ProbeStreams() error = %v", err)
+	}
+
+	if err := replaceWithVerifiedOutput(target, outputFile, mkv.ExpectSameStreams(streams), ""); err != nil {
+		t.Fatalf("replaceWithVerifiedOutput() error = %v", err)
+	}
+	if _, err := os.Stat(outputFile); !os.IsNotExist(err) {
+		t.Fatalf("temp output still exists: %v", err)
+	}
+
+	if err := copyFile(samplePath, outputFile); err != nil {
+		t.Fatalf("copy output failed: %v", err)
+	}
+	err = replaceWithVerifiedOutput(target, outputFile, mkv.ExpectStreamsAfterRemoval(streams, streams[3:4]), "")
+	if err == nil || !strings.Contains(err.Error(), "output has 11 streams, expected 10") {
+		t.Fatalf("replaceWithVerifiedOutput() error = %v, want stream count mismatch", err)
+	}
+}
+
+func TestCheckRemuxDiskSpace(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "movie.mkv")
+	if err := os.WriteFile(target, []byte("movie"), 0o644); err != nil {
+		t.Fatalf("write target failed: %v", err)
+	}
+
+	if err := checkRemuxDiskSpace(target); err != nil {
+		t.Fatalf("checkRemuxDiskSpace() error = %v", err)
+	}
+	if err := checkRemuxDiskSpace(target, filepath.Join(filepath.Dir(target), "missing.srt")); !os.IsNotExist(err) {
+		t.Fatalf("checkRemuxDiskSpace() error = %v, want missing file error", err)
+	}
+}
@@ -5,6 +5,7 @@ go 1.23.4
 require (
 	github.com/saintfish/chardet v0.0.0-20230101081208-5e3ef4b5456d
 	github.com/spf13/cobra v1.10.2
+	golang.org/x/sys v0.28.0
 	golang.org/x/text v0.21.0
 )
 
@@ -0,0 +1,398 @@
+package journal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	OpWrite  = "write"
+	OpRename = "rename"
+
+	entryFileName = "entry.json"
+)
+
+var ErrNoEntries = errors.New("nothing to undo")
+
+// Entry is the record of one invocation. Operations are kept in the order
+// they were made and are undone in reverse.
+type Entry struct {
+	ID         string      `json:"id"`
+	Command    string      `json:"command"`
+	Dir        string      `json:"dir"`
+	Time       time.Time   `json:"time"`
+	Operations []Operation `json:"operations"`
+}
+
+// Operation is a file rewrite or a rename. For a rewrite, Saved names the
+// copy of the original content in the entry directory; it is empty when the
+// write created Path.
+type Operation struct {
+	Kind       string `json:"kind"`
+	Path       string `json:"path,omitempty"`
+	Saved      string `json:"saved,omitempty"`
+	BeforeHash string `json:"before_hash,omitempty"`
+	AfterHash  string `json:"after_hash,omitempty"`
+	From       string `json:"from,omitempty"`
+	To         string `json:"to,omitempty"`
+}
+
+// Journal records the changes of one invocation. Nothing is written to the
+// state directory until the first change is recorded, and the entry is saved
+// after every change so that an interrupted run can still be undone.
+type Journal struct {
+	root  string
+	dir   string
+	entry Entry
+}
+
+// StateDir returns $XDG_STATE_HOME/subs, or ~/.local/state/subs when
+// XDG_STATE_HOME is not set.
+func StateDir() (string, error) {
+	if stateHome := os.Getenv("XDG_STATE_HOME"); stateHome != "" {
+		return filepath.Join(stateHome, "subs"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state", "subs"), nil
+}
+
+func Begin(command string) (*Journal, error) {
+	root, err := StateDir()
+	if err != nil {
+		return nil, err
+	}
+	workDir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	return &Journal{
+		root: root,
+		entry: Entry{
+			Command: command,
+			Dir:     workDir,
+			Time:    time.Now(),
+		},
+	}, nil
+}
+
+// ID returns the entry id, or "" when nothing has been recorded.
+func (j *Journal) ID() string {
+	return j.entry.ID
+}
+
+// RecordWrite saves the current content of path before it is replaced by
+// data.
+func (j *Journal) RecordWrite(path string, data []byte) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if err := j.ensureDir(); err != nil {
+		return err
+	}
+
+	op := Operation{Kind: OpWrite, Path: absPath, AfterHash: hashBytes(data)}
+	original, err := os.ReadFile(absPath)
+	switch {
+	case err == nil:
+		op.Saved = filepath.Join("files", strconv.Itoa(len(j.entry.Operations)+1))
+		op.BeforeHash = hashBytes(original)
+		if err := os.WriteFile(filepath.Join(j.dir, op.Saved), original, 0o600); err != nil {
+			return err
+		}
+	case !os.IsNotExist(err):
+		return err
+	}
+
+	return j.add(op)
+}
+
+// RecordRename records that from was renamed to to.
+func (j *Journal) RecordRename(from, to string) error {
+	absFrom, err := filepath.Abs(from)
+	if err != nil {
+		return err
+	}
+	absTo, err := filepath.Abs(to)
+	if err != nil {
+		return err
+	}
+	if err := j.ensureDir(); err != nil {
+		return err
+	}
+	return j.add(Operation{Kind: OpRename, From: absFrom, To: absTo})
+}
+
+func (j *Journal) add(op Operation) error {
+	j.entry.Operations = append(j.entry.Operations, op)
+	return writeEntry(j.dir, j.entry)
+}
+
+func (j *Journal) ensureDir() error {
+	if j.dir != "" {
+		return nil
+	}
+	if err := os.MkdirAll(j.root, 0o700); err != nil {
+		return err
+	}
+
+	baseID := j.entry.Time.Format("20060102-150405")
+	for n := 1; ; n++ {
+		id := baseID
+		if n > 1 {
+			id = fmt.Sprintf("%s-%d", baseID, n)
+		}
+		dir := filepath.Join(j.root, id)
+		err := os.Mkdir(dir, 0o700)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := os.Mkdir(filepath.Join(dir, "files"), 0o700); err != nil {
+			return err
+		}
+		j.entry.ID = id
+		j.dir = dir
+		return nil
+	}
+}
+
+func writeEntry(dir string, entry Entry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, entryFileName), data, 0o600)
+}
+
+// List returns the recorded entries, newest first.
+func List() ([]Entry, error) {
+	root, err := StateDir()
+	if err != nil {
+		return nil, err
+	}
+	dirEntries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+		entry, err := readEntry(filepath.Join(root, dirEntry.Name()))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, k int) bool {
+		return entries[i].ID > entries[k].ID
+	})
+	return entries, nil
+}
+
+// Load returns the entry with id, or the newest entry when id is empty.
+func Load(id string) (Entry, error) {
+	if id == "" {
+		entries, err := List()
+		if err != nil {
+			return Entry{}, err
+		}
+		if len(entries) == 0 {
+			return Entry{}, ErrNoEntries
+		}
+		return entries[0], nil
+	}
+
+	root, err := StateDir()
+	if err != nil {
+		return Entry{}, err
+	}
+	if id != filepath.Base(id) {
+		return Entry{}, fmt.Errorf("invalid journal id: %s", id)
+	}
+	entry, err := readEntry(filepath.Join(root, id))
+	if os.IsNotExist(err) {
+		return Entry{}, fmt.Errorf("no journal entry %s", id)
+	}
+	return entry, err
+}
+
+func readEntry(dir string) (Entry, error) {
+	data, err := os.ReadFile(filepath.Join(dir, entryFileName))
+	if err != nil {
+		return Entry{}, err
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return Entry{}, fmt.Errorf("invalid journal entry %s: %w", filepath.Base(dir), err)
+	}
+	return entry, nil
+}
+
+// Plan returns the operations of entry that still need undoing, in the order
+// Undo applies them. A rewritten file that already has its original content
+// is skipped. It fails when any file changed after entry was recorded.
+// Renames are checked against the files left by the reverse renames before
+// them, so a chain such as A→A2, B→A can be undone.
+func Plan(entry Entry) ([]Operation, error) {
+	var pending []Operation
+	var conflicts []string
+	moved := map[string]bool{}
+	exists := func(path string) bool {
+		if present, ok := moved[filepath.Clean(path)]; ok {
+			return present
+		}
+		_, err := os.Stat(path)
+		return err == nil
+	}
+	for i := len(entry.Operations) - 1; i >= 0; i-- {
+		op := entry.Operations[i]
+		switch op.Kind {
+		case OpWrite:
+			current, err := os.ReadFile(op.Path)
+			if os.IsNotExist(err) {
+				conflicts = append(conflicts, op.Path+" no longer exists")
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			switch hashBytes(current) {
+			case op.AfterHash:
+				pending = append(pending, op)
+			case op.BeforeHash:
+			default:
+				conflicts = append(conflicts, op.Path+" changed since")
+			}
+		case OpRename:
+			if !exists(op.To) {
+				conflicts = append(conflicts, op.To+" no longer exists")
+				continue
+			}
+			if exists(op.From) {
+				conflicts = append(conflicts, op.From+" exists again")
+				continue
+			}
+			moved[filepath.Clean(op.To)] = false
+			moved[filepath.Clean(op.From)] = true
+			pending = append(pending, op)
+		default:
+			return nil, fmt.Errorf("unknown journal operation: %s", op.Kind)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("cannot undo %s: %s", entry.ID, strings.Join(conflicts, "; "))
+	}
+	return pending, nil
+}
+
+// Undo reverts entry and removes it from the journal. Nothing is changed when
+// Plan fails.
+func Undo(entry Entry) ([]Operation, error) {
+	pending, err := Plan(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := StateDir()
+	if err != nil {
+		return nil, err
+	}
+	entryDir := filepath.Join(root, entry.ID)
+
+	for _, op := range pending {
+		switch op.Kind {
+		case OpWrite:
+			if op.Saved == "" {
+				if err := os.Remove(op.Path); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			original, err := os.ReadFile(filepath.Join(entryDir, op.Saved))
+			if err != nil {
+				return nil, err
+			}
+			perm := os.FileMode(0o644)
+			if info, err := os.Stat(op.Path); err == nil {
+				perm = info.Mode().Perm()
+			}
+			if err := writeFileAtomic(op.Path, original, perm); err != nil {
+				return nil, err
+			}
+		case OpRename:
+			if err := os.Rename(op.To, op.From); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := os.RemoveAll(entryDir); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// Counts returns the number of rewritten files and renames in entry.
+func (entry Entry) Counts() (writes, renames int) {
+	for _, op := range entry.Operations {
+		if op.Kind == OpRename {
+			renames++
+		} else {
+			writes++
+		}
+	}
+	return writes, renames
+}
+
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
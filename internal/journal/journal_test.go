@@ -0,0 +1,220 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUndo_RestoresRewrittenFiles(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	target := filepath.Join(t.TempDir(), "a.ass")
+	if err := os.WriteFile(target, []byte("before"), 0o640); err != nil {
+		t.Fatalf("write target failed: %v", err)
+	}
+
+	entry, err := Begin("subs style font reset")
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if err := entry.RecordWrite(target, []byte("after")); err != nil {
+		t.Fatalf("RecordWrite() error = %v", err)
+	}
+	if err := os.WriteFile(target, []byte("after"), 0o640); err != nil {
+		t.Fatalf("rewrite target failed: %v", err)
+	}
+
+	loaded, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded.ID != entry.ID() || loaded.Command != "subs style font reset" {
+		t.Fatalf("Load() = %+v, want entry %s", loaded, entry.ID())
+	}
+
+	undone, err := Undo(loaded)
+	if err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+	if len(undone) != 1 {
+		t.Fatalf("Undo() = %v, want one operation", undone)
+	}
+
+	content, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("read target failed: %v", err)
+	}
+	if string(content) != "before" {
+		t.Fatalf("content = %q, want before", content)
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		t.Fatalf("stat target failed: %v", err)
+	}
+	if info.Mode().Perm() != 0o640 {
+		t.Fatalf("mode = %v, want 0640", info.Mode().Perm())
+	}
+
+	if _, err := Load(""); err != ErrNoEntries {
+		t.Fatalf("Load() after undo error = %v, want ErrNoEntries", err)
+	}
+}
+
+func TestUndo_RevertsRenames(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := t.TempDir()
+	from := filepath.Join(dir, "sub.srt")
+	to := filepath.Join(dir, "Show.S01E01.srt")
+	if err := os.WriteFile(to, []byte("1"), 0o644); err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+
+	entry, err := Begin("subs file rename")
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if err := entry.RecordRename(from, to); err != nil {
+		t.Fatalf("RecordRename() error = %v", err)
+	}
+
+	loaded, err := Load(entry.ID())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if _, err := Undo(loaded); err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+
+	if _, err := os.Stat(from); err != nil {
+		t.Fatalf("expected %s to be restored: %v", from, err)
+	}
+	if _, err := os.Stat(to); !os.IsNotExist(err) {
+		t.Fatalf("expected %s to be gone, got %v", to, err)
+	}
+}
+
+func TestUndo_RevertsChainedRenames(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := t.TempDir()
+	a := filepath.Join(dir, "A.S01E01.srt")
+	aMoved := filepath.Join(dir, "A.S01E01.zh-Hans.srt")
+	b := filepath.Join(dir, "B.S01E01.srt")
+
+	// A is renamed away first, then B takes its name.
+	if err := os.WriteFile(aMoved, []byte("a"), 0o644); err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+	if err := os.WriteFile(a, []byte("b"), 0o644); err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+
+	entry, err := Begin("subs file rename")
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if err := entry.RecordRename(a, aMoved); err != nil {
+		t.Fatalf("RecordRename() error = %v", err)
+	}
+	if err := entry.RecordRename(b, a); err != nil {
+		t.Fatalf("RecordRename() error = %v", err)
+	}
+
+	loaded, err := Load(entry.ID())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if _, err := Undo(loaded); err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+
+	for path, want := range map[string]string{a: "a", b: "b"} {
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read %s failed: %v", path, err)
+		}
+		if string(got) != want {
+			t.Fatalf("%s = %q, want %q", path, got, want)
+		}
+	}
+	if _, err := os.Stat(aMoved); !os.IsNotExist(err) {
+		t.Fatalf("expected %s to be gone, got %v", aMoved, err)
+	}
+}
+
+func TestUndo_RefusesWhenFileChangedSince(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := t.TempDir()
+	changed := filepath.Join(dir, "a.srt")
+	untouched := filepath.Join(dir, "b.srt")
+	for _, path := range []string{changed, untouched} {
+		if err := os.WriteFile(path, []byte("before"), 0o644); err != nil {
+			t.Fatalf("write file failed: %v", err)
+		}
+	}
+
+	entry, err := Begin("subs encoding reset")
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	for _, path := range []string{changed, untouched} {
+		if err := entry.RecordWrite(path, []byte("after")); err != nil {
+			t.Fatalf("RecordWrite() error = %v", err)
+		}
+		if err := os.WriteFile(path, []byte("after"), 0o644); err != nil {
+			t.Fatalf("rewrite file failed: %v", err)
+		}
+	}
+	if err := os.WriteFile(changed, []byte("edited by hand"), 0o644); err != nil {
+		t.Fatalf("edit file failed: %v", err)
+	}
+
+	loaded, err := Load(entry.ID())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	_, err = Undo(loaded)
+	if err == nil || !strings.Contains(err.Error(), changed+" changed since") {
+		t.Fatalf("Undo() error = %v, want changed since", err)
+	}
+
+	content, err := os.ReadFile(untouched)
+	if err != nil {
+		t.Fatalf("read file failed: %v", err)
+	}
+	if string(content) != "after" {
+		t.Fatalf("untouched content = %q, want nothing restored", content)
+	}
+	if _, err := Load(entry.ID()); err != nil {
+		t.Fatalf("entry should be kept after a refused undo: %v", err)
+	}
+}
+
+func TestBegin_WritesNothingUntilAChangeIsRecorded(t *testing.T) {
+	stateHome := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", stateHome)
+
+	entry, err := Begin("subs dialogue font prune")
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if entry.ID() != "" {
+		t.Fatalf("ID() = %q, want empty", entry.ID())
+	}
+	if _, err := os.Stat(filepath.Join(stateHome, "subs")); !os.IsNotExist(err) {
+		t.Fatalf("state dir should not exist yet, got %v", err)
+	}
+
+	entries, err := List()
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("List() = %v, %v, want no entries", entries, err)
+	}
+}
+
+func TestLoad_RejectsPathsAsIDs(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	if _, err := Load("../x"); err == nil || !strings.Contains(err.Error(), "invalid journal id") {
+		t.Fatalf("Load() error = %v, want invalid journal id", err)
+	}
+}
@@ -0,0 +1,109 @@
+package mkv
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// AttachedFile is an attachment with the stream id info shows for it.
+type AttachedFile struct {
+	Attachment
+	StreamID string
+}
+
+// ListAttachments lists the attachments of a Matroska file. Other containers
+// have no attachments.
+func ListAttachments(fileName string) ([]AttachedFile, error) {
+	container, err := ReadContainer(fileName)
+	if err != nil {
+		if errors.Is(err, ErrNotMatroska) {
+			return nil, fmt.Errorf("attachments are only supported in mkv files: %s", fileName)
+		}
+		return nil, err
+	}
+
+	// Attachments are numbered after all tracks, as ffmpeg does. Incomplete
+	// attachments get no stream and cannot be dumped, so they are left out.
+	next := 0
+	for _, track := range container.Tracks {
+		if hasStream(track) {
+			next++
+		}
+	}
+	files := make([]AttachedFile, 0, len(container.Attachments))
+	for _, attachment := range container.Attachments {
+		if !attachmentHasStream(attachment) {
+			continue
+		}
+		files = append(files, AttachedFile{
+			Attachment: attachment,
+			StreamID:   "0:" + strconv.Itoa(next),
+		})
+		next++
+	}
+	return files, nil
+}
+
+func IsFontAttachment(attachment Attachment) bool {
+	codec := attachmentCodecName(attachment)
+	return codec == "ttf" || codec == "otf"
+}
+
+// AttachmentFileName returns a file name for attachment that cannot escape
+// the output directory.
+func AttachmentFileName(attachment Attachment) string {
+	name := filepath.Base(strings.ReplaceAll(attachment.FileName, `\`, "/"))
+	if name == "." || name == "/" || strings.TrimSpace(name) == "" {
+		return fmt.Sprintf("attachment_%d", attachment.UID)
+	}
+	return SanitizeFileNamePart(name)
+}
+
+// BuildAttachmentExtractFFmpegArgs dumps attachment to outputPath. ffmpeg
+// only runs with an output file, so the input is also copied to a null
+// output of no length.
+func BuildAttachmentExtractFFmpegArgs(sourceFile string, attachment AttachedFile, outputPath string) []string {
+	return []string{
+		"-hide_banner",
+		"-y",
+		"-dump_attachment:" + StreamIDTail(attachment.StreamID),
+		outputPath,
+		"-i",
+		sourceFile,
+		"-t",
+		"0",
+		"-f",
+		"null",
+		"-",
+	}
+}
+
+// UserFontDir returns the per-user font directory of the current platform,
+// where fonts are picked up without administrator rights.
+func UserFontDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		return filepath.Join(home, "Library", "Fonts"), nil
+	case "windows":
+		localAppData := os.Getenv("LOCALAPPDATA")
+		if localAppData == "" {
+			localAppData = filepath.Join(home, "AppData", "Local")
+		}
+		return filepath.Join(localAppData, "Microsoft", "Windows", "Fonts"), nil
+	}
+
+	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+		return filepath.Join(dataHome, "fonts"), nil
+	}
+	return filepath.Join(home, ".local", "share", "fonts"), nil
+}
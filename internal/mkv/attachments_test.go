@@ -0,0 +1,92 @@
+package mkv
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestListAttachmentsAndExtractArgs(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "movie.mkv")
+	if err := os.WriteFile(fileName, buildTestMatroska(t), 0o644); err != nil {
+		t.Fatalf("write test mkv failed: %v", err)
+	}
+
+	attachments, err := ListAttachments(fileName)
+	if err != nil {
+		t.Fatalf("ListAttachments() error = %v", err)
+	}
+	if len(attachments) != 1 {
+		t.Fatalf("attachment count = %d, want 1", len(attachments))
+	}
+	attachment := attachments[0]
+	if attachment.StreamID != "0:2" || attachment.FileName != "font.ttf" || !IsFontAttachment(attachment.Attachment) {
+		t.Fatalf("unexpected attachment %+v", attachment)
+	}
+
+	args := strings.Join(BuildAttachmentExtractFFmpegArgs(fileName, attachment, "out/font.ttf"), " ")
+	if want := "-hide_banner -y -dump_attachment:2 out/font.ttf -i " + fileName + " -t 0 -f null -"; args != want {
+		t.Fatalf("ffmpeg args = %q, want %q", args, want)
+	}
+}
+
+func TestListAttachments_NumbersOnlyStreamedEntries(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "skipped.mkv")
+	if err := os.WriteFile(fileName, buildSkippedEntriesMatroska(t), 0o644); err != nil {
+		t.Fatalf("write synthesized mkv failed: %v", err)
+	}
+
+	attachments, err := ListAttachments(fileName)
+	if err != nil {
+		t.Fatalf("ListAttachments() error = %v", err)
+	}
+	if len(attachments) != 1 || attachments[0].FileName != "font.ttf" || attachments[0].StreamID != "0:3" {
+		t.Fatalf("unexpected attachments %+v", attachments)
+	}
+}
+
+func TestListAttachments_RejectsOtherContainers(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "movie.mkv")
+	if err := os.WriteFile(fileName, []byte("not matroska"), 0o644); err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+	if _, err := ListAttachments(fileName); err == nil || !strings.Contains(err.Error(), "only supported in mkv files") {
+		t.Fatalf("error = %v, want matroska only error", err)
+	}
+}
+
+func TestAttachmentFileName(t *testing.T) {
+	tests := []struct {
+		attachment Attachment
+		want       string
+	}{
+		{attachment: Attachment{FileName: "Font Regular.otf"}, want: "Font Regular.otf"},
+		{attachment: Attachment{FileName: "../../.bashrc"}, want: ".bashrc"},
+		{attachment: Attachment{FileName: `C:\fonts\a.ttf`}, want: "a.ttf"},
+		{attachment: Attachment{FileName: "a:b.ttf"}, want: "a_b.ttf"},
+		{attachment: Attachment{UID: 7}, want: "attachment_7"},
+	}
+	for _, tt := range tests {
+		if got := AttachmentFileName(tt.attachment); got != tt.want {
+			t.Fatalf("AttachmentFileName(%q) = %q, want %q", tt.attachment.FileName, got, tt.want)
+		}
+	}
+}
+
+func TestUserFontDir_UsesXDGDataHome(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("XDG_DATA_HOME is only used on unix desktops")
+	}
+	dataHome := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+
+	dir, err := UserFontDir()
+	if err != nil {
+		t.Fatalf("UserFontDir() error = %v", err)
+	}
+	if dir != filepath.Join(dataHome, "fonts") {
+		t.Fatalf("UserFontDir() = %q, want fonts under XDG_DATA_HOME", dir)
+	}
+}
@@ -0,0 +1,470 @@
+package mkv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ChapterFormatOGM        = "ogm"
+	ChapterFormatXML        = "xml"
+	ChapterFormatFFMetadata = "ffmetadata"
+)
+
+const ffmetadataHeader = ";FFMETADATA1"
+
+var (
+	chapterTimestampRE = regexp.MustCompile(`^(\d+):(\d{1,2}):(\d{1,2})(?:\.(\d{1,9}))?$`)
+	ogmChapterRE       = regexp.MustCompile(`^CHAPTER(\d+)(NAME)?=(.*)$`)
+)
+
+type xmlChapters struct {
+	XMLName  xml.Name     `xml:"Chapters"`
+	Editions []xmlEdition `xml:"EditionEntry"`
+}
+
+type xmlEdition struct {
+	Atoms []xmlChapterAtom `xml:"ChapterAtom"`
+}
+
+type xmlChapterAtom struct {
+	UID      uint64              `xml:"ChapterUID,omitempty"`
+	Start    string              `xml:"ChapterTimeStart"`
+	End      string              `xml:"ChapterTimeEnd,omitempty"`
+	Hidden   int                 `xml:"ChapterFlagHidden,omitempty"`
+	Displays []xmlChapterDisplay `xml:"ChapterDisplay"`
+	Atoms    []xmlChapterAtom    `xml:"ChapterAtom"`
+}
+
+type xmlChapterDisplay struct {
+	String   string `xml:"ChapterString"`
+	Language string `xml:"ChapterLanguage,omitempty"`
+}
+
+func ValidateChapterFormat(format string) error {
+	switch format {
+	case ChapterFormatOGM, ChapterFormatXML, ChapterFormatFFMetadata:
+		return nil
+	}
+	return fmt.Errorf("unsupported chapter format: %s (use ogm, xml or ffmetadata)", format)
+}
+
+// ReadChapters reads chapters from the Matroska header, or with ffmpeg for
+// other containers. Chapters without an end time end where the next one
+// starts, and the last one at the end of the file.
+func ReadChapters(fileName string) ([]Chapter, error) {
+	container, err := ReadContainer(fileName)
+	if err == nil {
+		return completeChapterEnds(container.Chapters, container.Info.Duration), nil
+	}
+	if !errors.Is(err, ErrNotMatroska) {
+		return nil, err
+	}
+
+	if err := RequireFFmpegInstalled(); err != nil {
+		return nil, err
+	}
+	metadataFile, err := os.CreateTemp("", "subs-chapters-*.txt")
+	if err != nil {
+		return nil, err
+	}
+	metadataPath := metadataFile.Name()
+	metadataFile.Close()
+	defer os.Remove(metadataPath)
+
+	output, err := RunFFmpeg("-hide_banner", "-y", "-i", fileName, "-f", ChapterFormatFFMetadata, metadataPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chapters of %s: %w: %s", fileName, err, bytes.TrimSpace(output))
+	}
+	data, err := os.ReadFile(metadataPath)
+	if err != nil {
+		return nil, err
+	}
+	chapters, err := ParseChapters(data, ChapterFormatFFMetadata)
+	if err != nil {
+		return nil, err
+	}
+	return completeChapterEnds(chapters, 0), nil
+}
+
+func completeChapterEnds(chapters []Chapter, duration time.Duration) []Chapter {
+	completed := append([]Chapter(nil), chapters...)
+	sort.SliceStable(completed, func(i, j int) bool {
+		return completed[i].Start < completed[j].Start
+	})
+	for i := range completed {
+		if completed[i].End > completed[i].Start {
+			continue
+		}
+		switch {
+		case i+1 < len(completed):
+			completed[i].End = completed[i+1].Start
+		case duration > completed[i].Start:
+			completed[i].End = duration
+		default:
+			completed[i].End = completed[i].Start
+		}
+	}
+	return completed
+}
+
+// FormatChapters writes chapters in format. OGM and ffmetadata cannot mark
+// chapters as hidden, so hidden chapters are left out of them.
+func FormatChapters(chapters []Chapter, format string) ([]byte, error) {
+	if err := ValidateChapterFormat(format); err != nil {
+		return nil, err
+	}
+
+	var visible []Chapter
+	for _, chapter := range chapters {
+		if !chapter.Hidden || format == ChapterFormatXML {
+			visible = append(visible, chapter)
+		}
+	}
+
+	var out bytes.Buffer
+	switch format {
+	case ChapterFormatOGM:
+		for i, chapter := range visible {
+			fmt.Fprintf(&out, "CHAPTER%02d=%s\n", i+1, formatChapterTimestamp(chapter.Start, 3))
+			fmt.Fprintf(&out, "CHAPTER%02dNAME=%s\n", i+1, chapter.Title)
+		}
+	case ChapterFormatFFMetadata:
+		out.WriteString(ffmetadataHeader + "\n")
+		for _, chapter := range visible {
+			out.WriteString("\n[CHAPTER]\nTIMEBASE=1/1000\n")
+			fmt.Fprintf(&out, "START=%d\nEND=%d\n", chapter.Start.Milliseconds(), chapter.End.Milliseconds())
+			if chapter.Title != "" {
+				fmt.Fprintf(&out, "title=%s\n", escapeFFMetadata(chapter.Title))
+			}
+		}
+	case ChapterFormatXML:
+		edition := xmlEdition{}
+		for _, chapter := range visible {
+			atom := xmlChapterAtom{
+				UID:   chapter.UID,
+				Start: formatChapterTimestamp(chapter.Start, 9),
+				Displays: []xmlChapterDisplay{{
+					String:   chapter.Title,
+					Language: chapter.Language,
+				}},
+			}
+			if chapter.End > chapter.Start {
+				atom.End = formatChapterTimestamp(chapter.End, 9)
+			}
+			if chapter.Hidden {
+				atom.Hidden = 1
+			}
+			edition.Atoms = append(edition.Atoms, atom)
+		}
+		data, err := xml.MarshalIndent(xmlChapters{Editions: []xmlEdition{edition}}, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		out.WriteString(xml.Header)
+		out.Write(data)
+		out.WriteString("\n")
+	}
+	return out.Bytes(), nil
+}
+
+// DetectChapterFormat guesses the format of a chapter file from its content.
+func DetectChapterFormat(data []byte) (string, error) {
+	text := strings.TrimSpace(strings.TrimPrefix(string(data), "\ufeff"))
+	switch {
+	case strings.HasPrefix(text, ffmetadataHeader):
+		return ChapterFormatFFMetadata, nil
+	case strings.Contains(text, "<Chapters"):
+		return ChapterFormatXML, nil
+	case strings.HasPrefix(text, "CHAPTER"):
+		return ChapterFormatOGM, nil
+	}
+	return "", errors.New("cannot detect the chapter format")
+}
+
+// ParseChapters reads a chapter file in format, or in the detected format
+// when format is empty.
+func ParseChapters(data []byte, format string) ([]Chapter, error) {
+	if format == "" {
+		var err error
+		if format, err = DetectChapterFormat(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := ValidateChapterFormat(format); err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	var chapters []Chapter
+	var err error
+	switch format {
+	case ChapterFormatOGM:
+		chapters, err = parseOGMChapters(data)
+	case ChapterFormatXML:
+		chapters, err = parseXMLChapters(data)
+	case ChapterFormatFFMetadata:
+		chapters, err = parseFFMetadataChapters(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s chapters: %w", format, err)
+	}
+	return chapters, nil
+}
+
+func parseOGMChapters(data []byte) ([]Chapter, error) {
+	byNumber := map[int]*Chapter{}
+	var numbers []int
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		match := ogmChapterRE.FindStringSubmatch(line)
+		if match == nil {
+			return nil, fmt.Errorf("unexpected line: %s", line)
+		}
+
+		number, _ := strconv.Atoi(match[1])
+		chapter, ok := byNumber[number]
+		if !ok {
+			chapter = &Chapter{UID: uint64(number)}
+			byNumber[number] = chapter
+			numbers = append(numbers, number)
+		}
+		if match[2] != "" {
+			chapter.Title = match[3]
+			continue
+		}
+		start, err := parseChapterTimestamp(match[3])
+		if err != nil {
+			return nil, err
+		}
+		chapter.Start = start
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Ints(numbers)
+	chapters := make([]Chapter, 0, len(numbers))
+	for _, number := range numbers {
+		chapters = append(chapters, *byNumber[number])
+	}
+	return completeChapterEnds(chapters, 0), nil
+}
+
+func parseXMLChapters(data []byte) ([]Chapter, error) {
+	var document xmlChapters
+	if err := xml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	var chapters []Chapter
+	var appendAtoms func(atoms []xmlChapterAtom) error
+	appendAtoms = func(atoms []xmlChapterAtom) error {
+		for _, atom := range atoms {
+			chapter := Chapter{UID: atom.UID, Hidden: atom.Hidden != 0}
+			var err error
+			if chapter.Start, err = parseChapterTimestamp(atom.Start); err != nil {
+				return err
+			}
+			if atom.End != "" {
+				if chapter.End, err = parseChapterTimestamp(atom.End); err != nil {
+					return err
+				}
+			}
+			if len(atom.Displays) > 0 {
+				chapter.Title = strings.TrimSpace(atom.Displays[0].String)
+				chapter.Language = strings.TrimSpace(atom.Displays[0].Language)
+			}
+			chapters = append(chapters, chapter)
+			if err := appendAtoms(atom.Atoms); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, edition := range document.Editions {
+		if err := appendAtoms(edition.Atoms); err != nil {
+			return nil, err
+		}
+	}
+	return chapters, nil
+}
+
+func parseFFMetadataChapters(data []byte) ([]Chapter, error) {
+	var chapters []Chapter
+	var current *Chapter
+	var numerator, denominator int64
+	var start, end int64
+
+	finish := func() {
+		if current == nil {
+			return
+		}
+		current.Start = time.Duration(start * numerator * int64(time.Second) / denominator)
+		current.End = time.Duration(end * numerator * int64(time.Second) / denominator)
+		chapters = append(chapters, *current)
+		current = nil
+	}
+
+	for _, line := range splitFFMetadataLines(string(data)) {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, ";") || strings.HasPrefix(trimmed, "#"):
+			continue
+		case strings.HasPrefix(trimmed, "["):
+			finish()
+			if trimmed == "[CHAPTER]" {
+				current = &Chapter{UID: uint64(len(chapters) + 1)}
+				numerator, denominator = 1, 1000000000
+				start, end = 0, 0
+			}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		key, value, ok := cutFFMetadataLine(line)
+		if !ok {
+			return nil, fmt.Errorf("unexpected line: %s", trimmed)
+		}
+		var err error
+		switch strings.ToUpper(key) {
+		case "TIMEBASE":
+			num, den, found := strings.Cut(value, "/")
+			if !found {
+				return nil, fmt.Errorf("invalid timebase: %s", value)
+			}
+			if numerator, err = strconv.ParseInt(num, 10, 64); err == nil {
+				denominator, err = strconv.ParseInt(den, 10, 64)
+			}
+			if err == nil && (numerator <= 0 || denominator <= 0) {
+				err = fmt.Errorf("invalid timebase: %s", value)
+			}
+		case "START":
+			start, err = strconv.ParseInt(value, 10, 64)
+		case "END":
+			end, err = strconv.ParseInt(value, 10, 64)
+		case "TITLE":
+			current.Title = value
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	finish()
+	return chapters, nil
+}
+
+// splitFFMetadataLines splits on newlines that are not escaped.
+func splitFFMetadataLines(text string) []string {
+	var lines []string
+	var line strings.Builder
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '\\' && i+1 < len(text):
+			line.WriteByte(text[i])
+			line.WriteByte(text[i+1])
+			i++
+		case text[i] == '\n':
+			lines = append(lines, strings.TrimSuffix(line.String(), "\r"))
+			line.Reset()
+		default:
+			line.WriteByte(text[i])
+		}
+	}
+	return append(lines, strings.TrimSuffix(line.String(), "\r"))
+}
+
+// cutFFMetadataLine splits key=value at the first unescaped '=' and removes
+// the escaping from both parts.
+func cutFFMetadataLine(line string) (string, string, bool) {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '=':
+			return unescapeFFMetadata(line[:i]), unescapeFFMetadata(line[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+func escapeFFMetadata(value string) string {
+	var escaped strings.Builder
+	for _, r := range value {
+		switch r {
+		case '=', ';', '#', '\\', '\n':
+			escaped.WriteByte('\\')
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String()
+}
+
+func unescapeFFMetadata(value string) string {
+	var unescaped strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			i++
+		}
+		unescaped.WriteByte(value[i])
+	}
+	return unescaped.String()
+}
+
+func formatChapterTimestamp(timestamp time.Duration, digits int) string {
+	if timestamp < 0 {
+		timestamp = 0
+	}
+	fraction := fmt.Sprintf("%09d", timestamp%time.Second)[:digits]
+	return fmt.Sprintf("%02d:%02d:%02d.%s", int64(timestamp/time.Hour), int64(timestamp/time.Minute)%60, int64(timestamp/time.Second)%60, fraction)
+}
+
+func parseChapterTimestamp(value string) (time.Duration, error) {
+	match := chapterTimestampRE.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return 0, fmt.Errorf("invalid chapter timestamp: %s", value)
+	}
+	hours, _ := strconv.ParseInt(match[1], 10, 64)
+	minutes, _ := strconv.ParseInt(match[2], 10, 64)
+	seconds, _ := strconv.ParseInt(match[3], 10, 64)
+	nanoseconds, _ := strconv.ParseInt((match[4] + "000000000")[:9], 10, 64)
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second + time.Duration(nanoseconds), nil
+}
+
+// BuildChapterImportFFmpegArgs replaces the chapters of targetFile with those
+// of an ffmetadata file; the output path is appended by the caller.
+func BuildChapterImportFFmpegArgs(targetFile, metadataFile string) []string {
+	return []string{
+		"-hide_banner",
+		"-y",
+		"-i",
+		targetFile,
+		"-f",
+		ChapterFormatFFMetadata,
+		"-i",
+		metadataFile,
+		"-map",
+		"0",
+		"-map_metadata",
+		"0",
+		"-map_chapters",
+		"1",
+		"-c",
+		"copy",
+	}
+}
@@ -0,0 +1,167 @@
+package mkv
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testChapters = []Chapter{
+	{UID: 1, Start: 0, End: 90 * time.Second, Title: "Opening", Language: "eng"},
+	{UID: 2, Start: 90*time.Second + 500*time.Millisecond, End: 20 * time.Minute, Title: "Part A; = #1"},
+}
+
+func TestFormatChapters(t *testing.T) {
+	ogm, err := FormatChapters(testChapters, ChapterFormatOGM)
+	if err != nil {
+		t.Fatalf("FormatChapters(ogm) error = %v", err)
+	}
+	wantOGM := "CHAPTER01=00:00:00.000\nCHAPTER01NAME=Opening\nCHAPTER02=00:01:30.500\nCHAPTER02NAME=Part A; = #1\n"
+	if string(ogm) != wantOGM {
+		t.Fatalf("ogm = %q, want %q", ogm, wantOGM)
+	}
+
+	ffmetadata, err := FormatChapters(testChapters, ChapterFormatFFMetadata)
+	if err != nil {
+		t.Fatalf("FormatChapters(ffmetadata) error = %v", err)
+	}
+	if !strings.HasPrefix(string(ffmetadata), ";FFMETADATA1\n") ||
+		!strings.Contains(string(ffmetadata), "START=90500\nEND=1200000\ntitle=Part A\\; \\= \\#1\n") {
+		t.Fatalf("ffmetadata = %q", ffmetadata)
+	}
+
+	xmlData, err := FormatChapters(testChapters, ChapterFormatXML)
+	if err != nil {
+		t.Fatalf("FormatChapters(xml) error = %v", err)
+	}
+	for _, want := range []string{
+		"<ChapterTimeStart>00:01:30.500000000</ChapterTimeStart>",
+		"<ChapterString>Opening</ChapterString>",
+		"<ChapterLanguage>eng</ChapterLanguage>",
+	} {
+		if !strings.Contains(string(xmlData), want) {
+			t.Fatalf("xml = %s, want contains %s", xmlData, want)
+		}
+	}
+
+	if _, err := FormatChapters(testChapters, "cue"); err == nil {
+		t.Fatal("expected unsupported format error")
+	}
+}
+
+func TestParseChapters_RoundTrip(t *testing.T) {
+	for _, format := range []string{ChapterFormatOGM, ChapterFormatXML, ChapterFormatFFMetadata} {
+		t.Run(format, func(t *testing.T) {
+			data, err := FormatChapters(testChapters, format)
+			if err != nil {
+				t.Fatalf("FormatChapters() error = %v", err)
+			}
+			detected, err := DetectChapterFormat(data)
+			if err != nil || detected != format {
+				t.Fatalf("DetectChapterFormat() = %q, %v, want %q", detected, err, format)
+			}
+
+			chapters, err := ParseChapters(data, "")
+			if err != nil {
+				t.Fatalf("ParseChapters() error = %v", err)
+			}
+			if len(chapters) != len(testChapters) {
+				t.Fatalf("chapters = %+v, want %d", chapters, len(testChapters))
+			}
+			for i, chapter := range chapters {
+				if chapter.Start != testChapters[i].Start || chapter.Title != testChapters[i].Title {
+					t.Fatalf("chapter %d = %+v, want %+v", i, chapter, testChapters[i])
+				}
+			}
+			// OGM has no end times; the first chapter ends where the next starts.
+			if format != ChapterFormatOGM && chapters[1].End != testChapters[1].End {
+				t.Fatalf("end = %v, want %v", chapters[1].End, testChapters[1].End)
+			}
+		})
+	}
+}
+
+func TestParseChapters_FFMetadataTimebase(t *testing.T) {
+	data := ";FFMETADATA1\ntitle=Movie\n\n[CHAPTER]\nTIMEBASE=1/90000\nSTART=90000\nEND=180000\ntitle=Multi\\\nline\n[STREAM]\ntitle=ignored\n"
+	chapters, err := ParseChapters([]byte(data), "")
+	if err != nil {
+		t.Fatalf("ParseChapters() error = %v", err)
+	}
+	if len(chapters) != 1 || chapters[0].Start != time.Second || chapters[0].End != 2*time.Second || chapters[0].Title != "Multi\nline" {
+		t.Fatalf("chapters = %+v", chapters)
+	}
+}
+
+func TestParseChapters_Errors(t *testing.T) {
+	tests := map[string]string{
+		"no chapters here":                   "cannot detect the chapter format",
+		"CHAPTER01=1:2\n":                    "invalid chapter timestamp: 1:2",
+		"CHAPTER01=00:00:00.000\nfoo=bar\n":  "unexpected line: foo=bar",
+		";FFMETADATA1\n[CHAPTER]\nSTART=x\n": "invalid ffmetadata chapters",
+	}
+	for data, want := range tests {
+		if _, err := ParseChapters([]byte(data), ""); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("ParseChapters(%q) error = %v, want contains %q", data, err, want)
+		}
+	}
+}
+
+func TestReadChapters_Matroska(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "movie.mkv")
+	if err := os.WriteFile(fileName, buildTestMatroska(t), 0o644); err != nil {
+		t.Fatalf("write test mkv failed: %v", err)
+	}
+
+	chapters, err := ReadChapters(fileName)
+	if err != nil {
+		t.Fatalf("ReadChapters() error = %v", err)
+	}
+	if len(chapters) != 2 {
+		t.Fatalf("chapters = %+v, want 2", chapters)
+	}
+	if chapters[0].End != time.Minute || chapters[1].End != 90*time.Second {
+		t.Fatalf("chapter ends = %v, %v, want next start and duration", chapters[0].End, chapters[1].End)
+	}
+}
+
+type metadataFFmpegRunner struct {
+	metadata string
+	args     []string
+}
+
+func (r *metadataFFmpegRunner) IsInstalled() error {
+	return nil
+}
+
+func (r *metadataFFmpegRunner) Run(args ...string) ([]byte, error) {
+	r.args = args
+	return nil, os.WriteFile(args[len(args)-1], []byte(r.metadata), 0o644)
+}
+
+func (r *metadataFFmpegRunner) RunContext(_ context.Context, _ func(FFmpegProgress), args ...string) ([]byte, error) {
+	return r.Run(args...)
+}
+
+func TestReadChapters_FallsBackToFFmpeg(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "movie.mp4")
+	if err := os.WriteFile(fileName, []byte("not matroska"), 0o644); err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+	runner := &metadataFFmpegRunner{metadata: ";FFMETADATA1\n[CHAPTER]\nTIMEBASE=1/1000\nSTART=0\nEND=5000\ntitle=Intro\n"}
+	SetFFmpegRunner(runner)
+	t.Cleanup(func() { SetFFmpegRunner(nil) })
+
+	chapters, err := ReadChapters(fileName)
+	if err != nil {
+		t.Fatalf("ReadChapters() error = %v", err)
+	}
+	if len(chapters) != 1 || chapters[0].Title != "Intro" || chapters[0].End != 5*time.Second {
+		t.Fatalf("chapters = %+v", chapters)
+	}
+	if strings.Join(runner.args[:6], " ") != "-hide_banner -y -i "+fileName+" -f ffmetadata" {
+		t.Fatalf("args = %v", runner.args)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || freebsd)
+
+package mkv
+
+// availableDiskSpace cannot query free space on this platform, so the free
+// space preflight is skipped.
+func availableDiskSpace(dir string) (uint64, bool, error) {
+	return 0, false, nil
+}
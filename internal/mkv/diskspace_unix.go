@@ -0,0 +1,13 @@
+//go:build linux || darwin || freebsd
+
+package mkv
+
+import "syscall"
+
+func availableDiskSpace(dir string) (uint64, bool, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, false, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), true, nil
+}
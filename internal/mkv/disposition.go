@@ -0,0 +1,161 @@
+package mkv
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DispositionFlags lists the ffmpeg stream disposition flags accepted by
+// the disposition command.
+var DispositionFlags = []string{
+	"default",
+	"dub",
+	"original",
+	"comment",
+	"lyrics",
+	"karaoke",
+	"forced",
+	"hearing_impaired",
+	"visual_impaired",
+	"clean_effects",
+	"captions",
+	"descriptions",
+	"dependent",
+	"metadata",
+}
+
+// ParseDispositionFlags parses a comma separated list of disposition flags.
+func ParseDispositionFlags(value string) ([]string, error) {
+	var flags []string
+	for _, part := range strings.Split(value, ",") {
+		flag := strings.ToLower(strings.TrimSpace(part))
+		if flag == "" {
+			continue
+		}
+		if !slices.Contains(DispositionFlags, flag) {
+			return nil, fmt.Errorf("unknown disposition flag: %s", flag)
+		}
+		if !slices.Contains(flags, flag) {
+			flags = append(flags, flag)
+		}
+	}
+	return flags, nil
+}
+
+// ValidateDispositionChange checks that setFlags and clearFlags describe a
+// non-empty, non-contradictory change.
+func ValidateDispositionChange(setFlags, clearFlags []string) error {
+	if len(setFlags) == 0 && len(clearFlags) == 0 {
+		return fmt.Errorf("at least one of --set or --clear is required")
+	}
+	for _, flag := range setFlags {
+		if slices.Contains(clearFlags, flag) {
+			return fmt.Errorf("disposition flag %s cannot be both set and cleared", flag)
+		}
+	}
+	return nil
+}
+
+// BuildDispositionFFmpegArgs remuxes sourceFile setting and clearing the
+// given flags on target while keeping its other flags. With exclusive, the
+// set flags are cleared on the other streams of the same type.
+func BuildDispositionFFmpegArgs(sourceFile string, allStreams []StreamInfo, target StreamInfo, setFlags, clearFlags []string, exclusive bool) []string {
+	ffmpegArgs := []string{
+		"-hide_banner",
+		"-y",
+		"-i",
+		sourceFile,
+		"-map",
+		"0",
+		"-c",
+		"copy",
+	}
+
+	var targetValue strings.Builder
+	for _, flag := range setFlags {
+		targetValue.WriteString("+" + flag)
+	}
+	for _, flag := range clearFlags {
+		targetValue.WriteString("-" + flag)
+	}
+	ffmpegArgs = append(ffmpegArgs, "-disposition:"+StreamIDTail(target.ID), targetValue.String())
+
+	if !exclusive || len(setFlags) == 0 {
+		return ffmpegArgs
+	}
+
+	siblingValue := "-" + strings.Join(setFlags, "-")
+	for _, stream := range allStreams {
+		if stream.Type != target.Type || stream.ID == target.ID {
+			continue
+		}
+		ffmpegArgs = append(ffmpegArgs, "-disposition:"+StreamIDTail(stream.ID), siblingValue)
+	}
+	return ffmpegArgs
+}
+
+// DispositionTrackEdits returns the header edits equivalent to
+// BuildDispositionFFmpegArgs. Flags without a Matroska track flag, such as
+// captions, report ErrInPlaceEditUnavailable.
+func DispositionTrackEdits(allStreams []StreamInfo, target StreamInfo, setFlags, clearFlags []string, exclusive bool) ([]TrackEdit, error) {
+	targetIndex, err := StreamIndex(target.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	targetEdit := TrackEdit{Index: targetIndex}
+	for _, flag := range setFlags {
+		if err := setTrackEditFlag(&targetEdit, flag, true); err != nil {
+			return nil, err
+		}
+	}
+	for _, flag := range clearFlags {
+		if err := setTrackEditFlag(&targetEdit, flag, false); err != nil {
+			return nil, err
+		}
+	}
+	edits := []TrackEdit{targetEdit}
+
+	if !exclusive || len(setFlags) == 0 {
+		return edits, nil
+	}
+
+	for _, stream := range allStreams {
+		if stream.Type != target.Type || stream.ID == target.ID {
+			continue
+		}
+		index, err := StreamIndex(stream.ID)
+		if err != nil {
+			return nil, err
+		}
+		edit := TrackEdit{Index: index}
+		for _, flag := range setFlags {
+			if err := setTrackEditFlag(&edit, flag, false); err != nil {
+				return nil, err
+			}
+		}
+		edits = append(edits, edit)
+	}
+	return edits, nil
+}
+
+func setTrackEditFlag(edit *TrackEdit, flag string, value bool) error {
+	switch flag {
+	case "default":
+		edit.Default = &value
+	case "forced":
+		edit.Forced = &value
+	case "hearing_impaired":
+		edit.HearingImpaired = &value
+	case "visual_impaired":
+		edit.VisualImpaired = &value
+	case "comment":
+		edit.Commentary = &value
+	case "original":
+		edit.Original = &value
+	default:
+		return fmt.Errorf("%w: disposition flag %s has no matroska track flag", ErrInPlaceEditUnavailable, flag)
+	}
+	return nil
+}
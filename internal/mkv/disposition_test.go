@@ -0,0 +1,126 @@
+package mkv
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseDispositionFlags(t *testing.T) {
+	flags, err := ParseDispositionFlags(" hearing_impaired, Comment,,hearing_impaired ")
+	if err != nil {
+		t.Fatalf("ParseDispositionFlags() error = %v", err)
+	}
+	if len(flags) != 2 || flags[0] != "hearing_impaired" || flags[1] != "comment" {
+		t.Fatalf("flags = %#v", flags)
+	}
+
+	if _, err := ParseDispositionFlags("default,subtitled"); err == nil {
+		t.Fatal("expected unknown flag error")
+	}
+}
+
+func TestValidateDispositionChange(t *testing.T) {
+	if err := ValidateDispositionChange(nil, nil); err == nil {
+		t.Fatal("expected empty change error")
+	}
+	if err := ValidateDispositionChange([]string{"default"}, []string{"default"}); err == nil {
+		t.Fatal("expected contradictory change error")
+	}
+	if err := ValidateDispositionChange([]string{"default"}, []string{"forced"}); err != nil {
+		t.Fatalf("ValidateDispositionChange() error = %v", err)
+	}
+}
+
+func TestBuildDispositionFFmpegArgs(t *testing.T) {
+	streams := []StreamInfo{
+		{ID: "0:0", Type: "Video"},
+		{ID: "0:1", Type: "Audio"},
+		{ID: "0:2", Type: "Subtitle", IsDefault: true},
+		{ID: "0:3", Type: "Audio"},
+		{ID: "0:4", Type: "Subtitle"},
+	}
+
+	args := BuildDispositionFFmpegArgs("target.mkv", streams, streams[4], []string{"default", "hearing_impaired"}, []string{"forced"}, false)
+	if !containsArgPair(args, "-disposition:4", "+default+hearing_impaired-forced") {
+		t.Fatalf("unexpected target disposition: %#v", args)
+	}
+	if len(args) != 10 {
+		t.Fatalf("non-exclusive change touched other streams: %#v", args)
+	}
+
+	args = BuildDispositionFFmpegArgs("target.mkv", streams, streams[1], []string{"default", "original"}, nil, true)
+	if !containsArgPair(args, "-disposition:1", "+default+original") {
+		t.Fatalf("unexpected target disposition: %#v", args)
+	}
+	if !containsArgPair(args, "-disposition:3", "-default-original") {
+		t.Fatalf("expected sibling audio stream to be cleared: %#v", args)
+	}
+	for _, arg := range args {
+		if arg == "-disposition:2" || arg == "-disposition:4" {
+			t.Fatalf("exclusive change touched subtitle streams: %#v", args)
+		}
+	}
+}
+
+func TestDispositionTrackEdits(t *testing.T) {
+	streams := []StreamInfo{
+		{ID: "0:0", Type: "Video"},
+		{ID: "0:1", Type: "Subtitle"},
+		{ID: "0:2", Type: "Subtitle"},
+	}
+
+	edits, err := DispositionTrackEdits(streams, streams[1], []string{"hearing_impaired"}, []string{"default"}, true)
+	if err != nil {
+		t.Fatalf("DispositionTrackEdits() error = %v", err)
+	}
+	if len(edits) != 2 {
+		t.Fatalf("edits = %+v, want target and sibling", edits)
+	}
+	if edits[0].Index != 1 || edits[0].HearingImpaired == nil || !*edits[0].HearingImpaired || edits[0].Default == nil || *edits[0].Default {
+		t.Fatalf("unexpected target edit %+v", edits[0])
+	}
+	if edits[1].Index != 2 || edits[1].HearingImpaired == nil || *edits[1].HearingImpaired || edits[1].Default != nil {
+		t.Fatalf("unexpected sibling edit %+v", edits[1])
+	}
+
+	if _, err := DispositionTrackEdits(streams, streams[1], []string{"captions"}, nil, false); !errors.Is(err, ErrInPlaceEditUnavailable) {
+		t.Fatalf("DispositionTrackEdits() error = %v, want ErrInPlaceEditUnavailable", err)
+	}
+}
+
+func TestEditTracksInPlace_DispositionFlags(t *testing.T) {
+	samplePath := filepath.Join("..", "..", "resources", "low_quality_with_subtitles_5s.mkv")
+	original, err := os.ReadFile(samplePath)
+	if err != nil {
+		t.Skip("skip in-place edit test: sample mkv not found")
+	}
+	target := filepath.Join(t.TempDir(), "sample.mkv")
+	if err := os.WriteFile(target, original, 0o644); err != nil {
+		t.Fatalf("write sample copy failed: %v", err)
+	}
+
+	enabled := true
+	edits := []TrackEdit{
+		{Index: 4, HearingImpaired: &enabled, VisualImpaired: &enabled},
+		{Index: 8, Commentary: &enabled, Original: &enabled},
+	}
+	if err := EditTracksInPlace(target, edits); err != nil {
+		t.Fatalf("EditTracksInPlace() error = %v", err)
+	}
+
+	container, err := ReadContainer(target)
+	if err != nil {
+		t.Fatalf("ReadContainer() error = %v", err)
+	}
+	if track := container.Tracks[4]; !track.IsHearingImpaired || !track.IsVisualImpaired {
+		t.Fatalf("track 4 = %+v, want hearing and visual impaired", track)
+	}
+	if track := container.Tracks[8]; !track.IsCommentary || !track.IsOriginal {
+		t.Fatalf("track 8 = %+v, want commentary and original", track)
+	}
+	if track := container.Tracks[3]; track.IsHearingImpaired || track.IsCommentary {
+		t.Fatalf("track 3 = %+v, want untouched", track)
+	}
+}
@@ -0,0 +1,329 @@
+package mkv
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"math/bits"
+)
+
+// EBML and Matroska element IDs, written with their length marker bits as
+// they appear in the file.
+const (
+	ebmlHeaderID  = 0x1A45DFA3
+	ebmlDocTypeID = 0x4282
+
+	segmentID = 0x18538067
+	clusterID = 0x1F43B675
+	voidID    = 0xEC
+	crc32ID   = 0xBF
+
+	seekHeadID     = 0x114D9B74
+	seekID         = 0x4DBB
+	seekElementID  = 0x53AB
+	seekPositionID = 0x53AC
+
+	infoID           = 0x1549A966
+	timestampScaleID = 0x2AD7B1
+	durationID       = 0x4489
+	segmentTitleID   = 0x7BA9
+	muxingAppID      = 0x4D80
+	writingAppID     = 0x5741
+
+	tracksID              = 0x1654AE6B
+	trackEntryID          = 0xAE
+	trackNumberID         = 0xD7
+	trackUIDID            = 0x73C5
+	trackTypeID           = 0x83
+	flagEnabledID         = 0xB9
+	flagDefaultID         = 0x88
+	flagForcedID          = 0x55AA
+	flagHearingImpairedID = 0x55AB
+	flagVisualImpairedID  = 0x55AC
+	flagOriginalID        = 0x55AE
+	flagCommentaryID      = 0x55AF
+	trackNameID           = 0x536E
+	trackLanguageID       = 0x22B59C
+	trackLanguageIETFID   = 0x22B59D
+	codecIDID             = 0x86
+	codecPrivateID        = 0x63A2
+	contentEncodingsID    = 0x6D80
+	contentEncodingID     = 0x6240
+	contentEncodingTypeID = 0x5033
+	contentCompressionID  = 0x5034
+	contentCompAlgoID     = 0x4254
+	contentCompSettingsID = 0x4255
+
+	clusterTimestampID = 0xE7
+	simpleBlockID      = 0xA3
+	blockGroupID       = 0xA0
+	blockID            = 0xA1
+
+	attachmentsID         = 0x1941A469
+	attachedFileID        = 0x61A7
+	fileDescriptionID     = 0x467E
+	fileNameID            = 0x466E
+	fileMimeTypeID        = 0x4660
+	fileDataID            = 0x465C
+	fileUIDID             = 0x46AE
+	chaptersID            = 0x1043A770
+	editionEntryID        = 0x45B9
+	chapterAtomID         = 0xB6
+	chapterUIDID          = 0x73C4
+	chapterTimeStartID    = 0x91
+	chapterTimeEndID      = 0x92
+	chapterFlagHiddenID   = 0x98
+	chapterDisplayID      = 0x80
+	chapterStringID       = 0x85
+	chapterLanguageID     = 0x437C
+	chapterLanguageIETFID = 0x437D
+)
+
+const maxLeafElementSize = 1 << 20
+
+var errInvalidVint = errors.New("invalid ebml variable-size integer")
+
+type ebmlElement struct {
+	ID         uint32
+	Offset     int64
+	DataOffset int64
+	Size       int64
+}
+
+func (e ebmlElement) HeaderSize() int64 {
+	return e.DataOffset - e.Offset
+}
+
+func (e ebmlElement) End() int64 {
+	return e.DataOffset + e.Size
+}
+
+func decodeVint(data []byte) (uint64, int, error) {
+	if len(data) == 0 {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+
+	length := bits.LeadingZeros8(data[0]) + 1
+	if length > 8 {
+		return 0, 0, errInvalidVint
+	}
+	if len(data) < length {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+
+	value := uint64(data[0] & (0xFF >> length))
+	for i := 1; i < length; i++ {
+		value = value<<8 | uint64(data[i])
+	}
+	return value, length, nil
+}
+
+func isUnknownVintSize(value uint64, length int) bool {
+	return value == 1<<(7*uint(length))-1
+}
+
+// readElementHeader reads the element ID and data size stored at offset.
+// Elements of unknown size are clamped to limit.
+func readElementHeader(r io.ReaderAt, offset, limit int64) (ebmlElement, error) {
+	var buf [12]byte
+	n, err := r.ReadAt(buf[:], offset)
+	if n == 0 {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return ebmlElement{}, err
+	}
+
+	idLength := bits.LeadingZeros8(buf[0]) + 1
+	if idLength > 4 {
+		return ebmlElement{}, fmt.Errorf("invalid ebml element id at offset %d", offset)
+	}
+	if n < idLength {
+		return ebmlElement{}, io.ErrUnexpectedEOF
+	}
+	var id uint32
+	for i := 0; i < idLength; i++ {
+		id = id<<8 | uint32(buf[i])
+	}
+
+	size, sizeLength, err := decodeVint(buf[idLength:n])
+	if err != nil {
+		return ebmlElement{}, fmt.Errorf("invalid size of ebml element 0x%X at offset %d: %w", id, offset, err)
+	}
+
+	element := ebmlElement{
+		ID:         id,
+		Offset:     offset,
+		DataOffset: offset + int64(idLength+sizeLength),
+	}
+	if isUnknownVintSize(size, sizeLength) {
+		element.Size = limit - element.DataOffset
+		if element.Size < 0 {
+			return ebmlElement{}, fmt.Errorf("ebml element 0x%X at offset %d exceeds its parent", id, offset)
+		}
+		return element, nil
+	}
+
+	if size > uint64(math.MaxInt64) || int64(size) > limit-element.DataOffset {
+		return ebmlElement{}, fmt.Errorf("ebml element 0x%X at offset %d exceeds its parent", id, offset)
+	}
+	element.Size = int64(size)
+	return element, nil
+}
+
+func forEachChild(r io.ReaderAt, parent ebmlElement, visit func(child ebmlElement) error) error {
+	offset := parent.DataOffset
+	for offset < parent.End() {
+		child, err := readElementHeader(r, offset, parent.End())
+		if err != nil {
+			return err
+		}
+		if err := visit(child); err != nil {
+			return err
+		}
+		offset = child.End()
+	}
+	return nil
+}
+
+func readElementData(r io.ReaderAt, element ebmlElement) ([]byte, error) {
+	if element.Size > maxLeafElementSize {
+		return nil, fmt.Errorf("ebml element 0x%X at offset %d is too large (%d bytes)", element.ID, element.Offset, element.Size)
+	}
+
+	data := make([]byte, element.Size)
+	if _, err := r.ReadAt(data, element.DataOffset); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+func readUnsigned(r io.ReaderAt, element ebmlElement) (uint64, error) {
+	if element.Size > 8 {
+		return 0, fmt.Errorf("invalid unsigned integer size %d for element 0x%X", element.Size, element.ID)
+	}
+
+	data, err := readElementData(r, element)
+	if err != nil {
+		return 0, err
+	}
+
+	var value uint64
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+	return value, nil
+}
+
+func readFloat(r io.ReaderAt, element ebmlElement) (float64, error) {
+	data, err := readElementData(r, element)
+	if err != nil {
+		return 0, err
+	}
+
+	switch len(data) {
+	case 0:
+		return 0, nil
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data))), nil
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(data)), nil
+	default:
+		return 0, fmt.Errorf("invalid float size %d for element 0x%X", len(data), element.ID)
+	}
+}
+
+func readString(r io.ReaderAt, element ebmlElement) (string, error) {
+	data, err := readElementData(r, element)
+	if err != nil {
+		return "", err
+	}
+
+	for len(data) > 0 && data[len(data)-1] == 0 {
+		data = data[:len(data)-1]
+	}
+	return string(data), nil
+}
+
+func encodeElementID(id uint32) []byte {
+	switch {
+	case id > 0xFFFFFF:
+		return []byte{byte(id >> 24), byte(id >> 16), byte(id >> 8), byte(id)}
+	case id > 0xFFFF:
+		return []byte{byte(id >> 16), byte(id >> 8), byte(id)}
+	case id > 0xFF:
+		return []byte{byte(id >> 8), byte(id)}
+	default:
+		return []byte{byte(id)}
+	}
+}
+
+// encodeVintSize encodes size using at least minLength bytes. It returns nil
+// when size cannot be represented in eight bytes.
+func encodeVintSize(size uint64, minLength int) []byte {
+	for length := max(minLength, 1); length <= 8; length++ {
+		if size >= 1<<(7*uint(length))-1 {
+			continue
+		}
+		encoded := make([]byte, length)
+		value := size
+		for i := length - 1; i >= 0; i-- {
+			encoded[i] = byte(value)
+			value >>= 8
+		}
+		encoded[0] |= 0x80 >> (length - 1)
+		return encoded
+	}
+	return nil
+}
+
+func encodeElement(id uint32, data []byte) []byte {
+	encoded := append(encodeElementID(id), encodeVintSize(uint64(len(data)), 1)...)
+	return append(encoded, data...)
+}
+
+func encodeUnsignedElement(id uint32, value uint64) []byte {
+	var raw [8]byte
+	binary.BigEndian.PutUint64(raw[:], value)
+	start := 0
+	for start < 7 && raw[start] == 0 {
+		start++
+	}
+	return encodeElement(id, raw[start:])
+}
+
+func encodeStringElement(id uint32, value string) []byte {
+	return encodeElement(id, []byte(value))
+}
+
+// encodeVoid returns a Void element occupying exactly total bytes.
+func encodeVoid(total int) ([]byte, error) {
+	for length := 1; length <= 8; length++ {
+		dataSize := total - 1 - length
+		if dataSize < 0 {
+			break
+		}
+		size := encodeVintSize(uint64(dataSize), length)
+		if len(size) != length {
+			continue
+		}
+		void := make([]byte, total)
+		void[0] = voidID
+		copy(void[1:], size)
+		return void, nil
+	}
+	return nil, fmt.Errorf("cannot encode a void element of %d bytes", total)
+}
+
+// encodeCRC32Element computes the Matroska CRC-32 element covering data.
+func encodeCRC32Element(data []byte) []byte {
+	var checksum [4]byte
+	binary.LittleEndian.PutUint32(checksum[:], crc32.ChecksumIEEE(data))
+	return encodeElement(crc32ID, checksum[:])
+}
@@ -0,0 +1,360 @@
+package mkv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// ErrInPlaceEditUnavailable reports that a header edit cannot be written into
+// the existing file, and the caller should remux with ffmpeg instead.
+var ErrInPlaceEditUnavailable = errors.New("in-place mkv edit is not possible")
+
+// TrackEdit describes metadata changes for the track at Index, counted in
+// the same order as ffmpeg stream indexes. Nil fields are left untouched.
+type TrackEdit struct {
+	Index           int
+	Default         *bool
+	Forced          *bool
+	HearingImpaired *bool
+	VisualImpaired  *bool
+	Commentary      *bool
+	Original        *bool
+	Language        *string
+	Name            *string
+}
+
+func StreamIndex(streamID string) (int, error) {
+	index, err := strconv.Atoi(StreamIDTail(streamID))
+	if err != nil || index < 0 {
+		return -1, fmt.Errorf("invalid stream id: %s", streamID)
+	}
+	return index, nil
+}
+
+// EditTracksInPlace rewrites the Tracks element of a Matroska file without
+// remuxing, the way mkvpropedit does. The new element must fit into the space
+// of the old one plus a directly following Void element; the remainder is
+// padded with a new Void so no other element moves.
+func EditTracksInPlace(fileName string, edits []TrackEdit) error {
+	file, err := os.OpenFile(fileName, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	region, offset, err := buildTracksEdit(file, edits)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteAt(region, offset); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// CheckTracksEditInPlace reports whether EditTracksInPlace can apply edits to
+// fileName, without writing anything.
+func CheckTracksEditInPlace(fileName string, edits []TrackEdit) error {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, _, err = buildTracksEdit(file, edits)
+	return err
+}
+
+// buildTracksEdit returns the bytes that replace the Tracks element of file
+// and the offset they are written at.
+func buildTracksEdit(file *os.File, edits []TrackEdit) ([]byte, int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	container, err := ParseContainer(file, info.Size())
+	if err != nil {
+		if errors.Is(err, ErrNotMatroska) {
+			return nil, 0, fmt.Errorf("%w: %v", ErrInPlaceEditUnavailable, err)
+		}
+		return nil, 0, err
+	}
+	if container.tracks.ID != tracksID {
+		return nil, 0, fmt.Errorf("%w: no tracks element found", ErrInPlaceEditUnavailable)
+	}
+	// Edits count ffmpeg streams; the rebuild counts TrackEntry elements,
+	// which include the tracks ffmpeg skips.
+	entryEdits := make([]TrackEdit, 0, len(edits))
+	for _, edit := range edits {
+		trackIndex, ok := container.trackIndex(edit.Index)
+		if !ok {
+			return nil, 0, fmt.Errorf("track index %d not found", edit.Index)
+		}
+		edit.Index = trackIndex
+		entryEdits = append(entryEdits, edit)
+	}
+
+	updated, err := rebuildTracksElement(file, container.tracks, entryEdits)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	available := container.tracks.End() - container.tracks.Offset
+	if container.tracks.End() < container.segment.End() {
+		next, err := readElementHeader(file, container.tracks.End(), container.segment.End())
+		if err == nil && next.ID == voidID {
+			available = next.End() - container.tracks.Offset
+		}
+	}
+
+	region, err := layoutTracksRegion(updated, container.tracks.HeaderSize(), available)
+	if err != nil {
+		return nil, 0, err
+	}
+	return region, container.tracks.Offset, nil
+}
+
+// layoutTracksRegion encodes the rebuilt Tracks payload followed by Void
+// padding so that it fills exactly available bytes.
+func layoutTracksRegion(payload []byte, headerSize, available int64) ([]byte, error) {
+	id := encodeElementID(tracksID)
+	sizeLength := int(headerSize) - len(id)
+	for ; sizeLength <= 8; sizeLength++ {
+		size := encodeVintSize(uint64(len(payload)), sizeLength)
+		if size == nil {
+			break
+		}
+
+		element := append(append(append([]byte{}, id...), size...), payload...)
+		remaining := available - int64(len(element))
+		if remaining < 0 {
+			break
+		}
+		if remaining == 0 {
+			return element, nil
+		}
+		if remaining == 1 {
+			continue
+		}
+
+		void, err := encodeVoid(int(remaining))
+		if err != nil {
+			return nil, err
+		}
+		return append(element, void...), nil
+	}
+
+	return nil, fmt.Errorf("%w: tracks header needs %d bytes but only %d are available", ErrInPlaceEditUnavailable, len(payload)+len(id)+1, available)
+}
+
+func rebuildTracksElement(r io.ReaderAt, tracks ebmlElement, edits []TrackEdit) ([]byte, error) {
+	editsByIndex := make(map[int]TrackEdit, len(edits))
+	for _, edit := range edits {
+		editsByIndex[edit.Index] = edit
+	}
+
+	var children [][]byte
+	hasCRC := false
+	index := 0
+	err := forEachChild(r, tracks, func(child ebmlElement) error {
+		switch child.ID {
+		case crc32ID:
+			hasCRC = true
+			return nil
+		case voidID:
+			return nil
+		case trackEntryID:
+			edit, ok := editsByIndex[index]
+			index++
+			if !ok {
+				// Muxers such as ffmpeg reserve eight-byte size fields;
+				// re-encoding them minimally frees room for the edits.
+				raw, err := readRawElement(r, child)
+				if err != nil {
+					return err
+				}
+				children = append(children, encodeElement(trackEntryID, raw[child.HeaderSize():]))
+				return nil
+			}
+			entry, err := rebuildTrackEntry(r, child, edit)
+			if err != nil {
+				return err
+			}
+			children = append(children, entry)
+			return nil
+		}
+
+		raw, err := readRawElement(r, child)
+		if err != nil {
+			return err
+		}
+		children = append(children, raw)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	payload := bytes.Join(children, nil)
+	if hasCRC {
+		payload = append(encodeCRC32Element(payload), payload...)
+	}
+
+	check := &Container{}
+	reader := &containerReader{r: bytes.NewReader(payload), container: check}
+	if err := reader.readTracks(ebmlElement{ID: tracksID, Size: int64(len(payload))}); err != nil {
+		return nil, fmt.Errorf("rebuilt tracks element is invalid: %w", err)
+	}
+	if len(check.Tracks) != index {
+		return nil, fmt.Errorf("rebuilt tracks element has %d tracks, want %d", len(check.Tracks), index)
+	}
+	return payload, nil
+}
+
+func rebuildTrackEntry(r io.ReaderAt, entry ebmlElement, edit TrackEdit) ([]byte, error) {
+	// Flags equal to their Matroska default are dropped instead of written,
+	// which frees the bytes needed to store flags set the other way.
+	replacements := map[uint32][]byte{}
+	flags := []struct {
+		id           uint32
+		value        *bool
+		defaultValue bool
+	}{
+		{flagDefaultID, edit.Default, true},
+		{flagForcedID, edit.Forced, false},
+		{flagHearingImpairedID, edit.HearingImpaired, false},
+		{flagVisualImpairedID, edit.VisualImpaired, false},
+		{flagCommentaryID, edit.Commentary, false},
+		{flagOriginalID, edit.Original, false},
+	}
+	for _, flag := range flags {
+		if flag.value != nil {
+			replacements[flag.id] = encodeFlagElement(flag.id, *flag.value, flag.defaultValue)
+		}
+	}
+	if edit.Language != nil {
+		language := *edit.Language
+		if language == "" {
+			language = undeterminedLanguage
+		}
+		replacements[trackLanguageID] = encodeStringElement(trackLanguageID, language)
+		// A stale BCP 47 tag would take precedence over the new language.
+		replacements[trackLanguageIETFID] = nil
+	}
+	if edit.Name != nil && *edit.Name != "" {
+		replacements[trackNameID] = encodeStringElement(trackNameID, *edit.Name)
+	} else if edit.Name != nil {
+		replacements[trackNameID] = nil
+	}
+
+	var children [][]byte
+	hasCRC := false
+	written := map[uint32]bool{}
+	err := forEachChild(r, entry, func(child ebmlElement) error {
+		if child.ID == crc32ID {
+			hasCRC = true
+			return nil
+		}
+		if replacement, ok := replacements[child.ID]; ok {
+			if !written[child.ID] {
+				children = append(children, replacement)
+				written[child.ID] = true
+			}
+			return nil
+		}
+
+		raw, err := readRawElement(r, child)
+		if err != nil {
+			return err
+		}
+		children = append(children, raw)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range []uint32{flagDefaultID, flagForcedID, flagHearingImpairedID, flagVisualImpairedID, flagCommentaryID, flagOriginalID, trackLanguageID, trackNameID} {
+		if replacement, ok := replacements[id]; ok && !written[id] && replacement != nil {
+			children = append(children, replacement)
+		}
+	}
+
+	payload := bytes.Join(children, nil)
+	if hasCRC {
+		payload = append(encodeCRC32Element(payload), payload...)
+	}
+	return encodeElement(trackEntryID, payload), nil
+}
+
+func readRawElement(r io.ReaderAt, element ebmlElement) ([]byte, error) {
+	raw := make([]byte, element.End()-element.Offset)
+	if _, err := r.ReadAt(raw, element.Offset); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return raw, nil
+}
+
+func encodeFlagElement(id uint32, value, defaultValue bool) []byte {
+	if value == defaultValue {
+		return nil
+	}
+	if value {
+		return encodeUnsignedElement(id, 1)
+	}
+	return encodeUnsignedElement(id, 0)
+}
+
+// DefaultToggleTrackEdits returns the header edits equivalent to
+// BuildDefaultToggleFFmpegArgs, limited to the flags that actually change.
+func DefaultToggleTrackEdits(allStreams []StreamInfo, target StreamInfo) ([]TrackEdit, error) {
+	return toggleTrackEdits(allStreams, target, target.IsDefault, func(stream StreamInfo) bool {
+		return stream.IsDefault
+	}, func(edit *TrackEdit, value bool) {
+		edit.Default = &value
+	})
+}
+
+// ForceToggleTrackEdits returns the header edits equivalent to
+// BuildForceToggleFFmpegArgs, limited to the flags that actually change.
+func ForceToggleTrackEdits(allStreams []StreamInfo, target StreamInfo) ([]TrackEdit, error) {
+	return toggleTrackEdits(allStreams, target, target.IsForced, func(stream StreamInfo) bool {
+		return stream.IsForced
+	}, func(edit *TrackEdit, value bool) {
+		edit.Forced = &value
+	})
+}
+
+func toggleTrackEdits(allStreams []StreamInfo, target StreamInfo, targetSet bool, current func(StreamInfo) bool, set func(*TrackEdit, bool)) ([]TrackEdit, error) {
+	var edits []TrackEdit
+	for _, stream := range allStreams {
+		if stream.Type != "Subtitle" {
+			continue
+		}
+
+		want := stream.ID == target.ID && !targetSet
+		if targetSet && stream.ID != target.ID {
+			want = current(stream)
+		}
+		if want == current(stream) {
+			continue
+		}
+
+		index, err := StreamIndex(stream.ID)
+		if err != nil {
+			return nil, err
+		}
+		edit := TrackEdit{Index: index}
+		set(&edit, want)
+		edits = append(edits, edit)
+	}
+	return edits, nil
+}
@@ -0,0 +1,265 @@
+package mkv
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEditTracksInPlace_SampleDefaultToggle(t *testing.T) {
+	samplePath := filepath.Join("..", "..", "resources", "low_quality_with_subtitles_5s.mkv")
+	original, err := os.ReadFile(samplePath)
+	if err != nil {
+		t.Skip("skip in-place edit test: sample mkv not found")
+	}
+	target := filepath.Join(t.TempDir(), "sample.mkv")
+	if err := os.WriteFile(target, original, 0o644); err != nil {
+		t.Fatalf("write sample copy failed: %v", err)
+	}
+
+	originalContainer, err := ReadContainer(target)
+	if err != nil {
+		t.Fatalf("ReadContainer() error = %v", err)
+	}
+	tracksEnd := originalContainer.tracks.End()
+	before := originalContainer.Streams()
+	edits, err := DefaultToggleTrackEdits(before, before[3])
+	if err != nil {
+		t.Fatalf("DefaultToggleTrackEdits() error = %v", err)
+	}
+	if len(edits) != 2 {
+		t.Fatalf("edit count = %d, want 2: %+v", len(edits), edits)
+	}
+
+	if err := EditTracksInPlace(target, edits); err != nil {
+		t.Fatalf("EditTracksInPlace() error = %v", err)
+	}
+
+	updated, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("read updated file failed: %v", err)
+	}
+	if len(updated) != len(original) {
+		t.Fatalf("file size = %d, want %d", len(updated), len(original))
+	}
+	container, err := ReadContainer(target)
+	if err != nil {
+		t.Fatalf("ReadContainer() after edit error = %v", err)
+	}
+	if !bytes.Equal(updated[tracksEnd:], original[tracksEnd:]) {
+		t.Fatal("bytes after the tracks element changed")
+	}
+
+	after := container.Streams()
+	for _, stream := range after {
+		if stream.Type != "Subtitle" {
+			continue
+		}
+		if stream.IsDefault != (stream.ID == "0:3") {
+			t.Fatalf("stream %s default = %v", stream.ID, stream.IsDefault)
+		}
+	}
+}
+
+func TestEditTracksInPlace_ReportsMissingSpace(t *testing.T) {
+	samplePath := filepath.Join("..", "..", "resources", "low_quality_with_subtitles_5s.mkv")
+	original, err := os.ReadFile(samplePath)
+	if err != nil {
+		t.Skip("skip in-place edit test: sample mkv not found")
+	}
+	target := filepath.Join(t.TempDir(), "sample.mkv")
+	if err := os.WriteFile(target, original, 0o644); err != nil {
+		t.Fatalf("write sample copy failed: %v", err)
+	}
+
+	name := strings.Repeat("A title that does not fit into the existing tracks element. ", 8)
+	err = EditTracksInPlace(target, []TrackEdit{{Index: 2, Name: &name}})
+	if !errors.Is(err, ErrInPlaceEditUnavailable) {
+		t.Fatalf("EditTracksInPlace() error = %v, want ErrInPlaceEditUnavailable", err)
+	}
+
+	unchanged, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("read file failed: %v", err)
+	}
+	if !bytes.Equal(unchanged, original) {
+		t.Fatal("file must stay untouched when the edit does not fit")
+	}
+}
+
+func TestCheckTracksEditInPlace_DoesNotWrite(t *testing.T) {
+	samplePath := filepath.Join("..", "..", "resources", "low_quality_with_subtitles_5s.mkv")
+	original, err := os.ReadFile(samplePath)
+	if err != nil {
+		t.Skip("skip in-place edit test: sample mkv not found")
+	}
+	target := filepath.Join(t.TempDir(), "sample.mkv")
+	if err := os.WriteFile(target, original, 0o644); err != nil {
+		t.Fatalf("write sample copy failed: %v", err)
+	}
+
+	title := "Commentary"
+	if err := CheckTracksEditInPlace(target, []TrackEdit{{Index: 2, Name: &title}}); err != nil {
+		t.Fatalf("CheckTracksEditInPlace() error = %v", err)
+	}
+	name := strings.Repeat("A title that does not fit into the existing tracks element. ", 8)
+	if err := CheckTracksEditInPlace(target, []TrackEdit{{Index: 2, Name: &name}}); !errors.Is(err, ErrInPlaceEditUnavailable) {
+		t.Fatalf("CheckTracksEditInPlace() error = %v, want ErrInPlaceEditUnavailable", err)
+	}
+
+	unchanged, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("read file failed: %v", err)
+	}
+	if !bytes.Equal(unchanged, original) {
+		t.Fatal("file must stay untouched by the check")
+	}
+}
+
+func TestEditTracksInPlace_UsesVoidPadding(t *testing.T) {
+	tracks := ebmlTestMaster(tracksID,
+		ebmlTestBytes(crc32ID, []byte{0, 0, 0, 0}),
+		ebmlTestMaster(trackEntryID,
+			ebmlTestUint(trackNumberID, 1),
+			ebmlTestUint(trackTypeID, trackTypeSubtitle),
+			ebmlTestString(codecIDID, "S_TEXT/UTF8"),
+			ebmlTestString(trackLanguageID, "eng"),
+			ebmlTestString(trackLanguageIETFID, "en"),
+		),
+	)
+	void, err := encodeVoid(64)
+	if err != nil {
+		t.Fatalf("encodeVoid() error = %v", err)
+	}
+	cluster := ebmlTestMaster(clusterID, ebmlTestUint(0xE7, 0))
+	segment := ebmlTestMaster(segmentID, tracks, void, cluster)
+	data := append(ebmlTestMaster(ebmlHeaderID, ebmlTestString(ebmlDocTypeID, "matroska")), segment...)
+
+	target := filepath.Join(t.TempDir(), "padded.mkv")
+	if err := os.WriteFile(target, data, 0o644); err != nil {
+		t.Fatalf("write synthesized mkv failed: %v", err)
+	}
+
+	forced := true
+	language := "chi"
+	name := "简体中文"
+	if err := EditTracksInPlace(target, []TrackEdit{{Index: 0, Forced: &forced, Language: &language, Name: &name}}); err != nil {
+		t.Fatalf("EditTracksInPlace() error = %v", err)
+	}
+
+	updated, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("read updated file failed: %v", err)
+	}
+	if len(updated) != len(data) {
+		t.Fatalf("file size = %d, want %d", len(updated), len(data))
+	}
+	if !bytes.HasSuffix(updated, cluster) {
+		t.Fatal("cluster moved or changed")
+	}
+
+	container, err := ParseContainer(bytes.NewReader(updated), int64(len(updated)))
+	if err != nil {
+		t.Fatalf("ParseContainer() error = %v", err)
+	}
+	track := container.Tracks[0]
+	if !track.IsForced || track.Language != "chi" || track.LanguageIETF != "" || track.Name != "简体中文" {
+		t.Fatalf("unexpected track after edit %+v", track)
+	}
+
+	crc, err := readRawElement(bytes.NewReader(updated), ebmlElement{Offset: container.tracks.DataOffset, DataOffset: container.tracks.DataOffset + 2, Size: 4})
+	if err != nil {
+		t.Fatalf("read crc failed: %v", err)
+	}
+	payload := updated[container.tracks.DataOffset+6 : container.tracks.End()]
+	if !bytes.Equal(crc, encodeCRC32Element(payload)) {
+		t.Fatal("tracks CRC-32 was not recomputed")
+	}
+}
+
+func TestEditTracksInPlace_SkipsTracksWithoutStream(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "skipped.mkv")
+	if err := os.WriteFile(target, buildSkippedEntriesMatroska(t), 0o644); err != nil {
+		t.Fatalf("write synthesized mkv failed: %v", err)
+	}
+
+	// Stream 0:2 is the subtitle, the fifth TrackEntry.
+	forced := true
+	if err := EditTracksInPlace(target, []TrackEdit{{Index: 2, Forced: &forced}}); err != nil {
+		t.Fatalf("EditTracksInPlace() error = %v", err)
+	}
+
+	container, err := ReadContainer(target)
+	if err != nil {
+		t.Fatalf("ReadContainer() error = %v", err)
+	}
+	for i, track := range container.Tracks {
+		if track.IsForced != (i == 4) {
+			t.Fatalf("track %d forced = %v", i, track.IsForced)
+		}
+	}
+
+	if err := CheckTracksEditInPlace(target, []TrackEdit{{Index: 3, Forced: &forced}}); err == nil {
+		t.Fatal("expected error for a stream index past the last track")
+	}
+}
+
+func TestEditTracksInPlace_RejectsNonMatroska(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "mock.mkv")
+	if err := os.WriteFile(target, []byte("mock"), 0o644); err != nil {
+		t.Fatalf("write mock failed: %v", err)
+	}
+
+	value := true
+	err := EditTracksInPlace(target, []TrackEdit{{Index: 0, Default: &value}})
+	if !errors.Is(err, ErrInPlaceEditUnavailable) {
+		t.Fatalf("EditTracksInPlace() error = %v, want ErrInPlaceEditUnavailable", err)
+	}
+}
+
+func TestEncodeVoid(t *testing.T) {
+	for _, total := range []int{2, 3, 128, 129, 130, 20000} {
+		void, err := encodeVoid(total)
+		if err != nil {
+			t.Fatalf("encodeVoid(%d) error = %v", total, err)
+		}
+		element, err := readElementHeader(bytes.NewReader(void), 0, int64(len(void)))
+		if err != nil {
+			t.Fatalf("readElementHeader(void %d) error = %v", total, err)
+		}
+		if element.ID != voidID || element.End() != int64(total) {
+			t.Fatalf("void %d decoded as %+v", total, element)
+		}
+	}
+
+	if _, err := encodeVoid(1); err == nil {
+		t.Fatal("expected error for one-byte void")
+	}
+}
+
+func TestForceToggleTrackEdits(t *testing.T) {
+	streams := []StreamInfo{
+		{ID: "0:0", Type: "Video"},
+		{ID: "0:1", Type: "Subtitle", IsForced: true},
+		{ID: "0:2", Type: "Subtitle"},
+	}
+
+	edits, err := ForceToggleTrackEdits(streams, streams[2])
+	if err != nil {
+		t.Fatalf("ForceToggleTrackEdits() error = %v", err)
+	}
+	if len(edits) != 2 || edits[0].Index != 1 || *edits[0].Forced || edits[1].Index != 2 || !*edits[1].Forced {
+		t.Fatalf("unexpected edits %+v", edits)
+	}
+
+	edits, err = ForceToggleTrackEdits(streams, streams[1])
+	if err != nil {
+		t.Fatalf("ForceToggleTrackEdits() error = %v", err)
+	}
+	if len(edits) != 1 || edits[0].Index != 1 || *edits[0].Forced {
+		t.Fatalf("unexpected edits when clearing %+v", edits)
+	}
+}
@@ -0,0 +1,224 @@
+package mkv
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultExtractNameTemplate names extracted subtitles the way players pick
+// them up next to the video, for example Episode.chi.srt.
+const DefaultExtractNameTemplate = "{base}.{lang}.{ext}"
+
+const (
+	ExtractFormatText  = "text"
+	ExtractFormatImage = "image"
+)
+
+const extractSeparators = ".-_ "
+
+var extractTemplatePlaceholderRE = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// subtitleConversionCodecs maps the --convert formats to ffmpeg encoders.
+var subtitleConversionCodecs = map[string]string{
+	"srt": "srt",
+	"vtt": "webvtt",
+	"ass": "ass",
+}
+
+// ExtractSelection filters subtitle streams by language and format. Format is
+// text, image, or a codec or file extension such as ass.
+type ExtractSelection struct {
+	Languages []string
+	Format    string
+}
+
+func SelectSubtitleStreamsForExtraction(allStreams []StreamInfo, selection ExtractSelection) ([]StreamInfo, error) {
+	var languages []string
+	for _, language := range selection.Languages {
+		language = strings.TrimSpace(language)
+		if language == "" {
+			continue
+		}
+		if !ValidLanguageTag(language) {
+			return nil, fmt.Errorf("invalid language tag: %s", language)
+		}
+		languages = append(languages, language)
+	}
+	format := strings.ToLower(strings.TrimSpace(selection.Format))
+
+	var selectedStreams []StreamInfo
+	for _, stream := range allStreams {
+		if stream.Type != "Subtitle" {
+			continue
+		}
+
+		language := stream.Language
+		if language == "" {
+			language = undeterminedLanguage
+		}
+		if len(languages) > 0 && !containsLanguage(languages, language) {
+			continue
+		}
+
+		switch format {
+		case "":
+		case ExtractFormatText:
+			if IsImageSubtitle(stream) {
+				continue
+			}
+		case ExtractFormatImage:
+			if !IsImageSubtitle(stream) {
+				continue
+			}
+		default:
+			if !matchesSubtitleFormat(stream, []string{format}) && SubtitleStreamExtension(stream) != format {
+				continue
+			}
+		}
+		selectedStreams = append(selectedStreams, stream)
+	}
+	return selectedStreams, nil
+}
+
+func ValidateSubtitleConversion(format string) error {
+	if _, ok := subtitleConversionCodecs[format]; !ok {
+		return fmt.Errorf("unsupported conversion format: %s (use srt, vtt or ass)", format)
+	}
+	return nil
+}
+
+// CheckSubtitleConversion rejects image-based streams, which ffmpeg cannot
+// transcode to a text format.
+func CheckSubtitleConversion(streams []StreamInfo, format string) error {
+	if err := ValidateSubtitleConversion(format); err != nil {
+		return err
+	}
+	for _, stream := range streams {
+		if IsImageSubtitle(stream) {
+			return fmt.Errorf("stream %s is an image-based subtitle (%s) and cannot be converted to %s", stream.ID, displaySubtitleCodec(stream), format)
+		}
+	}
+	return nil
+}
+
+func displaySubtitleCodec(stream StreamInfo) string {
+	if stream.SubtitleFormat != "" {
+		return stream.SubtitleFormat
+	}
+	return stream.Codec
+}
+
+// ExtractFileExtension returns the extension of the extracted file, which is
+// the conversion format when one is given.
+func ExtractFileExtension(stream StreamInfo, convert string) string {
+	if convert != "" {
+		return convert
+	}
+	return SubtitleStreamExtension(stream)
+}
+
+// BuildConvertExtractFFmpegArgs extracts stream transcoded to format instead
+// of copying it.
+func BuildConvertExtractFFmpegArgs(sourceFile string, stream StreamInfo, outputPath, format string) ([]string, error) {
+	if err := CheckSubtitleConversion([]StreamInfo{stream}, format); err != nil {
+		return nil, err
+	}
+	return buildExtractFFmpegArgs(sourceFile, stream, outputPath, subtitleConversionCodecs[format]), nil
+}
+
+// ValidateExtractNameTemplate rejects templates with unknown placeholders.
+func ValidateExtractNameTemplate(template string) error {
+	if strings.TrimSpace(template) == "" {
+		return fmt.Errorf("name template is empty")
+	}
+	for _, match := range extractTemplatePlaceholderRE.FindAllStringSubmatch(template, -1) {
+		switch match[1] {
+		case "base", "lang", "title", "id", "ext":
+		default:
+			return fmt.Errorf("unknown placeholder %s in name template", match[0])
+		}
+	}
+	if strings.ContainsAny(template, `/\`) {
+		return fmt.Errorf("name template must not contain a path separator: %s", template)
+	}
+	return nil
+}
+
+// RenderExtractFileName fills template for stream of videoFile, converted to
+// convert when it is not empty. An empty placeholder is dropped together
+// with the separators right before it, or right after it when none precede
+// it, so a stream without a title renders {base}.{lang}.{title}.{ext} as
+// Episode.chi.srt. The separator before {ext} is always kept.
+func RenderExtractFileName(template, videoFile string, stream StreamInfo, convert string) (string, error) {
+	if err := ValidateExtractNameTemplate(template); err != nil {
+		return "", err
+	}
+
+	values := map[string]string{
+		"base": strings.TrimSuffix(filepath.Base(videoFile), filepath.Ext(videoFile)),
+		"id":   SanitizeStreamID(stream.ID),
+		"ext":  ExtractFileExtension(stream, convert),
+	}
+	if language := normalizeLanguageTag(stream.Language); language != "" && language != undeterminedLanguage {
+		values["lang"] = SanitizeFileNamePart(stream.Language)
+	}
+	if strings.TrimSpace(stream.Title) != "" {
+		values["title"] = SanitizeStreamTitle(stream.Title)
+	}
+
+	// parts alternate between literals and placeholders, starting and
+	// ending with a possibly empty literal.
+	var parts []string
+	var fields []string
+	last := 0
+	for _, match := range extractTemplatePlaceholderRE.FindAllStringSubmatchIndex(template, -1) {
+		parts = append(parts, template[last:match[0]])
+		fields = append(fields, template[match[2]:match[3]])
+		last = match[1]
+	}
+	parts = append(parts, template[last:])
+
+	for i, field := range fields {
+		if values[field] != "" {
+			continue
+		}
+		before := &parts[i]
+		if trimmed := strings.TrimRight(*before, extractSeparators); len(trimmed) < len(*before) {
+			*before = trimmed
+			continue
+		}
+		if i+1 < len(fields) && fields[i+1] == "ext" {
+			continue
+		}
+		parts[i+1] = strings.TrimLeft(parts[i+1], extractSeparators)
+	}
+
+	var name strings.Builder
+	for i, part := range parts {
+		name.WriteString(part)
+		if i < len(fields) {
+			name.WriteString(values[fields[i]])
+		}
+	}
+
+	fileName := strings.Trim(name.String(), " ")
+	if fileName == "" || strings.HasPrefix(fileName, ".") {
+		return "", fmt.Errorf("name template %s renders an empty file name for stream %s", template, stream.ID)
+	}
+	return fileName, nil
+}
+
+// UniqueExtractFileName numbers fileName (Episode.eng.2.srt) when another
+// stream of the same run already uses it.
+func UniqueExtractFileName(fileName string, used map[string]bool) string {
+	candidate := fileName
+	ext := filepath.Ext(fileName)
+	for n := 2; used[candidate]; n++ {
+		candidate = strings.TrimSuffix(fileName, ext) + "." + strconv.Itoa(n) + ext
+	}
+	used[candidate] = true
+	return candidate
+}
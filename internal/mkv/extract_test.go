@@ -0,0 +1,186 @@
+package mkv
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderExtractFileName(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		stream   StreamInfo
+		convert  string
+		want     string
+	}{
+		{
+			name:     "default template",
+			template: DefaultExtractNameTemplate,
+			stream:   StreamInfo{ID: "0:2", Codec: "subrip", Language: "chi"},
+			want:     "Episode.chi.srt",
+		},
+		{
+			name:     "drops empty title",
+			template: "{base}.{lang}.{title}.{ext}",
+			stream:   StreamInfo{ID: "0:2", Codec: "ass", Language: "eng"},
+			want:     "Episode.eng.ass",
+		},
+		{
+			name:     "keeps sanitized title",
+			template: "{base}.{lang}.{title}.{ext}",
+			stream:   StreamInfo{ID: "0:2", Codec: "ass", Language: "eng", Title: "Signs/Songs"},
+			want:     "Episode.eng.Signs_Songs.ass",
+		},
+		{
+			name:     "drops undetermined language at the start",
+			template: "{lang}_{base}.{ext}",
+			stream:   StreamInfo{ID: "0:2", Codec: "subrip", Language: "und"},
+			want:     "Episode.srt",
+		},
+		{
+			name:     "empty placeholder right before the extension",
+			template: "{base}{lang}.{ext}",
+			stream:   StreamInfo{ID: "0:2", Codec: "subrip"},
+			want:     "Episode.srt",
+		},
+		{
+			name:     "empty title at the start",
+			template: "{title}.{base}.{ext}",
+			stream:   StreamInfo{ID: "0:2", Codec: "ass", Language: "eng"},
+			want:     "Episode.ass",
+		},
+		{
+			name:     "empty placeholder between two separators",
+			template: "{base} - {title} - {lang}.{ext}",
+			stream:   StreamInfo{ID: "0:2", Codec: "subrip", Language: "eng"},
+			want:     "Episode - eng.srt",
+		},
+		{
+			name:     "consecutive empty placeholders",
+			template: "{base}.{title}.{lang}.{ext}",
+			stream:   StreamInfo{ID: "0:2", Codec: "subrip"},
+			want:     "Episode.srt",
+		},
+		{
+			name:     "stream id",
+			template: "{base}-{id}.{ext}",
+			stream:   StreamInfo{ID: "0:4", Codec: "hdmv_pgs_subtitle", SubtitleFormat: "pgssub"},
+			want:     "Episode-0_4.sup",
+		},
+		{
+			name:     "converted extension",
+			template: DefaultExtractNameTemplate,
+			stream:   StreamInfo{ID: "0:2", Codec: "ass", Language: "eng"},
+			convert:  "srt",
+			want:     "Episode.eng.srt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderExtractFileName(tt.template, "/videos/Episode.mkv", tt.stream, tt.convert)
+			if err != nil {
+				t.Fatalf("RenderExtractFileName() error = %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("RenderExtractFileName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderExtractFileName_RejectsBadTemplates(t *testing.T) {
+	stream := StreamInfo{ID: "0:2", Codec: "subrip"}
+	for template, want := range map[string]string{
+		"{base}.{episode}.{ext}": "unknown placeholder {episode}",
+		"subs/{base}.{ext}":      "path separator",
+		"  ":                     "empty",
+		"{title}.{lang}":         "empty file name",
+		"{title}.{ext}":          "empty file name",
+	} {
+		if _, err := RenderExtractFileName(template, "Episode.mkv", stream, ""); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("template %q: error = %v, want contains %q", template, err, want)
+		}
+	}
+}
+
+func TestUniqueExtractFileName(t *testing.T) {
+	used := map[string]bool{}
+	got := []string{
+		UniqueExtractFileName("Episode.eng.srt", used),
+		UniqueExtractFileName("Episode.eng.srt", used),
+		UniqueExtractFileName("Episode.eng.srt", used),
+		UniqueExtractFileName("Episode.chi.srt", used),
+	}
+	want := []string{"Episode.eng.srt", "Episode.eng.2.srt", "Episode.eng.3.srt", "Episode.chi.srt"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("UniqueExtractFileName() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestSelectSubtitleStreamsForExtraction(t *testing.T) {
+	streams := []StreamInfo{
+		{ID: "0:0", Type: "Video", Codec: "h264"},
+		{ID: "0:1", Type: "Subtitle", Codec: "subrip", Language: "zho"},
+		{ID: "0:2", Type: "Subtitle", Codec: "ass", Language: "eng", SubtitleFormat: "ass"},
+		{ID: "0:3", Type: "Subtitle", Codec: "hdmv_pgs_subtitle", Language: "eng", SubtitleFormat: "pgssub"},
+		{ID: "0:4", Type: "Subtitle", Codec: "subrip"},
+	}
+
+	tests := []struct {
+		name      string
+		selection ExtractSelection
+		want      []string
+	}{
+		{name: "all subtitles", want: []string{"0:1", "0:2", "0:3", "0:4"}},
+		{name: "language aliases", selection: ExtractSelection{Languages: []string{"chi", "eng"}}, want: []string{"0:1", "0:2", "0:3"}},
+		{name: "undetermined", selection: ExtractSelection{Languages: []string{"und"}}, want: []string{"0:4"}},
+		{name: "text", selection: ExtractSelection{Languages: []string{"eng"}, Format: "text"}, want: []string{"0:2"}},
+		{name: "image", selection: ExtractSelection{Format: "image"}, want: []string{"0:3"}},
+		{name: "extension", selection: ExtractSelection{Format: "SRT"}, want: []string{"0:1", "0:4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := SelectSubtitleStreamsForExtraction(streams, tt.selection)
+			if err != nil {
+				t.Fatalf("SelectSubtitleStreamsForExtraction() error = %v", err)
+			}
+			var got []string
+			for _, stream := range selected {
+				got = append(got, stream.ID)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("selected = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := SelectSubtitleStreamsForExtraction(streams, ExtractSelection{Languages: []string{"english"}}); err == nil {
+		t.Fatal("expected invalid language error")
+	}
+}
+
+func TestBuildConvertExtractFFmpegArgs(t *testing.T) {
+	stream := StreamInfo{ID: "0:2", Type: "Subtitle", Codec: "ass", SubtitleFormat: "ass"}
+	args, err := BuildConvertExtractFFmpegArgs("movie.mkv", stream, "out.vtt", "vtt")
+	if err != nil {
+		t.Fatalf("BuildConvertExtractFFmpegArgs() error = %v", err)
+	}
+	want := []string{"-hide_banner", "-i", "movie.mkv", "-map", "0:2", "-c", "webvtt", "out.vtt"}
+	if strings.Join(args, " ") != strings.Join(want, " ") {
+		t.Fatalf("args = %v, want %v", args, want)
+	}
+
+	if _, err := BuildConvertExtractFFmpegArgs("movie.mkv", stream, "out.sub", "sub"); err == nil || !strings.Contains(err.Error(), "unsupported conversion format: sub") {
+		t.Fatalf("error = %v, want unsupported format", err)
+	}
+
+	pgs := StreamInfo{ID: "0:3", Type: "Subtitle", Codec: "hdmv_pgs_subtitle", SubtitleFormat: "pgssub"}
+	_, err = BuildConvertExtractFFmpegArgs("movie.mkv", pgs, "out.srt", "srt")
+	if err == nil || err.Error() != "stream 0:3 is an image-based subtitle (pgssub) and cannot be converted to srt" {
+		t.Fatalf("error = %v, want image conversion error", err)
+	}
+}
@@ -0,0 +1,712 @@
+package mkv
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrNotMatroska = errors.New("not a matroska file")
+
+const (
+	trackTypeVideo    = 0x01
+	trackTypeAudio    = 0x02
+	trackTypeSubtitle = 0x11
+	trackTypeMetadata = 0x21
+
+	defaultTimestampScale = 1000000
+	defaultTrackLanguage  = "eng"
+	undeterminedLanguage  = "und"
+)
+
+type Container struct {
+	DocType     string
+	Info        SegmentInfo
+	Tracks      []Track
+	Attachments []Attachment
+	Chapters    []Chapter
+
+	segment ebmlElement
+	tracks  ebmlElement
+}
+
+type SegmentInfo struct {
+	Title          string
+	MuxingApp      string
+	WritingApp     string
+	TimestampScale uint64
+	Duration       time.Duration
+}
+
+type Track struct {
+	Number            uint64
+	UID               uint64
+	Type              uint64
+	CodecID           string
+	Language          string
+	LanguageIETF      string
+	Name              string
+	IsEnabled         bool
+	IsDefault         bool
+	IsForced          bool
+	IsHearingImpaired bool
+	IsVisualImpaired  bool
+	IsOriginal        bool
+	IsCommentary      bool
+	CodecPrivate      []byte
+
+	// compression is the frame compression of the track, or nil.
+	compression *trackCompression
+}
+
+// Matroska ContentCompAlgo values.
+const (
+	compressionZlib            = 0
+	compressionHeaderStripping = 3
+)
+
+type trackCompression struct {
+	algorithm uint64
+	settings  []byte
+	encrypted bool
+}
+
+type Attachment struct {
+	UID         uint64
+	FileName    string
+	MimeType    string
+	Description string
+	DataOffset  int64
+	Size        int64
+}
+
+type Chapter struct {
+	UID      uint64
+	Start    time.Duration
+	End      time.Duration
+	Title    string
+	Language string
+	Hidden   bool
+}
+
+type containerReader struct {
+	r         io.ReaderAt
+	segment   ebmlElement
+	container *Container
+	visited   map[int64]bool
+	seeks     []int64
+}
+
+func ReadContainer(fileName string) (*Container, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseContainer(file, info.Size())
+}
+
+func ParseContainer(r io.ReaderAt, size int64) (*Container, error) {
+	header, err := readElementHeader(r, 0, size)
+	if err != nil || header.ID != ebmlHeaderID {
+		return nil, ErrNotMatroska
+	}
+
+	container := &Container{
+		DocType: "matroska",
+		Info:    SegmentInfo{TimestampScale: defaultTimestampScale},
+	}
+	if err := forEachChild(r, header, func(child ebmlElement) error {
+		if child.ID != ebmlDocTypeID {
+			return nil
+		}
+		docType, err := readString(r, child)
+		if err != nil {
+			return err
+		}
+		container.DocType = docType
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("invalid ebml header: %w", err)
+	}
+	if container.DocType != "matroska" && container.DocType != "webm" {
+		return nil, ErrNotMatroska
+	}
+
+	segment, err := findSegment(r, header.End(), size)
+	if err != nil {
+		return nil, err
+	}
+
+	container.segment = segment
+	reader := &containerReader{
+		r:         r,
+		segment:   segment,
+		container: container,
+		visited:   make(map[int64]bool),
+	}
+	if err := reader.read(); err != nil {
+		return nil, err
+	}
+	return container, nil
+}
+
+func findSegment(r io.ReaderAt, offset, size int64) (ebmlElement, error) {
+	for offset < size {
+		element, err := readElementHeader(r, offset, size)
+		if err != nil {
+			return ebmlElement{}, fmt.Errorf("failed to find matroska segment: %w", err)
+		}
+		if element.ID == segmentID {
+			return element, nil
+		}
+		offset = element.End()
+	}
+	return ebmlElement{}, fmt.Errorf("failed to find matroska segment: %w", io.ErrUnexpectedEOF)
+}
+
+// read walks the top-level elements of the segment until the first cluster,
+// then follows SeekHead entries to reach metadata stored after the clusters.
+func (cr *containerReader) read() error {
+	offset := cr.segment.DataOffset
+	for offset < cr.segment.End() {
+		element, err := readElementHeader(cr.r, offset, cr.segment.End())
+		if err != nil {
+			return err
+		}
+		if element.ID == clusterID && len(cr.seeks) > 0 {
+			break
+		}
+		if err := cr.readTopLevel(element); err != nil {
+			return err
+		}
+		offset = element.End()
+	}
+
+	for len(cr.seeks) > 0 {
+		position := cr.seeks[0]
+		cr.seeks = cr.seeks[1:]
+		if cr.visited[position] {
+			continue
+		}
+
+		element, err := readElementHeader(cr.r, position, cr.segment.End())
+		if err != nil {
+			return fmt.Errorf("invalid seek position %d: %w", position, err)
+		}
+		if err := cr.readTopLevel(element); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (cr *containerReader) readTopLevel(element ebmlElement) error {
+	if cr.visited[element.Offset] {
+		return nil
+	}
+	cr.visited[element.Offset] = true
+
+	switch element.ID {
+	case seekHeadID:
+		return cr.readSeekHead(element)
+	case infoID:
+		return cr.readInfo(element)
+	case tracksID:
+		return cr.readTracks(element)
+	case attachmentsID:
+		return cr.readAttachments(element)
+	case chaptersID:
+		return cr.readChapters(element)
+	}
+	return nil
+}
+
+func (cr *containerReader) readSeekHead(element ebmlElement) error {
+	return forEachChild(cr.r, element, func(seek ebmlElement) error {
+		if seek.ID != seekID {
+			return nil
+		}
+
+		var targetID uint64
+		position := int64(-1)
+		if err := forEachChild(cr.r, seek, func(child ebmlElement) error {
+			switch child.ID {
+			case seekElementID:
+				data, err := readElementData(cr.r, child)
+				if err != nil {
+					return err
+				}
+				for _, b := range data {
+					targetID = targetID<<8 | uint64(b)
+				}
+			case seekPositionID:
+				value, err := readUnsigned(cr.r, child)
+				if err != nil {
+					return err
+				}
+				position = int64(value)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		switch targetID {
+		case seekHeadID, infoID, tracksID, attachmentsID, chaptersID:
+		default:
+			return nil
+		}
+		absolute := cr.segment.DataOffset + position
+		if position >= 0 && absolute < cr.segment.End() {
+			cr.seeks = append(cr.seeks, absolute)
+		}
+		return nil
+	})
+}
+
+func (cr *containerReader) readInfo(element ebmlElement) error {
+	info := &cr.container.Info
+	var duration float64
+	if err := forEachChild(cr.r, element, func(child ebmlElement) error {
+		var err error
+		switch child.ID {
+		case timestampScaleID:
+			info.TimestampScale, err = readUnsigned(cr.r, child)
+		case durationID:
+			duration, err = readFloat(cr.r, child)
+		case segmentTitleID:
+			info.Title, err = readString(cr.r, child)
+		case muxingAppID:
+			info.MuxingApp, err = readString(cr.r, child)
+		case writingAppID:
+			info.WritingApp, err = readString(cr.r, child)
+		}
+		return err
+	}); err != nil {
+		return fmt.Errorf("invalid segment info: %w", err)
+	}
+
+	if info.TimestampScale == 0 {
+		info.TimestampScale = defaultTimestampScale
+	}
+	info.Duration = time.Duration(duration * float64(info.TimestampScale))
+	return nil
+}
+
+func (cr *containerReader) readTracks(element ebmlElement) error {
+	if cr.container.tracks.ID == tracksID {
+		return nil
+	}
+	cr.container.tracks = element
+
+	return forEachChild(cr.r, element, func(entry ebmlElement) error {
+		if entry.ID != trackEntryID {
+			return nil
+		}
+
+		track, err := readTrackEntry(cr.r, entry)
+		if err != nil {
+			return fmt.Errorf("invalid track entry: %w", err)
+		}
+		cr.container.Tracks = append(cr.container.Tracks, track)
+		return nil
+	})
+}
+
+func readTrackEntry(r io.ReaderAt, entry ebmlElement) (Track, error) {
+	track := Track{
+		Language:  defaultTrackLanguage,
+		IsEnabled: true,
+		IsDefault: true,
+	}
+
+	err := forEachChild(r, entry, func(child ebmlElement) error {
+		var err error
+		switch child.ID {
+		case trackNumberID:
+			track.Number, err = readUnsigned(r, child)
+		case trackUIDID:
+			track.UID, err = readUnsigned(r, child)
+		case trackTypeID:
+			track.Type, err = readUnsigned(r, child)
+		case codecIDID:
+			track.CodecID, err = readString(r, child)
+		case trackLanguageID:
+			track.Language, err = readString(r, child)
+		case trackLanguageIETFID:
+			track.LanguageIETF, err = readString(r, child)
+		case trackNameID:
+			track.Name, err = readString(r, child)
+		case flagEnabledID:
+			track.IsEnabled, err = readFlag(r, child)
+		case flagDefaultID:
+			track.IsDefault, err = readFlag(r, child)
+		case flagForcedID:
+			track.IsForced, err = readFlag(r, child)
+		case flagHearingImpairedID:
+			track.IsHearingImpaired, err = readFlag(r, child)
+		case flagVisualImpairedID:
+			track.IsVisualImpaired, err = readFlag(r, child)
+		case flagOriginalID:
+			track.IsOriginal, err = readFlag(r, child)
+		case flagCommentaryID:
+			track.IsCommentary, err = readFlag(r, child)
+		case codecPrivateID:
+			track.CodecPrivate, err = readElementData(r, child)
+		case contentEncodingsID:
+			track.compression, err = readContentEncodings(r, child)
+		}
+		return err
+	})
+	return track, err
+}
+
+// readContentEncodings returns the frame compression of a track, which is
+// flagged as encrypted when any encoding is not a compression.
+func readContentEncodings(r io.ReaderAt, encodings ebmlElement) (*trackCompression, error) {
+	compression := &trackCompression{algorithm: compressionZlib}
+	hasCompression := false
+	err := forEachChild(r, encodings, func(encoding ebmlElement) error {
+		if encoding.ID != contentEncodingID {
+			return nil
+		}
+		return forEachChild(r, encoding, func(child ebmlElement) error {
+			switch child.ID {
+			case contentEncodingTypeID:
+				encodingType, err := readUnsigned(r, child)
+				if err != nil {
+					return err
+				}
+				compression.encrypted = compression.encrypted || encodingType != 0
+			case contentCompressionID:
+				hasCompression = true
+				return forEachChild(r, child, func(setting ebmlElement) error {
+					var err error
+					switch setting.ID {
+					case contentCompAlgoID:
+						compression.algorithm, err = readUnsigned(r, setting)
+					case contentCompSettingsID:
+						compression.settings, err = readElementData(r, setting)
+					}
+					return err
+				})
+			}
+			return nil
+		})
+	})
+	if !hasCompression && !compression.encrypted {
+		return nil, err
+	}
+	return compression, err
+}
+
+func readFlag(r io.ReaderAt, element ebmlElement) (bool, error) {
+	value, err := readUnsigned(r, element)
+	return value != 0, err
+}
+
+func (cr *containerReader) readAttachments(element ebmlElement) error {
+	return forEachChild(cr.r, element, func(file ebmlElement) error {
+		if file.ID != attachedFileID {
+			return nil
+		}
+
+		var attachment Attachment
+		if err := forEachChild(cr.r, file, func(child ebmlElement) error {
+			var err error
+			switch child.ID {
+			case fileUIDID:
+				attachment.UID, err = readUnsigned(cr.r, child)
+			case fileNameID:
+				attachment.FileName, err = readString(cr.r, child)
+			case fileMimeTypeID:
+				attachment.MimeType, err = readString(cr.r, child)
+			case fileDescriptionID:
+				attachment.Description, err = readString(cr.r, child)
+			case fileDataID:
+				attachment.DataOffset = child.DataOffset
+				attachment.Size = child.Size
+			}
+			return err
+		}); err != nil {
+			return fmt.Errorf("invalid attachment: %w", err)
+		}
+
+		cr.container.Attachments = append(cr.container.Attachments, attachment)
+		return nil
+	})
+}
+
+func (cr *containerReader) readChapters(element ebmlElement) error {
+	return forEachChild(cr.r, element, func(edition ebmlElement) error {
+		if edition.ID != editionEntryID {
+			return nil
+		}
+		if err := cr.readChapterAtoms(edition); err != nil {
+			return fmt.Errorf("invalid chapter: %w", err)
+		}
+		return nil
+	})
+}
+
+func (cr *containerReader) readChapterAtoms(parent ebmlElement) error {
+	return forEachChild(cr.r, parent, func(atom ebmlElement) error {
+		if atom.ID != chapterAtomID {
+			return nil
+		}
+
+		var chapter Chapter
+		if err := forEachChild(cr.r, atom, func(child ebmlElement) error {
+			var err error
+			switch child.ID {
+			case chapterUIDID:
+				chapter.UID, err = readUnsigned(cr.r, child)
+			case chapterTimeStartID:
+				var start uint64
+				start, err = readUnsigned(cr.r, child)
+				chapter.Start = time.Duration(start)
+			case chapterTimeEndID:
+				var end uint64
+				end, err = readUnsigned(cr.r, child)
+				chapter.End = time.Duration(end)
+			case chapterFlagHiddenID:
+				chapter.Hidden, err = readFlag(cr.r, child)
+			case chapterDisplayID:
+				if chapter.Title == "" {
+					chapter.Title, chapter.Language, err = readChapterDisplay(cr.r, child)
+				}
+			}
+			return err
+		}); err != nil {
+			return err
+		}
+
+		cr.container.Chapters = append(cr.container.Chapters, chapter)
+		return cr.readChapterAtoms(atom)
+	})
+}
+
+func readChapterDisplay(r io.ReaderAt, display ebmlElement) (string, string, error) {
+	var title, language string
+	err := forEachChild(r, display, func(child ebmlElement) error {
+		var err error
+		switch child.ID {
+		case chapterStringID:
+			title, err = readString(r, child)
+		case chapterLanguageID:
+			if language == "" {
+				language, err = readString(r, child)
+			}
+		case chapterLanguageIETFID:
+			language, err = readString(r, child)
+		}
+		return err
+	})
+	return title, language, err
+}
+
+// Streams converts the tracks and attachments to the stream list ffmpeg
+// reports for the same file, so both sources can be used interchangeably.
+// Entries ffmpeg creates no stream for are left out, so the IDs match.
+func (c *Container) Streams() []StreamInfo {
+	streams := make([]StreamInfo, 0, len(c.Tracks)+len(c.Attachments))
+	for _, track := range c.Tracks {
+		if !hasStream(track) {
+			continue
+		}
+		stream := StreamInfo{
+			ID:                "0:" + strconv.Itoa(len(streams)),
+			Type:              trackStreamType(track.Type),
+			Codec:             codecNameForID(track.CodecID),
+			Title:             track.Name,
+			IsDefault:         track.IsDefault,
+			IsForced:          track.IsForced,
+			IsHearingImpaired: track.IsHearingImpaired,
+		}
+		if track.Language != undeterminedLanguage {
+			stream.Language = track.Language
+		}
+		if stream.Type == "Subtitle" {
+			stream.SubtitleFormat = subtitleFormatForCodec(stream.Codec)
+		}
+		streams = append(streams, stream)
+	}
+
+	for _, attachment := range c.Attachments {
+		if !attachmentHasStream(attachment) {
+			continue
+		}
+		streams = append(streams, StreamInfo{
+			ID:    "0:" + strconv.Itoa(len(streams)),
+			Type:  "Attachment",
+			Codec: attachmentCodecName(attachment),
+			Title: attachment.Description,
+		})
+	}
+
+	return streams
+}
+
+// hasStream reports whether ffmpeg creates a stream for track. It skips
+// tracks of other types, such as buttons, and tracks without a codec.
+func hasStream(track Track) bool {
+	switch track.Type {
+	case trackTypeVideo, trackTypeAudio, trackTypeSubtitle, trackTypeMetadata:
+		return track.CodecID != ""
+	}
+	return false
+}
+
+// attachmentHasStream reports whether ffmpeg creates a stream for
+// attachment. Incomplete attachments are skipped with a warning.
+func attachmentHasStream(attachment Attachment) bool {
+	return attachment.FileName != "" && attachment.MimeType != "" && attachment.Size > 0
+}
+
+// trackIndex returns the index in c.Tracks of the track ffmpeg numbers as
+// stream index.
+func (c *Container) trackIndex(streamIndex int) (int, bool) {
+	for i, track := range c.Tracks {
+		if !hasStream(track) {
+			continue
+		}
+		if streamIndex == 0 {
+			return i, true
+		}
+		streamIndex--
+	}
+	return -1, false
+}
+
+func trackStreamType(trackType uint64) string {
+	switch trackType {
+	case trackTypeVideo:
+		return "Video"
+	case trackTypeAudio:
+		return "Audio"
+	case trackTypeSubtitle:
+		return "Subtitle"
+	default:
+		return "Data"
+	}
+}
+
+var matroskaCodecNames = map[string]string{
+	"V_MPEG4/ISO/AVC":    "h264",
+	"V_MPEGH/ISO/HEVC":   "hevc",
+	"V_AV1":              "av1",
+	"V_VP8":              "vp8",
+	"V_VP9":              "vp9",
+	"V_MPEG2":            "mpeg2video",
+	"V_MPEG4/ISO/ASP":    "mpeg4",
+	"V_MS/VFW/FOURCC":    "rawvideo",
+	"A_AAC":              "aac",
+	"A_AC3":              "ac3",
+	"A_EAC3":             "eac3",
+	"A_DTS":              "dts",
+	"A_FLAC":             "flac",
+	"A_MPEG/L3":          "mp3",
+	"A_MPEG/L2":          "mp2",
+	"A_OPUS":             "opus",
+	"A_TRUEHD":           "truehd",
+	"A_VORBIS":           "vorbis",
+	"A_PCM/INT/LIT":      "pcm_s16le",
+	"S_TEXT/UTF8":        "subrip",
+	"S_TEXT/ASCII":       "subrip",
+	"S_TEXT/ASS":         "ass",
+	"S_TEXT/SSA":         "ass",
+	"S_ASS":              "ass",
+	"S_SSA":              "ass",
+	"S_TEXT/WEBVTT":      "webvtt",
+	"S_HDMV/PGS":         "hdmv_pgs_subtitle",
+	"S_HDMV/TEXTST":      "hdmv_text_subtitle",
+	"S_VOBSUB":           "dvd_subtitle",
+	"S_DVBSUB":           "dvb_subtitle",
+	"S_ARIBSUB":          "arib_caption",
+	"S_KATE":             "kate",
+	"D_WEBVTT/SUBTITLES": "webvtt",
+}
+
+func codecNameForID(codecID string) string {
+	if name, ok := matroskaCodecNames[codecID]; ok {
+		return name
+	}
+	switch {
+	case strings.HasPrefix(codecID, "A_AAC/"):
+		return "aac"
+	case strings.HasPrefix(codecID, "A_PCM/"):
+		return "pcm_s16le"
+	}
+	return strings.ToLower(codecID)
+}
+
+// subtitleDecoderNames lists the decoder ffmpeg prints in parentheses after
+// the codec name, which ParseSubtitleFormat turns into the displayed format.
+var subtitleDecoderNames = map[string]string{
+	"subrip":            "srt",
+	"ass":               "ssa",
+	"hdmv_pgs_subtitle": "pgssub",
+	"dvd_subtitle":      "dvdsub",
+	"dvb_subtitle":      "dvbsub",
+}
+
+func subtitleFormatForCodec(codec string) string {
+	if decoder, ok := subtitleDecoderNames[codec]; ok {
+		return ParseSubtitleFormat(codec + " (" + decoder + ")")
+	}
+	return codec
+}
+
+func attachmentCodecName(attachment Attachment) string {
+	switch strings.ToLower(attachment.MimeType) {
+	case "application/x-truetype-font", "application/x-font-ttf", "font/ttf", "font/sfnt":
+		return "ttf"
+	case "application/vnd.ms-opentype", "application/x-font-opentype", "font/otf":
+		return "otf"
+	}
+
+	switch strings.ToLower(strings.TrimPrefix(filepath.Ext(attachment.FileName), ".")) {
+	case "ttf", "ttc":
+		return "ttf"
+	case "otf":
+		return "otf"
+	}
+	return "none"
+}
+
+// ReadStreams lists streams from the Matroska header without running ffmpeg.
+func ReadStreams(fileName string) ([]StreamInfo, error) {
+	container, err := ReadContainer(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return container.Streams(), nil
+}
+
+// ProbeStreams reads streams natively and only falls back to ffmpeg when the
+// file cannot be parsed as Matroska.
+func ProbeStreams(fileName string) ([]StreamInfo, error) {
+	if streams, err := ReadStreams(fileName); err == nil {
+		return streams, nil
+	} else if os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if err := RequireFFmpegInstalled(); err != nil {
+		return nil, err
+	}
+	return ListStreams(fileName)
+}
@@ -0,0 +1,417 @@
+package mkv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadContainer_SampleFile(t *testing.T) {
+	samplePath := filepath.Join("..", "..", "resources", "low_quality_with_subtitles_5s.mkv")
+	if _, err := os.Stat(samplePath); err != nil {
+		t.Skip("skip matroska test: sample mkv not found")
+	}
+
+	container, err := ReadContainer(samplePath)
+	if err != nil {
+		t.Fatalf("ReadContainer() error = %v", err)
+	}
+
+	if container.Info.Title != "Big Buck Bunny - test 8" {
+		t.Fatalf("segment title = %q", container.Info.Title)
+	}
+	if container.Info.Duration < 5*time.Second || container.Info.Duration > 6*time.Second {
+		t.Fatalf("segment duration = %v, want about 5s", container.Info.Duration)
+	}
+	if len(container.Tracks) != 11 {
+		t.Fatalf("track count = %d, want 11", len(container.Tracks))
+	}
+	if container.Tracks[0].CodecID != "V_MPEGH/ISO/HEVC" {
+		t.Fatalf("video codec id = %q", container.Tracks[0].CodecID)
+	}
+	if container.Tracks[8].Name != "Commentary" || container.Tracks[8].Type != trackTypeAudio {
+		t.Fatalf("unexpected commentary track %+v", container.Tracks[8])
+	}
+
+	streams := container.Streams()
+	want := []StreamInfo{
+		{ID: "0:0", Type: "Video", Codec: "hevc", IsDefault: true},
+		{ID: "0:1", Type: "Audio", Codec: "aac", IsDefault: true},
+		{ID: "0:2", Type: "Subtitle", Codec: "subrip", Language: "eng", SubtitleFormat: "srt", IsDefault: true},
+		{ID: "0:3", Type: "Subtitle", Codec: "subrip", Language: "hun", SubtitleFormat: "srt"},
+		{ID: "0:4", Type: "Subtitle", Codec: "subrip", Language: "ger", SubtitleFormat: "srt"},
+		{ID: "0:5", Type: "Subtitle", Codec: "subrip", Language: "fre", SubtitleFormat: "srt"},
+		{ID: "0:6", Type: "Subtitle", Codec: "subrip", Language: "spa", SubtitleFormat: "srt"},
+		{ID: "0:7", Type: "Subtitle", Codec: "subrip", Language: "ita", SubtitleFormat: "srt"},
+		{ID: "0:8", Type: "Audio", Codec: "aac", Language: "eng", Title: "Commentary"},
+		{ID: "0:9", Type: "Subtitle", Codec: "subrip", Language: "jpn", SubtitleFormat: "srt"},
+		{ID: "0:10", Type: "Subtitle", Codec: "subrip", SubtitleFormat: "srt"},
+	}
+	if len(streams) != len(want) {
+		t.Fatalf("stream count = %d, want %d", len(streams), len(want))
+	}
+	for i := range want {
+		if streams[i] != want[i] {
+			t.Fatalf("stream %d = %+v, want %+v", i, streams[i], want[i])
+		}
+	}
+}
+
+func TestParseContainer_SynthesizedFile(t *testing.T) {
+	data := buildTestMatroska(t)
+
+	container, err := ParseContainer(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("ParseContainer() error = %v", err)
+	}
+
+	if container.Info.MuxingApp != "subs-test" {
+		t.Fatalf("muxing app = %q", container.Info.MuxingApp)
+	}
+	if container.Info.Duration != 90*time.Second {
+		t.Fatalf("duration = %v, want 1m30s", container.Info.Duration)
+	}
+
+	if len(container.Tracks) != 2 {
+		t.Fatalf("track count = %d, want 2", len(container.Tracks))
+	}
+	subtitle := container.Tracks[1]
+	if subtitle.Language != "chi" || subtitle.LanguageIETF != "zh-Hans" || subtitle.Name != "简体中文" {
+		t.Fatalf("unexpected subtitle metadata %+v", subtitle)
+	}
+	if subtitle.IsDefault || !subtitle.IsForced || !subtitle.IsHearingImpaired {
+		t.Fatalf("unexpected subtitle flags %+v", subtitle)
+	}
+
+	if len(container.Attachments) != 1 {
+		t.Fatalf("attachment count = %d, want 1", len(container.Attachments))
+	}
+	attachment := container.Attachments[0]
+	if attachment.FileName != "font.ttf" || attachment.MimeType != "font/ttf" || attachment.Size != 4 {
+		t.Fatalf("unexpected attachment %+v", attachment)
+	}
+	if got := string(data[attachment.DataOffset : attachment.DataOffset+attachment.Size]); got != "FONT" {
+		t.Fatalf("attachment data = %q, want FONT", got)
+	}
+
+	if len(container.Chapters) != 2 {
+		t.Fatalf("chapter count = %d, want 2", len(container.Chapters))
+	}
+	if container.Chapters[0].Title != "Opening" || container.Chapters[0].Language != "eng" {
+		t.Fatalf("unexpected first chapter %+v", container.Chapters[0])
+	}
+	if container.Chapters[1].Start != 60*time.Second || container.Chapters[1].Title != "Part B" {
+		t.Fatalf("unexpected nested chapter %+v", container.Chapters[1])
+	}
+
+	streams := container.Streams()
+	if len(streams) != 3 {
+		t.Fatalf("stream count = %d, want 3", len(streams))
+	}
+	if streams[1].SubtitleFormat != "ass (ssa)" || streams[1].Codec != "ass" || !streams[1].IsHearingImpaired {
+		t.Fatalf("unexpected subtitle stream %+v", streams[1])
+	}
+	if streams[2].ID != "0:2" || streams[2].Type != "Attachment" || streams[2].Codec != "ttf" {
+		t.Fatalf("unexpected attachment stream %+v", streams[2])
+	}
+}
+
+func TestContainerStreams_SkipsEntriesWithoutStream(t *testing.T) {
+	data := buildSkippedEntriesMatroska(t)
+
+	container, err := ParseContainer(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("ParseContainer() error = %v", err)
+	}
+	if len(container.Tracks) != 5 || len(container.Attachments) != 2 {
+		t.Fatalf("parsed %d tracks and %d attachments, want 5 and 2", len(container.Tracks), len(container.Attachments))
+	}
+
+	streams := container.Streams()
+	want := []StreamInfo{
+		{ID: "0:0", Type: "Video", Codec: "h264", Language: "eng", IsDefault: true},
+		{ID: "0:1", Type: "Data", Codec: "d_webvtt/metadata", Language: "eng", IsDefault: true},
+		{ID: "0:2", Type: "Subtitle", Codec: "subrip", Language: "chi", SubtitleFormat: "srt", IsDefault: true},
+		{ID: "0:3", Type: "Attachment", Codec: "ttf"},
+	}
+	if len(streams) != len(want) {
+		t.Fatalf("streams = %+v, want %+v", streams, want)
+	}
+	for i := range want {
+		if streams[i] != want[i] {
+			t.Fatalf("stream %d = %+v, want %+v", i, streams[i], want[i])
+		}
+	}
+}
+
+func TestParseContainer_RejectsNonMatroska(t *testing.T) {
+	data := []byte("not a matroska file")
+	if _, err := ParseContainer(bytes.NewReader(data), int64(len(data))); !errors.Is(err, ErrNotMatroska) {
+		t.Fatalf("ParseContainer() error = %v, want ErrNotMatroska", err)
+	}
+
+	header := ebmlTestMaster(ebmlHeaderID, ebmlTestString(ebmlDocTypeID, "avi"))
+	if _, err := ParseContainer(bytes.NewReader(header), int64(len(header))); !errors.Is(err, ErrNotMatroska) {
+		t.Fatalf("ParseContainer() for unknown doctype error = %v, want ErrNotMatroska", err)
+	}
+}
+
+func TestParseContainer_RejectsTruncatedTracks(t *testing.T) {
+	data := buildTestMatroska(t)
+	truncated := data[:len(data)/2]
+	if _, err := ParseContainer(bytes.NewReader(truncated), int64(len(truncated))); err == nil {
+		t.Fatal("expected error for truncated file")
+	}
+}
+
+func TestDecodeVint(t *testing.T) {
+	value, length, err := decodeVint([]byte{0x81})
+	if err != nil || value != 1 || length != 1 {
+		t.Fatalf("decodeVint(0x81) = %d, %d, %v", value, length, err)
+	}
+
+	value, length, err = decodeVint([]byte{0x40, 0x02})
+	if err != nil || value != 2 || length != 2 {
+		t.Fatalf("decodeVint(0x4002) = %d, %d, %v", value, length, err)
+	}
+	if !isUnknownVintSize(0x7F, 1) {
+		t.Fatal("expected 0xFF to be an unknown size")
+	}
+
+	if _, _, err := decodeVint([]byte{0x00}); err == nil {
+		t.Fatal("expected invalid vint error")
+	}
+	if _, _, err := decodeVint([]byte{0x20, 0x00}); err == nil {
+		t.Fatal("expected truncated vint error")
+	}
+}
+
+func TestProbeStreams_FallsBackToFFmpeg(t *testing.T) {
+	old := ffmpegRunner
+	t.Cleanup(func() { ffmpegRunner = old })
+
+	fileName := filepath.Join(t.TempDir(), "mock.mkv")
+	if err := os.WriteFile(fileName, []byte("mock"), 0o644); err != nil {
+		t.Fatalf("write mock failed: %v", err)
+	}
+
+	runner := &fakeFFmpegRunner{
+		installed: true,
+		output:    "Stream #0:0: Video: h264\nStream #0:1(eng): Subtitle: subrip (srt) (hearing_impaired)\n",
+	}
+	SetFFmpegRunner(runner)
+
+	streams, err := ProbeStreams(fileName)
+	if err != nil {
+		t.Fatalf("ProbeStreams() error = %v", err)
+	}
+	if len(streams) != 2 || streams[1].Codec != "subrip" || !streams[1].IsHearingImpaired {
+		t.Fatalf("unexpected streams %+v", streams)
+	}
+
+	SetFFmpegRunner(&fakeFFmpegRunner{installed: false})
+	if _, err := ProbeStreams(fileName); err == nil {
+		t.Fatal("expected ffmpeg missing error")
+	}
+}
+
+// buildTestMatroska synthesizes a minimal file whose Attachments and Chapters
+// sit after the first cluster, so they are only reachable through SeekHead.
+func buildTestMatroska(t *testing.T) []byte {
+	t.Helper()
+	return buildTestMatroskaWithCluster(t, ebmlTestMaster(clusterID, ebmlTestUint(clusterTimestampID, 0)))
+}
+
+func buildTestMatroskaWithCluster(t *testing.T, cluster []byte) []byte {
+	t.Helper()
+
+	info := ebmlTestMaster(infoID,
+		ebmlTestUint(timestampScaleID, 1000000),
+		ebmlTestFloat(durationID, 90000),
+		ebmlTestString(muxingAppID, "subs-test"),
+	)
+	tracks := ebmlTestMaster(tracksID,
+		ebmlTestMaster(trackEntryID,
+			ebmlTestUint(trackNumberID, 1),
+			ebmlTestUint(trackTypeID, trackTypeVideo),
+			ebmlTestString(codecIDID, "V_MPEG4/ISO/AVC"),
+		),
+		ebmlTestMaster(trackEntryID,
+			ebmlTestUint(trackNumberID, 2),
+			ebmlTestUint(trackTypeID, trackTypeSubtitle),
+			ebmlTestString(codecIDID, "S_TEXT/ASS"),
+			ebmlTestString(trackLanguageID, "chi"),
+			ebmlTestString(trackLanguageIETFID, "zh-Hans"),
+			ebmlTestString(trackNameID, "简体中文"),
+			ebmlTestUint(flagDefaultID, 0),
+			ebmlTestUint(flagForcedID, 1),
+			ebmlTestUint(flagHearingImpairedID, 1),
+		),
+	)
+	attachments := ebmlTestMaster(attachmentsID,
+		ebmlTestMaster(attachedFileID,
+			ebmlTestString(fileNameID, "font.ttf"),
+			ebmlTestString(fileMimeTypeID, "font/ttf"),
+			ebmlTestString(fileDataID, "FONT"),
+			ebmlTestUint(fileUIDID, 7),
+		),
+	)
+	chapters := ebmlTestMaster(chaptersID,
+		ebmlTestMaster(editionEntryID,
+			ebmlTestMaster(chapterAtomID,
+				ebmlTestUint(chapterUIDID, 1),
+				ebmlTestUint(chapterTimeStartID, 0),
+				ebmlTestMaster(chapterDisplayID,
+					ebmlTestString(chapterStringID, "Opening"),
+					ebmlTestString(chapterLanguageID, "eng"),
+				),
+				ebmlTestMaster(chapterAtomID,
+					ebmlTestUint(chapterUIDID, 2),
+					ebmlTestUint(chapterTimeStartID, uint64(60*time.Second)),
+					ebmlTestMaster(chapterDisplayID, ebmlTestString(chapterStringID, "Part B")),
+				),
+			),
+		),
+	)
+
+	// Seek positions are written with a fixed width so the SeekHead size does
+	// not depend on the positions it stores.
+	seekEntry := func(id uint32, position int) []byte {
+		var rawID, rawPosition [4]byte
+		binary.BigEndian.PutUint32(rawID[:], id)
+		binary.BigEndian.PutUint32(rawPosition[:], uint32(position))
+		return ebmlTestMaster(seekID,
+			ebmlTestBytes(seekElementID, rawID[:]),
+			ebmlTestBytes(seekPositionID, rawPosition[:]),
+		)
+	}
+	seekHeadSize := len(ebmlTestMaster(seekHeadID, seekEntry(attachmentsID, 0), seekEntry(chaptersID, 0)))
+	attachmentsPosition := seekHeadSize + len(info) + len(tracks) + len(cluster)
+	chaptersPosition := attachmentsPosition + len(attachments)
+	seekHead := ebmlTestMaster(seekHeadID,
+		seekEntry(attachmentsID, attachmentsPosition),
+		seekEntry(chaptersID, chaptersPosition),
+	)
+
+	segment := ebmlTestMaster(segmentID, seekHead, info, tracks, cluster, attachments, chapters)
+	header := ebmlTestMaster(ebmlHeaderID, ebmlTestString(ebmlDocTypeID, "matroska"))
+	return append(header, segment...)
+}
+
+// buildSkippedEntriesMatroska synthesizes a file with a button track, a
+// track without a codec and an attachment without a MIME type, none of
+// which ffmpeg creates a stream for. The Tracks element is followed by Void
+// padding so it can be edited in place.
+func buildSkippedEntriesMatroska(t *testing.T) []byte {
+	t.Helper()
+
+	tracks := ebmlTestMaster(tracksID,
+		ebmlTestMaster(trackEntryID,
+			ebmlTestUint(trackNumberID, 1),
+			ebmlTestUint(trackTypeID, trackTypeVideo),
+			ebmlTestString(codecIDID, "V_MPEG4/ISO/AVC"),
+		),
+		ebmlTestMaster(trackEntryID,
+			ebmlTestUint(trackNumberID, 2),
+			ebmlTestUint(trackTypeID, 0x12),
+			ebmlTestString(codecIDID, "B_VOBBTN"),
+		),
+		ebmlTestMaster(trackEntryID,
+			ebmlTestUint(trackNumberID, 3),
+			ebmlTestUint(trackTypeID, trackTypeAudio),
+		),
+		ebmlTestMaster(trackEntryID,
+			ebmlTestUint(trackNumberID, 4),
+			ebmlTestUint(trackTypeID, trackTypeMetadata),
+			ebmlTestString(codecIDID, "D_WEBVTT/METADATA"),
+		),
+		ebmlTestMaster(trackEntryID,
+			ebmlTestUint(trackNumberID, 5),
+			ebmlTestUint(trackTypeID, trackTypeSubtitle),
+			ebmlTestString(codecIDID, "S_TEXT/UTF8"),
+			ebmlTestString(trackLanguageID, "chi"),
+		),
+	)
+	void, err := encodeVoid(32)
+	if err != nil {
+		t.Fatalf("encodeVoid() error = %v", err)
+	}
+	attachments := ebmlTestMaster(attachmentsID,
+		ebmlTestMaster(attachedFileID,
+			ebmlTestString(fileNameID, "cover.bin"),
+			ebmlTestString(fileDataID, "DATA"),
+			ebmlTestUint(fileUIDID, 1),
+		),
+		ebmlTestMaster(attachedFileID,
+			ebmlTestString(fileNameID, "font.ttf"),
+			ebmlTestString(fileMimeTypeID, "font/ttf"),
+			ebmlTestString(fileDataID, "FONT"),
+			ebmlTestUint(fileUIDID, 2),
+		),
+	)
+	cluster := ebmlTestMaster(clusterID, ebmlTestUint(0xE7, 0))
+
+	segment := ebmlTestMaster(segmentID, tracks, void, attachments, cluster)
+	header := ebmlTestMaster(ebmlHeaderID, ebmlTestString(ebmlDocTypeID, "matroska"))
+	return append(header, segment...)
+}
+
+func ebmlTestID(id uint32) []byte {
+	var raw [4]byte
+	binary.BigEndian.PutUint32(raw[:], id)
+	start := 0
+	for start < 3 && raw[start] == 0 {
+		start++
+	}
+	return raw[start:]
+}
+
+func ebmlTestSize(size int) []byte {
+	for length := 1; length <= 8; length++ {
+		if uint64(size) < 1<<(7*uint(length))-1 {
+			encoded := make([]byte, length)
+			value := uint64(size)
+			for i := length - 1; i >= 0; i-- {
+				encoded[i] = byte(value)
+				value >>= 8
+			}
+			encoded[0] |= 0x80 >> (length - 1)
+			return encoded
+		}
+	}
+	panic("ebml test element too large")
+}
+
+func ebmlTestBytes(id uint32, data []byte) []byte {
+	element := append(ebmlTestID(id), ebmlTestSize(len(data))...)
+	return append(element, data...)
+}
+
+func ebmlTestMaster(id uint32, children ...[]byte) []byte {
+	return ebmlTestBytes(id, bytes.Join(children, nil))
+}
+
+func ebmlTestString(id uint32, value string) []byte {
+	return ebmlTestBytes(id, []byte(value))
+}
+
+func ebmlTestUint(id uint32, value uint64) []byte {
+	var raw [8]byte
+	binary.BigEndian.PutUint64(raw[:], value)
+	start := 0
+	for start < 7 && raw[start] == 0 {
+		start++
+	}
+	return ebmlTestBytes(id, raw[start:])
+}
+
+func ebmlTestFloat(id uint32, value float64) []byte {
+	var raw [8]byte
+	binary.BigEndian.PutUint64(raw[:], math.Float64bits(value))
+	return ebmlTestBytes(id, raw[:])
+}
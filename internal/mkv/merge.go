@@ -0,0 +1,263 @@
+package mkv
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MergeSubtitle is one subtitle file to add to an mkv, with the metadata
+// and dispositions of the stream it becomes.
+type MergeSubtitle struct {
+	Path     string
+	Language string
+	Title    string
+	Default  bool
+	Forced   bool
+	// HearingImpaired marks SDH subtitles.
+	HearingImpaired bool
+	// Delay shifts the subtitle timing; negative values make it earlier.
+	Delay time.Duration
+	// CharEnc is the character encoding of a non-UTF-8 source file. The
+	// subtitle is then re-encoded to UTF-8 instead of stream copied.
+	CharEnc string
+}
+
+// ParseMergeSubtitleSpec parses a merge argument of the form
+// file.srt[:lang=chi][:title=简体][:default][:forced][:sdh][:delay=1.5s][:charenc=GBK].
+// An argument naming an
+// existing file is taken as a plain path, so file names containing colons
+// keep working.
+func ParseMergeSubtitleSpec(spec string) (MergeSubtitle, error) {
+	if _, err := os.Stat(spec); err == nil {
+		return MergeSubtitle{Path: spec}, nil
+	}
+
+	parts := strings.Split(spec, ":")
+	subtitle := MergeSubtitle{}
+	end := len(parts)
+	for end > 1 {
+		option := parts[end-1]
+		key, value, hasValue := strings.Cut(option, "=")
+		switch {
+		case hasValue && (key == "lang" || key == "language"):
+			if subtitle.Language == "" {
+				subtitle.Language = value
+			}
+		case hasValue && key == "title":
+			if subtitle.Title == "" {
+				subtitle.Title = value
+			}
+		case hasValue && key == "delay":
+			delay, err := ParseSubtitleDelay(value)
+			if err != nil {
+				return MergeSubtitle{}, err
+			}
+			subtitle.Delay = delay
+		case hasValue && key == "charenc":
+			subtitle.CharEnc = value
+		case !hasValue && option == "default":
+			subtitle.Default = true
+		case !hasValue && option == "forced":
+			subtitle.Forced = true
+		case !hasValue && (option == "hearing-impaired" || option == "sdh"):
+			subtitle.HearingImpaired = true
+		default:
+			if strings.Contains(option, "=") {
+				return MergeSubtitle{}, fmt.Errorf("unknown merge option %q in %s", key, spec)
+			}
+			subtitle.Path = strings.Join(parts[:end], ":")
+			return subtitle, nil
+		}
+		end--
+	}
+
+	subtitle.Path = strings.Join(parts[:end], ":")
+	return subtitle, nil
+}
+
+// ParseSubtitleDelay parses a subtitle offset such as 1.5s, -500ms or a
+// plain number of seconds.
+func ParseSubtitleDelay(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if delay, err := time.ParseDuration(value); err == nil {
+		return delay, nil
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid subtitle delay: %s", value)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// Merge default policies: auto makes the first new subtitle default unless it
+// is forced or hearing impaired, yes always does, and no keeps the existing
+// default. A subtitle marked default on its own is never overridden.
+const (
+	MergeDefaultAuto = "auto"
+	MergeDefaultYes  = "yes"
+	MergeDefaultNo   = "no"
+)
+
+func ValidateMergeDefaultPolicy(policy string) error {
+	switch policy {
+	case MergeDefaultAuto, MergeDefaultYes, MergeDefaultNo:
+		return nil
+	}
+	return fmt.Errorf("invalid default policy: %s (want auto, yes or no)", policy)
+}
+
+func ApplyMergeDefaultPolicy(subtitles []MergeSubtitle, policy string) error {
+	if err := ValidateMergeDefaultPolicy(policy); err != nil {
+		return err
+	}
+
+	for _, subtitle := range subtitles {
+		if subtitle.Default {
+			return nil
+		}
+	}
+	if len(subtitles) == 0 || policy == MergeDefaultNo {
+		return nil
+	}
+
+	for i := range subtitles {
+		if policy == MergeDefaultYes || (!subtitles[i].Forced && !subtitles[i].HearingImpaired) {
+			subtitles[i].Default = true
+			return nil
+		}
+	}
+	return nil
+}
+
+// BuildMergeFFmpegArgs adds subtitles to targetFile as new subtitle streams
+// after its targetSubtitleCount existing ones. When any new subtitle is
+// default, the default flag is cleared on the existing subtitle streams.
+func BuildMergeFFmpegArgs(targetFile string, targetSubtitleCount int, subtitles []MergeSubtitle) []string {
+	maps := []string{"-map", "0"}
+	for inputIndex := range subtitles {
+		maps = append(maps, "-map", strconv.Itoa(inputIndex+1))
+	}
+	return buildMergeFFmpegArgs(targetFile, maps, targetSubtitleCount, targetSubtitleCount, subtitles)
+}
+
+// BuildMergeAtPositionFFmpegArgs is BuildMergeFFmpegArgs with the new
+// subtitles inserted before the existing subtitle stream at position.
+func BuildMergeAtPositionFFmpegArgs(targetFile string, targetStreams []StreamInfo, subtitles []MergeSubtitle, position int) ([]string, error) {
+	targetSubtitleCount := 0
+	for _, stream := range targetStreams {
+		if stream.Type == "Subtitle" {
+			targetSubtitleCount++
+		}
+	}
+	if position < 0 || position > targetSubtitleCount {
+		return nil, fmt.Errorf("position %d is out of range, target has %d subtitle streams", position, targetSubtitleCount)
+	}
+
+	var newMaps []string
+	for inputIndex := range subtitles {
+		newMaps = append(newMaps, "-map", strconv.Itoa(inputIndex+1))
+	}
+
+	var maps []string
+	subtitleIndex := 0
+	for _, stream := range targetStreams {
+		if stream.Type == "Subtitle" {
+			if subtitleIndex == position {
+				maps = append(maps, newMaps...)
+			}
+			subtitleIndex++
+		}
+		maps = append(maps, "-map", "0:"+StreamIDTail(stream.ID))
+	}
+	if position == targetSubtitleCount {
+		maps = append(maps, newMaps...)
+	}
+
+	return buildMergeFFmpegArgs(targetFile, maps, targetSubtitleCount, position, subtitles), nil
+}
+
+func buildMergeFFmpegArgs(targetFile string, maps []string, targetSubtitleCount, position int, subtitles []MergeSubtitle) []string {
+	ffmpegArgs := []string{
+		"-hide_banner",
+		"-y",
+		"-i",
+		targetFile,
+	}
+	for _, subtitle := range subtitles {
+		if subtitle.Delay != 0 {
+			ffmpegArgs = append(ffmpegArgs, "-itsoffset", strconv.FormatFloat(subtitle.Delay.Seconds(), 'f', -1, 64))
+		}
+		if subtitle.CharEnc != "" {
+			ffmpegArgs = append(ffmpegArgs, "-sub_charenc", subtitle.CharEnc)
+		}
+		ffmpegArgs = append(ffmpegArgs, "-i", subtitle.Path)
+	}
+	ffmpegArgs = append(ffmpegArgs, "-c", "copy")
+	ffmpegArgs = append(ffmpegArgs, maps...)
+	// -sub_charenc only applies when the subtitle is decoded, and mp4 only
+	// stores mov_text subtitles.
+	mp4 := IsMP4File(targetFile)
+	for offset, subtitle := range subtitles {
+		if subtitle.CharEnc == "" && !mp4 {
+			continue
+		}
+		codec := "srt"
+		if ext := strings.ToLower(filepath.Ext(subtitle.Path)); ext == ".ass" || ext == ".ssa" {
+			codec = "ass"
+		}
+		if mp4 {
+			codec = mp4SubtitleCodec
+		}
+		ffmpegArgs = append(ffmpegArgs, "-c:s:"+strconv.Itoa(position+offset), codec)
+	}
+
+	hasNewDefault := false
+	for _, subtitle := range subtitles {
+		if subtitle.Default {
+			hasNewDefault = true
+		}
+	}
+	if hasNewDefault {
+		for subtitleIndex := 0; subtitleIndex < targetSubtitleCount; subtitleIndex++ {
+			outputIndex := subtitleIndex
+			if subtitleIndex >= position {
+				outputIndex += len(subtitles)
+			}
+			ffmpegArgs = append(ffmpegArgs, "-disposition:s:"+strconv.Itoa(outputIndex), "-default")
+		}
+	}
+
+	for offset, subtitle := range subtitles {
+		newSubtitleIndex := strconv.Itoa(position + offset)
+
+		var dispositions []string
+		if subtitle.Default {
+			dispositions = append(dispositions, "default")
+		}
+		if subtitle.Forced {
+			dispositions = append(dispositions, "forced")
+		}
+		if subtitle.HearingImpaired {
+			dispositions = append(dispositions, "hearing_impaired")
+		}
+		if len(dispositions) > 0 {
+			ffmpegArgs = append(ffmpegArgs, "-disposition:s:"+newSubtitleIndex, strings.Join(dispositions, "+"))
+		}
+
+		if subtitle.Language != "" {
+			language := subtitle.Language
+			if mp4 {
+				language = mp4LanguageTag(language)
+			}
+			ffmpegArgs = append(ffmpegArgs, "-metadata:s:s:"+newSubtitleIndex, "language="+language)
+		}
+		if subtitle.Title != "" {
+			ffmpegArgs = append(ffmpegArgs, "-metadata:s:s:"+newSubtitleIndex, "title="+subtitle.Title)
+		}
+	}
+	return ffmpegArgs
+}
@@ -0,0 +1,226 @@
+package mkv
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMergeSubtitleSpec(t *testing.T) {
+	subtitle, err := ParseMergeSubtitleSpec("movie.chs.srt:lang=chi:title=简体:default")
+	if err != nil {
+		t.Fatalf("ParseMergeSubtitleSpec() error = %v", err)
+	}
+	want := MergeSubtitle{Path: "movie.chs.srt", Language: "chi", Title: "简体", Default: true}
+	if subtitle != want {
+		t.Fatalf("subtitle = %+v, want %+v", subtitle, want)
+	}
+
+	subtitle, err = ParseMergeSubtitleSpec("dir:with:colons/movie.srt:forced:language=eng")
+	if err != nil {
+		t.Fatalf("ParseMergeSubtitleSpec() error = %v", err)
+	}
+	want = MergeSubtitle{Path: "dir:with:colons/movie.srt", Language: "eng", Forced: true}
+	if subtitle != want {
+		t.Fatalf("subtitle = %+v, want %+v", subtitle, want)
+	}
+
+	subtitle, err = ParseMergeSubtitleSpec("movie.srt:sdh")
+	if err != nil {
+		t.Fatalf("ParseMergeSubtitleSpec() error = %v", err)
+	}
+	if !subtitle.HearingImpaired {
+		t.Fatalf("subtitle = %+v, want hearing impaired", subtitle)
+	}
+
+	subtitle, err = ParseMergeSubtitleSpec("movie.srt:delay=-1.5s:charenc=GBK")
+	if err != nil {
+		t.Fatalf("ParseMergeSubtitleSpec() error = %v", err)
+	}
+	want = MergeSubtitle{Path: "movie.srt", Delay: -1500 * time.Millisecond, CharEnc: "GBK"}
+	if subtitle != want {
+		t.Fatalf("subtitle = %+v, want %+v", subtitle, want)
+	}
+
+	if _, err := ParseMergeSubtitleSpec("movie.srt:speed=2"); err == nil {
+		t.Fatal("expected unknown option error")
+	}
+	if _, err := ParseMergeSubtitleSpec("movie.srt:delay=soon"); err == nil {
+		t.Fatal("expected invalid delay error")
+	}
+}
+
+func TestParseSubtitleDelay(t *testing.T) {
+	for value, want := range map[string]time.Duration{
+		"1.5s":   1500 * time.Millisecond,
+		"-500ms": -500 * time.Millisecond,
+		"2":      2 * time.Second,
+		"0.25":   250 * time.Millisecond,
+	} {
+		delay, err := ParseSubtitleDelay(value)
+		if err != nil || delay != want {
+			t.Fatalf("ParseSubtitleDelay(%q) = %v, %v, want %v", value, delay, err, want)
+		}
+	}
+}
+
+func TestParseMergeSubtitleSpec_ExistingFileWithColon(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "movie:default")
+	if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+		t.Fatalf("write subtitle failed: %v", err)
+	}
+
+	subtitle, err := ParseMergeSubtitleSpec(path)
+	if err != nil {
+		t.Fatalf("ParseMergeSubtitleSpec() error = %v", err)
+	}
+	if subtitle.Path != path || subtitle.Default {
+		t.Fatalf("subtitle = %+v, want plain path", subtitle)
+	}
+}
+
+func TestBuildMergeFFmpegArgs_MultipleSubtitles(t *testing.T) {
+	args := BuildMergeFFmpegArgs("target.mkv", 1, []MergeSubtitle{
+		{Path: "chi.srt", Language: "chi", Title: "简体", Default: true},
+		{Path: "eng.srt", Language: "eng"},
+		{Path: "signs.ass", Language: "eng", Forced: true},
+	})
+
+	for _, pair := range [][2]string{
+		{"-i", "chi.srt"},
+		{"-i", "eng.srt"},
+		{"-i", "signs.ass"},
+		{"-map", "1"},
+		{"-map", "2"},
+		{"-map", "3"},
+		{"-disposition:s:0", "-default"},
+		{"-disposition:s:1", "default"},
+		{"-disposition:s:3", "forced"},
+		{"-metadata:s:s:1", "language=chi"},
+		{"-metadata:s:s:1", "title=简体"},
+		{"-metadata:s:s:2", "language=eng"},
+		{"-metadata:s:s:3", "language=eng"},
+	} {
+		if !containsArgPair(args, pair[0], pair[1]) {
+			t.Fatalf("expected %s %s in args: %#v", pair[0], pair[1], args)
+		}
+	}
+	for _, arg := range args {
+		if arg == "-disposition:s:2" {
+			t.Fatalf("plain subtitle should not get a disposition: %#v", args)
+		}
+	}
+
+	args = BuildMergeFFmpegArgs("target.mkv", 2, []MergeSubtitle{{Path: "eng.srt"}})
+	if containsArgPair(args, "-disposition:s:0", "-default") {
+		t.Fatalf("existing defaults should be kept without a new default: %#v", args)
+	}
+}
+
+func TestBuildMergeFFmpegArgs_DelayAndCharEnc(t *testing.T) {
+	args := BuildMergeFFmpegArgs("target.mkv", 2, []MergeSubtitle{
+		{Path: "chi.srt", Delay: 1500 * time.Millisecond, CharEnc: "GBK"},
+		{Path: "eng.srt", Delay: -2 * time.Second},
+		{Path: "signs.ass", CharEnc: "BIG5"},
+	})
+
+	want := []string{
+		"-hide_banner", "-y", "-i", "target.mkv",
+		"-itsoffset", "1.5", "-sub_charenc", "GBK", "-i", "chi.srt",
+		"-itsoffset", "-2", "-i", "eng.srt",
+		"-sub_charenc", "BIG5", "-i", "signs.ass",
+	}
+	for i, arg := range want {
+		if args[i] != arg {
+			t.Fatalf("args[%d] = %q, want %q: %#v", i, args[i], arg, args)
+		}
+	}
+	if !containsArgPair(args, "-c:s:2", "srt") || !containsArgPair(args, "-c:s:4", "ass") {
+		t.Fatalf("re-encoded subtitles need an explicit codec: %#v", args)
+	}
+	if containsArgPair(args, "-c:s:3", "srt") {
+		t.Fatalf("UTF-8 subtitle should be stream copied: %#v", args)
+	}
+}
+
+func TestApplyMergeDefaultPolicy(t *testing.T) {
+	defaults := func(subtitles []MergeSubtitle) []bool {
+		var values []bool
+		for _, subtitle := range subtitles {
+			values = append(values, subtitle.Default)
+		}
+		return values
+	}
+
+	tests := []struct {
+		name      string
+		policy    string
+		subtitles []MergeSubtitle
+		want      []bool
+	}{
+		{"auto picks first", MergeDefaultAuto, []MergeSubtitle{{Path: "a.srt"}, {Path: "b.srt"}}, []bool{true, false}},
+		{"auto skips forced and sdh", MergeDefaultAuto, []MergeSubtitle{{Path: "a.srt", Forced: true}, {Path: "b.srt", HearingImpaired: true}, {Path: "c.srt"}}, []bool{false, false, true}},
+		{"auto without candidate", MergeDefaultAuto, []MergeSubtitle{{Path: "a.srt", Forced: true}}, []bool{false}},
+		{"yes picks first", MergeDefaultYes, []MergeSubtitle{{Path: "a.srt", Forced: true}, {Path: "b.srt"}}, []bool{true, false}},
+		{"no keeps existing default", MergeDefaultNo, []MergeSubtitle{{Path: "a.srt"}}, []bool{false}},
+		{"explicit default wins", MergeDefaultYes, []MergeSubtitle{{Path: "a.srt"}, {Path: "b.srt", Default: true}}, []bool{false, true}},
+	}
+	for _, tt := range tests {
+		if err := ApplyMergeDefaultPolicy(tt.subtitles, tt.policy); err != nil {
+			t.Fatalf("%s: ApplyMergeDefaultPolicy() error = %v", tt.name, err)
+		}
+		got := defaults(tt.subtitles)
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Fatalf("%s: defaults = %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	}
+
+	if err := ApplyMergeDefaultPolicy(nil, "sometimes"); err == nil {
+		t.Fatal("expected invalid policy error")
+	}
+}
+
+func TestBuildMergeAtPositionFFmpegArgs(t *testing.T) {
+	streams := []StreamInfo{
+		{ID: "0:0", Type: "Video"},
+		{ID: "0:1", Type: "Audio"},
+		{ID: "0:2", Type: "Subtitle"},
+		{ID: "0:3", Type: "Subtitle"},
+		{ID: "0:4", Type: "Audio"},
+	}
+	args, err := BuildMergeAtPositionFFmpegArgs("target.mkv", streams, []MergeSubtitle{
+		{Path: "chi.srt", Language: "chi", Default: true, HearingImpaired: true},
+	}, 1)
+	if err != nil {
+		t.Fatalf("BuildMergeAtPositionFFmpegArgs() error = %v", err)
+	}
+
+	var maps []string
+	for i, arg := range args {
+		if arg == "-map" {
+			maps = append(maps, args[i+1])
+		}
+	}
+	wantMaps := []string{"0:0", "0:1", "0:2", "1", "0:3", "0:4"}
+	if strings.Join(maps, " ") != strings.Join(wantMaps, " ") {
+		t.Fatalf("maps = %v, want %v", maps, wantMaps)
+	}
+	for _, pair := range [][2]string{
+		{"-disposition:s:0", "-default"},
+		{"-disposition:s:2", "-default"},
+		{"-disposition:s:1", "default+hearing_impaired"},
+		{"-metadata:s:s:1", "language=chi"},
+	} {
+		if !containsArgPair(args, pair[0], pair[1]) {
+			t.Fatalf("expected %s %s in args: %#v", pair[0], pair[1], args)
+		}
+	}
+
+	if _, err := BuildMergeAtPositionFFmpegArgs("target.mkv", streams, []MergeSubtitle{{Path: "chi.srt"}}, 3); err == nil {
+		t.Fatal("expected out of range position error")
+	}
+}
@@ -1,6 +1,7 @@
 package mkv
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -17,21 +18,29 @@ var (
 	languageTagRE      = regexp.MustCompile(`^[a-z]{3}$`)
 	streamDefaultRE    = regexp.MustCompile(`(?i)\bdefault\b`)
 	streamForcedRE     = regexp.MustCompile(`(?i)\bforced\b`)
+	streamHearingRE    = regexp.MustCompile(`(?i)\bhearing_impaired\b`)
+	streamTrackIDRE    = regexp.MustCompile(`\[0x[0-9a-fA-F]+\]`)
 )
 
 type StreamInfo struct {
-	ID             string
-	Type           string
-	Language       string
-	SubtitleFormat string
-	Title          string
-	IsDefault      bool
-	IsForced       bool
+	ID                string
+	Type              string
+	Codec             string
+	Language          string
+	SubtitleFormat    string
+	Title             string
+	IsDefault         bool
+	IsForced          bool
+	IsHearingImpaired bool
 }
 
 type FFmpegRunner interface {
 	IsInstalled() error
 	Run(args ...string) ([]byte, error)
+	// RunContext runs ffmpeg with -progress output, passes every progress
+	// block to progress and stops ffmpeg when ctx is done. It returns the
+	// log ffmpeg writes to stderr.
+	RunContext(ctx context.Context, progress func(FFmpegProgress), args ...string) ([]byte, error)
 }
 
 type commandFFmpegRunner struct{}
@@ -83,11 +92,13 @@ func ParseMKVStreams(output string) ([]StreamInfo, error) {
 			streamDesc := strings.TrimSpace(match[3])
 
 			stream := StreamInfo{
-				ID:        streamID,
-				Type:      streamType,
-				Language:  language,
-				IsDefault: isSubtitleDefault(streamDesc),
-				IsForced:  isSubtitleForced(streamDesc),
+				ID:                streamID,
+				Type:              streamType,
+				Codec:             ParseStreamCodec(streamDesc),
+				Language:          language,
+				IsDefault:         isSubtitleDefault(streamDesc),
+				IsForced:          isSubtitleForced(streamDesc),
+				IsHearingImpaired: isSubtitleHearingImpaired(streamDesc),
 			}
 			if streamType == "Subtitle" {
 				stream.SubtitleFormat = ParseSubtitleFormat(streamDesc)
@@ -127,6 +138,8 @@ func ListStreams(fileName string) ([]StreamInfo, error) {
 }
 
 func ParseStreamIDAndLanguage(rawID string) (streamID, language string) {
+	// MP4 stream ids carry the container track id, as in 0:2[0x3](eng).
+	rawID = streamTrackIDRE.ReplaceAllString(rawID, "")
 	open := strings.Index(rawID, "(")
 	if open < 0 {
 		return strings.TrimSpace(rawID), ""
@@ -150,6 +163,18 @@ func isSubtitleForced(description string) bool {
 	return streamForcedRE.MatchString(strings.ToLower(description))
 }
 
+func isSubtitleHearingImpaired(description string) bool {
+	return streamHearingRE.MatchString(description)
+}
+
+func ParseStreamCodec(description string) string {
+	codec := strings.TrimSpace(description)
+	if end := strings.IndexAny(codec, " ,("); end >= 0 {
+		codec = codec[:end]
+	}
+	return strings.ToLower(codec)
+}
+
 func ParseSubtitleFormat(description string) string {
 	description = strings.TrimSpace(description)
 	if comma := strings.Index(description, ","); comma >= 0 {
@@ -167,7 +192,8 @@ func ParseSubtitleFormat(description string) string {
 			}
 
 			formatValue := strings.TrimSpace(rest[:close])
-			if isSubtitleMetadataKeyword(formatValue) {
+			// A codec tag such as mov_text (tx3g / 0x67337874) is not a format.
+			if isSubtitleMetadataKeyword(formatValue) || strings.Contains(formatValue, " / 0x") {
 				return formatPrefix
 			}
 			return formatValue
@@ -186,6 +212,35 @@ func isSubtitleMetadataKeyword(keyword string) bool {
 	}
 }
 
+// subtitleFileExtensions maps subtitle codec names and the formats ffmpeg
+// prints for them to the extension of the file they are extracted to.
+var subtitleFileExtensions = map[string]string{
+	"subrip":            "srt",
+	"srt":               "srt",
+	"ass":               "ass",
+	"ssa":               "ass",
+	"webvtt":            "vtt",
+	mp4SubtitleCodec:    "srt",
+	"hdmv_pgs_subtitle": "sup",
+	"pgssub":            "sup",
+	"dvd_subtitle":      "idx",
+	"dvdsub":            "idx",
+	"dvb_subtitle":      "mks",
+	"dvbsub":            "mks",
+}
+
+// imageSubtitleCodecs lists bitmap subtitle codecs and their format names,
+// which cannot be converted to text.
+var imageSubtitleCodecs = map[string]bool{
+	"hdmv_pgs_subtitle": true,
+	"pgssub":            true,
+	"dvd_subtitle":      true,
+	"dvdsub":            true,
+	"dvb_subtitle":      true,
+	"dvbsub":            true,
+	"xsub":              true,
+}
+
 func SubtitleFileExtension(subtitleFormat string) string {
 	normalizedFormat := strings.ToLower(strings.TrimSpace(subtitleFormat))
 	if normalizedFormat == "" {
@@ -195,10 +250,33 @@ func SubtitleFileExtension(subtitleFormat string) string {
 	if strings.HasPrefix(normalizedFormat, "ass") {
 		return "ass"
 	}
+	if ext, ok := subtitleFileExtensions[normalizedFormat]; ok {
+		return ext
+	}
 
 	return strings.TrimPrefix(normalizedFormat, ".")
 }
 
+// SubtitleStreamExtension picks the extension by codec name, falling back
+// to the subtitle format for codecs without a known mapping.
+func SubtitleStreamExtension(stream StreamInfo) string {
+	if ext, ok := subtitleFileExtensions[stream.Codec]; ok {
+		return ext
+	}
+	return SubtitleFileExtension(stream.SubtitleFormat)
+}
+
+// IsImageSubtitle reports whether stream is a bitmap subtitle such as PGS
+// or VobSub.
+func IsImageSubtitle(stream StreamInfo) bool {
+	return stream.Type == "Subtitle" && (imageSubtitleCodecs[stream.Codec] || imageSubtitleCodecs[strings.ToLower(stream.SubtitleFormat)])
+}
+
+// IsVobSubStream reports whether stream must be extracted with ExtractVobSub.
+func IsVobSubStream(stream StreamInfo) bool {
+	return SubtitleStreamExtension(stream) == "idx"
+}
+
 func StreamIDMatch(rawID, target string) bool {
 	rawID = strings.TrimSpace(rawID)
 	target = strings.TrimSpace(target)
@@ -273,7 +351,7 @@ func SubtitleOutputPath(fileName, outputBaseDir string, stream StreamInfo) (stri
 	}
 
 	filename := strings.Join(parts, "_")
-	ext := SubtitleFileExtension(stream.SubtitleFormat)
+	ext := SubtitleStreamExtension(stream)
 
 	return filepath.Join(SubtitleOutputDir(fileName, outputBaseDir), fmt.Sprintf("%s.%s", filename, ext)), nil
 }
@@ -296,6 +374,9 @@ func SanitizeFileNamePart(value string) string {
 }
 
 func MergeOutputPath(targetFile string) string {
+	if IsMP4File(targetFile) {
+		return targetFile + ".tmp_subs" + strings.ToLower(filepath.Ext(targetFile))
+	}
 	return targetFile + ".tmp_subs.mkv"
 }
 
@@ -312,63 +393,34 @@ func ValidLanguageTag(language string) bool {
 	return languageTagRE.MatchString(language)
 }
 
-func BuildMergeFFmpegArgs(targetFile, subtitleFile string, targetSubtitleCount int, languageTag, subtitleTitle string) []string {
-	ffmpegArgs := []string{
-		"-hide_banner",
-		"-y",
-		"-i",
-		targetFile,
-		"-i",
-		subtitleFile,
-		"-c",
-		"copy",
-		"-map",
-		"0",
-		"-map",
-		"1",
-	}
-	for subtitleIndex := 0; subtitleIndex < targetSubtitleCount; subtitleIndex++ {
-		ffmpegArgs = append(ffmpegArgs, "-disposition:s:"+strconv.Itoa(subtitleIndex), "-default")
-	}
-	newSubtitleIndex := strconv.Itoa(targetSubtitleCount)
-	ffmpegArgs = append(ffmpegArgs, "-disposition:s:"+newSubtitleIndex, "default")
-	if languageTag != "" || subtitleTitle != "" {
-		if languageTag != "" {
-			ffmpegArgs = append(ffmpegArgs, "-metadata:s:s:"+newSubtitleIndex, "language="+languageTag)
-		}
-		if subtitleTitle != "" {
-			ffmpegArgs = append(ffmpegArgs, "-metadata:s:s:"+newSubtitleIndex, "title="+subtitleTitle)
-		}
+func BuildExtractFFmpegArgs(sourceFile string, stream StreamInfo, outputPath string) []string {
+	codec := "copy"
+	if stream.Codec == mp4SubtitleCodec || strings.EqualFold(stream.SubtitleFormat, mp4SubtitleCodec) {
+		// mov_text cannot be stored outside mp4; export it as SubRip.
+		codec = "srt"
 	}
-	return ffmpegArgs
+	return buildExtractFFmpegArgs(sourceFile, stream, outputPath, codec)
 }
 
-func BuildExtractFFmpegArgs(sourceFile string, stream StreamInfo, outputPath string) []string {
-	return []string{
+func buildExtractFFmpegArgs(sourceFile string, stream StreamInfo, outputPath, codec string) []string {
+	ffmpegArgs := []string{
 		"-hide_banner",
 		"-i",
 		sourceFile,
 		"-map",
 		stream.ID,
 		"-c",
-		"copy",
-		outputPath,
+		codec,
 	}
+	if strings.EqualFold(filepath.Ext(outputPath), ".mks") {
+		// DVB subtitles have no raw file format; keep them in Matroska.
+		ffmpegArgs = append(ffmpegArgs, "-f", "matroska")
+	}
+	return append(ffmpegArgs, outputPath)
 }
 
 func BuildRemoveFFmpegArgs(sourceFile string, stream StreamInfo) []string {
-	return []string{
-		"-hide_banner",
-		"-y",
-		"-i",
-		sourceFile,
-		"-map",
-		"0",
-		"-map",
-		"-" + stream.ID,
-		"-c",
-		"copy",
-	}
+	return BuildRemoveStreamsFFmpegArgs(sourceFile, []StreamInfo{stream})
 }
 
 func StreamDefaultSubtitleIndex(allStreams []StreamInfo, targetID string) (int, error) {
@@ -386,6 +438,14 @@ func StreamDefaultSubtitleIndex(allStreams []StreamInfo, targetID string) (int,
 }
 
 func BuildDefaultToggleFFmpegArgs(sourceFile string, allStreams []StreamInfo, target StreamInfo) []string {
+	return buildToggleFFmpegArgs(sourceFile, allStreams, target, target.IsDefault, "default")
+}
+
+func BuildForceToggleFFmpegArgs(sourceFile string, allStreams []StreamInfo, target StreamInfo) []string {
+	return buildToggleFFmpegArgs(sourceFile, allStreams, target, target.IsForced, "forced")
+}
+
+func buildToggleFFmpegArgs(sourceFile string, allStreams []StreamInfo, target StreamInfo, targetSet bool, flag string) []string {
 	targetIndex, err := StreamDefaultSubtitleIndex(allStreams, target.ID)
 	if err != nil {
 		return nil
@@ -402,8 +462,10 @@ func BuildDefaultToggleFFmpegArgs(sourceFile string, allStreams []StreamInfo, ta
 		"copy",
 	}
 
-	if target.IsDefault {
-		ffmpegArgs = append(ffmpegArgs, "-disposition:s:"+strconv.Itoa(targetIndex), "0")
+	// Relative dispositions change flag only and keep the other flags of
+	// each stream.
+	if targetSet {
+		ffmpegArgs = append(ffmpegArgs, "-disposition:s:"+strconv.Itoa(targetIndex), "-"+flag)
 		return ffmpegArgs
 	}
 
@@ -412,9 +474,9 @@ func BuildDefaultToggleFFmpegArgs(sourceFile string, allStreams []StreamInfo, ta
 		if stream.Type != "Subtitle" {
 			continue
 		}
-		value := "0"
+		value := "-" + flag
 		if stream.ID == target.ID {
-			value = "default"
+			value = "+" + flag
 		}
 		ffmpegArgs = append(ffmpegArgs, "-disposition:s:"+strconv.Itoa(subtitleIndex), value)
 		subtitleIndex++
@@ -422,12 +484,30 @@ func BuildDefaultToggleFFmpegArgs(sourceFile string, allStreams []StreamInfo, ta
 	return ffmpegArgs
 }
 
-func BuildForceToggleFFmpegArgs(sourceFile string, allStreams []StreamInfo, target StreamInfo) []string {
-	targetIndex, err := StreamDefaultSubtitleIndex(allStreams, target.ID)
-	if err != nil {
-		return nil
+// FindStreamForTrackEdit returns the subtitle or audio stream matching
+// targetID.
+func FindStreamForTrackEdit(allStreams []StreamInfo, targetID string) (StreamInfo, error) {
+	idRE := regexp.MustCompile(`^[0-9]+$`)
+	if !idRE.MatchString(targetID) {
+		return StreamInfo{}, fmt.Errorf("invalid stream id: %s", targetID)
 	}
 
+	for _, stream := range allStreams {
+		if StreamIDMatch(stream.ID, targetID) {
+			if stream.Type != "Subtitle" && stream.Type != "Audio" {
+				return StreamInfo{}, fmt.Errorf("stream id %s is not a subtitle or audio stream", targetID)
+			}
+			return stream, nil
+		}
+	}
+
+	return StreamInfo{}, fmt.Errorf("stream id %s not found", targetID)
+}
+
+// BuildTrackMetadataFFmpegArgs remuxes sourceFile with new language and/or
+// title tags on stream. Nil values are left untouched; an empty title removes
+// the tag.
+func BuildTrackMetadataFFmpegArgs(sourceFile string, stream StreamInfo, languageTag, title *string) []string {
 	ffmpegArgs := []string{
 		"-hide_banner",
 		"-y",
@@ -439,22 +519,12 @@ func BuildForceToggleFFmpegArgs(sourceFile string, allStreams []StreamInfo, targ
 		"copy",
 	}
 
-	if target.IsForced {
-		ffmpegArgs = append(ffmpegArgs, "-disposition:s:"+strconv.Itoa(targetIndex), "0")
-		return ffmpegArgs
+	specifier := "-metadata:s:" + StreamIDTail(stream.ID)
+	if languageTag != nil {
+		ffmpegArgs = append(ffmpegArgs, specifier, "language="+*languageTag)
 	}
-
-	subtitleIndex := 0
-	for _, stream := range allStreams {
-		if stream.Type != "Subtitle" {
-			continue
-		}
-		value := "0"
-		if stream.ID == target.ID {
-			value = "forced"
-		}
-		ffmpegArgs = append(ffmpegArgs, "-disposition:s:"+strconv.Itoa(subtitleIndex), value)
-		subtitleIndex++
+	if title != nil {
+		ffmpegArgs = append(ffmpegArgs, specifier, "title="+*title)
 	}
 	return ffmpegArgs
 }
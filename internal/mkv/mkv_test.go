@@ -1,6 +1,7 @@
 package mkv
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"testing"
@@ -128,7 +129,7 @@ func TestBuildDefaultToggleFFmpegArgs(t *testing.T) {
 
 	offTarget := StreamInfo{ID: "0:2", Type: "Subtitle", IsDefault: true}
 	offArgs := BuildDefaultToggleFFmpegArgs("target.mkv", streams, offTarget)
-	if len(offArgs) == 0 || !containsArgPair(offArgs, "-disposition:s:1", "0") {
+	if len(offArgs) == 0 || !containsArgPair(offArgs, "-disposition:s:1", "-default") {
 		t.Fatalf("unexpected off args: %#v", offArgs)
 	}
 
@@ -137,10 +138,10 @@ func TestBuildDefaultToggleFFmpegArgs(t *testing.T) {
 	if len(onArgs) == 0 {
 		t.Fatalf("BuildDefaultToggleFFmpegArgs() for enable returned empty")
 	}
-	if !containsArgPair(onArgs, "-disposition:s:0", "default") {
+	if !containsArgPair(onArgs, "-disposition:s:0", "+default") {
 		t.Fatalf("expected enable target to be set as default: %#v", onArgs)
 	}
-	if !containsArgPair(onArgs, "-disposition:s:1", "0") {
+	if !containsArgPair(onArgs, "-disposition:s:1", "-default") {
 		t.Fatalf("expected other subtitle to be unset: %#v", onArgs)
 	}
 }
@@ -156,7 +157,7 @@ func TestBuildForceToggleFFmpegArgs(t *testing.T) {
 
 	offTarget := StreamInfo{ID: "0:2", Type: "Subtitle", IsForced: true}
 	offArgs := BuildForceToggleFFmpegArgs("target.mkv", streams, offTarget)
-	if len(offArgs) == 0 || !containsArgPair(offArgs, "-disposition:s:1", "0") {
+	if len(offArgs) == 0 || !containsArgPair(offArgs, "-disposition:s:1", "-forced") {
 		t.Fatalf("unexpected off args: %#v", offArgs)
 	}
 
@@ -165,10 +166,10 @@ func TestBuildForceToggleFFmpegArgs(t *testing.T) {
 	if len(onArgs) == 0 {
 		t.Fatalf("BuildForceToggleFFmpegArgs() for enable returned empty")
 	}
-	if !containsArgPair(onArgs, "-disposition:s:0", "forced") {
+	if !containsArgPair(onArgs, "-disposition:s:0", "+forced") {
 		t.Fatalf("expected enable target to be set as forced: %#v", onArgs)
 	}
-	if !containsArgPair(onArgs, "-disposition:s:1", "0") {
+	if !containsArgPair(onArgs, "-disposition:s:1", "-forced") {
 		t.Fatalf("expected existing forced stream to be unset: %#v", onArgs)
 	}
 }
@@ -204,6 +205,53 @@ func TestFindStreamForSubtitleRemoval(t *testing.T) {
 	}
 }
 
+func TestFindStreamForTrackEdit(t *testing.T) {
+	streams := []StreamInfo{
+		{ID: "0:0", Type: "Video"},
+		{ID: "0:1", Type: "Audio"},
+		{ID: "0:2", Type: "Subtitle"},
+	}
+
+	for _, id := range []string{"1", "2"} {
+		stream, err := FindStreamForTrackEdit(streams, id)
+		if err != nil {
+			t.Fatalf("FindStreamForTrackEdit(%q) error = %v", id, err)
+		}
+		if stream.ID != "0:"+id {
+			t.Fatalf("unexpected stream %+v", stream)
+		}
+	}
+
+	if _, err := FindStreamForTrackEdit(streams, "0"); err == nil {
+		t.Fatal("expected video stream error")
+	}
+	if _, err := FindStreamForTrackEdit(streams, "9"); err == nil {
+		t.Fatal("expected missing stream error")
+	}
+}
+
+func TestBuildTrackMetadataFFmpegArgs(t *testing.T) {
+	language := "chi"
+	title := ""
+	args := BuildTrackMetadataFFmpegArgs("target.mkv", StreamInfo{ID: "0:3", Type: "Subtitle"}, &language, &title)
+	if !containsArgPair(args, "-metadata:s:3", "language=chi") {
+		t.Fatalf("expected language metadata: %#v", args)
+	}
+	if !containsArgPair(args, "-metadata:s:3", "title=") {
+		t.Fatalf("expected title to be cleared: %#v", args)
+	}
+	if !containsArgPair(args, "-map", "0") || !containsArgPair(args, "-c", "copy") {
+		t.Fatalf("expected stream copy of all streams: %#v", args)
+	}
+
+	args = BuildTrackMetadataFFmpegArgs("target.mkv", StreamInfo{ID: "0:1", Type: "Audio"}, nil, &language)
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "language=") {
+			t.Fatalf("language should be untouched: %#v", args)
+		}
+	}
+}
+
 func TestSubtitleOutputPath(t *testing.T) {
 	path, err := SubtitleOutputPath("movie.mkv", "/tmp", StreamInfo{
 		ID:             "0:4",
@@ -241,7 +289,7 @@ func TestSanitizeFileNamePart(t *testing.T) {
 }
 
 func TestBuildMergeFFmpegArgs(t *testing.T) {
-	args := BuildMergeFFmpegArgs("target.mkv", "sub.srt", 2, "eng", "title")
+	args := BuildMergeFFmpegArgs("target.mkv", 2, []MergeSubtitle{{Path: "sub.srt", Language: "eng", Title: "title", Default: true}})
 	if len(args) == 0 {
 		t.Fatal("BuildMergeFFmpegArgs() expected non-empty args")
 	}
@@ -281,6 +329,10 @@ func (f *fakeFFmpegRunner) Run(args ...string) ([]byte, error) {
 	return []byte(f.output), f.runErr
 }
 
+func (f *fakeFFmpegRunner) RunContext(_ context.Context, _ func(FFmpegProgress), args ...string) ([]byte, error) {
+	return f.Run(args...)
+}
+
 func TestFFmpegRunnerIntegration(t *testing.T) {
 	old := ffmpegRunner
 	t.Cleanup(func() {
@@ -0,0 +1,45 @@
+package mkv
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// mp4SubtitleCodec is the only text subtitle codec MP4 players support.
+const mp4SubtitleCodec = "mov_text"
+
+func IsMKVFile(fileName string) bool {
+	return strings.EqualFold(filepath.Ext(fileName), ".mkv")
+}
+
+func IsMP4File(fileName string) bool {
+	ext := filepath.Ext(fileName)
+	return strings.EqualFold(ext, ".mp4") || strings.EqualFold(ext, ".m4v")
+}
+
+// IsVideoContainerFile reports whether fileName is an mkv or mp4 file.
+func IsVideoContainerFile(fileName string) bool {
+	return IsMKVFile(fileName) || IsMP4File(fileName)
+}
+
+// mp4LanguageTag returns the ISO 639-2/T code MP4 expects, so chi is stored
+// as zho.
+func mp4LanguageTag(language string) string {
+	return normalizeLanguageTag(language)
+}
+
+// ValidateMP4DefaultToggle rejects clearing the last default subtitle of an
+// mp4 file: the muxer enables the first subtitle track when none is default,
+// so the toggle would silently move the default elsewhere.
+func ValidateMP4DefaultToggle(allStreams []StreamInfo, target StreamInfo) error {
+	if !target.IsDefault {
+		return nil
+	}
+	for _, stream := range allStreams {
+		if stream.Type == "Subtitle" && stream.IsDefault && stream.ID != target.ID {
+			return nil
+		}
+	}
+	return fmt.Errorf("mp4 files always keep one subtitle enabled; set another subtitle stream as default instead of clearing %s", target.ID)
+}
@@ -0,0 +1,98 @@
+package mkv
+
+import "testing"
+
+const mp4FFmpegOutput = `Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'movie.mp4':
+  Duration: 00:00:05.00, start: 0.000000, bitrate: 500 kb/s
+  Stream #0:0[0x1](und): Video: h264 (High) (avc1 / 0x31637661), yuv420p, 320x240, 25 fps (default)
+  Stream #0:1[0x2](eng): Audio: aac (LC) (mp4a / 0x6134706D), 48000 Hz, stereo (default)
+  Stream #0:2[0x3](eng): Subtitle: mov_text (tx3g / 0x67337874), 0 kb/s (default)
+    Metadata:
+      handler_name    : SubtitleHandler
+  Stream #0:3[0x4](zho): Subtitle: mov_text (tx3g / 0x67337874), 0 kb/s
+    Metadata:
+      title           : 简体中文
+At least one output file must be specified
+`
+
+func TestParseMKVStreams_MP4(t *testing.T) {
+	streams, err := ParseMKVStreams(mp4FFmpegOutput)
+	if err != nil {
+		t.Fatalf("ParseMKVStreams() error = %v", err)
+	}
+	if len(streams) != 4 {
+		t.Fatalf("stream count = %d, want 4", len(streams))
+	}
+
+	subtitle := streams[2]
+	if subtitle.ID != "0:2" || subtitle.Language != "eng" || subtitle.Codec != "mov_text" || subtitle.SubtitleFormat != "mov_text" || !subtitle.IsDefault {
+		t.Fatalf("unexpected mp4 subtitle %+v", subtitle)
+	}
+	if streams[3].Title != "简体中文" || streams[3].IsDefault {
+		t.Fatalf("unexpected mp4 subtitle %+v", streams[3])
+	}
+}
+
+func TestIsVideoContainerFile(t *testing.T) {
+	for name, want := range map[string]bool{
+		"movie.mkv": true,
+		"movie.MKV": true,
+		"movie.mp4": true,
+		"movie.M4V": true,
+		"movie.avi": false,
+		"movie.srt": false,
+	} {
+		if got := IsVideoContainerFile(name); got != want {
+			t.Fatalf("IsVideoContainerFile(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestMP4ExtractAndOutputPath(t *testing.T) {
+	stream := StreamInfo{ID: "0:2", Type: "Subtitle", Codec: "mov_text", SubtitleFormat: "mov_text"}
+	if ext := SubtitleFileExtension(stream.SubtitleFormat); ext != "srt" {
+		t.Fatalf("SubtitleFileExtension(mov_text) = %q, want srt", ext)
+	}
+	args := BuildExtractFFmpegArgs("movie.mp4", stream, "out.srt")
+	if !containsArgPair(args, "-c", "srt") {
+		t.Fatalf("mov_text should be converted to srt: %#v", args)
+	}
+
+	if got := MergeOutputPath("movie.M4V"); got != "movie.M4V.tmp_subs.m4v" {
+		t.Fatalf("MergeOutputPath(m4v) = %q", got)
+	}
+	if got := MergeOutputPath("movie.mkv"); got != "movie.mkv.tmp_subs.mkv" {
+		t.Fatalf("MergeOutputPath(mkv) = %q", got)
+	}
+}
+
+func TestBuildMergeFFmpegArgs_MP4(t *testing.T) {
+	args := BuildMergeFFmpegArgs("movie.mp4", 2, []MergeSubtitle{
+		{Path: "chi.ass", Language: "chi", Default: true},
+		{Path: "eng.srt", Language: "eng"},
+	})
+
+	for _, pair := range [][2]string{
+		{"-c:s:2", "mov_text"},
+		{"-c:s:3", "mov_text"},
+		{"-metadata:s:s:2", "language=zho"},
+		{"-metadata:s:s:3", "language=eng"},
+	} {
+		if !containsArgPair(args, pair[0], pair[1]) {
+			t.Fatalf("expected %s %s in args: %#v", pair[0], pair[1], args)
+		}
+	}
+}
+
+func TestValidateMP4DefaultToggle(t *testing.T) {
+	streams := []StreamInfo{
+		{ID: "0:2", Type: "Subtitle", IsDefault: true},
+		{ID: "0:3", Type: "Subtitle"},
+	}
+	if err := ValidateMP4DefaultToggle(streams, streams[1]); err != nil {
+		t.Fatalf("setting a new default should be allowed: %v", err)
+	}
+	if err := ValidateMP4DefaultToggle(streams, streams[0]); err == nil {
+		t.Fatal("expected error when clearing the only default subtitle")
+	}
+}
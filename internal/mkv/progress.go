@@ -0,0 +1,131 @@
+package mkv
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ffmpegDurationRE = regexp.MustCompile(`Duration:\s*(\d+):(\d{2}):(\d{2}(?:\.\d+)?)`)
+
+// FFmpegProgress is one block of ffmpeg -progress output. Duration is the
+// probed length of the input, or zero when it is unknown.
+type FFmpegProgress struct {
+	OutTime  time.Duration
+	Duration time.Duration
+	Speed    float64
+	Done     bool
+}
+
+// Percent returns how much of the input has been written, or -1 when the
+// duration is unknown.
+func (p FFmpegProgress) Percent() float64 {
+	if p.Duration <= 0 {
+		return -1
+	}
+	if p.Done {
+		return 100
+	}
+	return min(float64(p.OutTime)/float64(p.Duration)*100, 100)
+}
+
+// ETA estimates the remaining time from the processing speed, or returns -1
+// when it cannot be estimated.
+func (p FFmpegProgress) ETA() time.Duration {
+	if p.Done {
+		return 0
+	}
+	if p.Duration <= 0 || p.Speed <= 0 {
+		return -1
+	}
+	return time.Duration(float64(max(p.Duration-p.OutTime, 0)) / p.Speed)
+}
+
+func (commandFFmpegRunner) RunContext(ctx context.Context, progress func(FFmpegProgress), args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "ffmpeg", append([]string{"-nostats", "-progress", "pipe:1"}, args...)...)
+	var output bytes.Buffer
+	cmd.Stderr = &output
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	parseErr := ParseFFmpegProgress(stdout, progress)
+	// Drain the pipe so ffmpeg never blocks on a full pipe buffer.
+	_, _ = io.Copy(io.Discard, stdout)
+	if err := cmd.Wait(); err != nil {
+		return output.Bytes(), err
+	}
+	return output.Bytes(), parseErr
+}
+
+// ParseFFmpegProgress reads the key=value blocks ffmpeg writes with
+// -progress and reports each block when its progress= line arrives.
+func ParseFFmpegProgress(r io.Reader, report func(FFmpegProgress)) error {
+	var current FFmpegProgress
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "out_time_us", "out_time_ms":
+			// Both keys are in microseconds.
+			if microseconds, err := strconv.ParseInt(value, 10, 64); err == nil && microseconds >= 0 {
+				current.OutTime = time.Duration(microseconds) * time.Microsecond
+			}
+		case "speed":
+			if speed, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "x"), 64); err == nil {
+				current.Speed = speed
+			}
+		case "progress":
+			current.Done = value == "end"
+			if report != nil {
+				report(current)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// RunFFmpegContext runs ffmpeg until it exits or ctx is done, and reports
+// progress against duration.
+func RunFFmpegContext(ctx context.Context, duration time.Duration, report func(FFmpegProgress), args ...string) ([]byte, error) {
+	return ffmpegRunner.RunContext(ctx, func(progress FFmpegProgress) {
+		progress.Duration = duration
+		if report != nil {
+			report(progress)
+		}
+	}, args...)
+}
+
+// ProbeDuration returns the duration of fileName from the Matroska header or
+// the ffmpeg input summary, or zero when it is unknown.
+func ProbeDuration(fileName string) time.Duration {
+	if container, err := ReadContainer(fileName); err == nil {
+		return container.Info.Duration
+	}
+	output, _ := RunFFmpeg("-hide_banner", "-i", fileName)
+	return ParseFFmpegDuration(string(output))
+}
+
+func ParseFFmpegDuration(output string) time.Duration {
+	match := ffmpegDurationRE.FindStringSubmatch(output)
+	if match == nil {
+		return 0
+	}
+	hours, _ := strconv.Atoi(match[1])
+	minutes, _ := strconv.Atoi(match[2])
+	seconds, _ := strconv.ParseFloat(match[3], 64)
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds*float64(time.Second))
+}
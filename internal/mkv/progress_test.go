@@ -0,0 +1,98 @@
+package mkv
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFFmpegProgress(t *testing.T) {
+	output := strings.Join([]string{
+		"frame=10",
+		"out_time_us=N/A",
+		"speed=N/A",
+		"progress=continue",
+		"out_time_us=30000000",
+		"out_time_ms=30000000",
+		"speed=2.5x",
+		"progress=continue",
+		"out_time_us=60000000",
+		"speed= 3x",
+		"progress=end",
+	}, "\n")
+
+	var updates []FFmpegProgress
+	if err := ParseFFmpegProgress(strings.NewReader(output), func(progress FFmpegProgress) {
+		updates = append(updates, progress)
+	}); err != nil {
+		t.Fatalf("ParseFFmpegProgress() error = %v", err)
+	}
+
+	if len(updates) != 3 {
+		t.Fatalf("updates = %+v, want 3", updates)
+	}
+	if updates[0].OutTime != 0 || updates[0].Speed != 0 {
+		t.Fatalf("first update = %+v, want unknown values ignored", updates[0])
+	}
+	if updates[1].OutTime != 30*time.Second || updates[1].Speed != 2.5 || updates[1].Done {
+		t.Fatalf("second update = %+v", updates[1])
+	}
+	if updates[2].OutTime != time.Minute || updates[2].Speed != 3 || !updates[2].Done {
+		t.Fatalf("last update = %+v", updates[2])
+	}
+}
+
+func TestFFmpegProgress_PercentAndETA(t *testing.T) {
+	progress := FFmpegProgress{OutTime: 30 * time.Second, Duration: 2 * time.Minute, Speed: 3}
+	if progress.Percent() != 25 {
+		t.Fatalf("Percent() = %v, want 25", progress.Percent())
+	}
+	if progress.ETA() != 30*time.Second {
+		t.Fatalf("ETA() = %v, want 30s", progress.ETA())
+	}
+
+	unknown := FFmpegProgress{OutTime: 30 * time.Second, Speed: 3}
+	if unknown.Percent() != -1 || unknown.ETA() != -1 {
+		t.Fatalf("unknown duration = %v, %v, want -1", unknown.Percent(), unknown.ETA())
+	}
+
+	done := FFmpegProgress{OutTime: 119 * time.Second, Duration: 2 * time.Minute, Done: true}
+	if done.Percent() != 100 || done.ETA() != 0 {
+		t.Fatalf("done = %v, %v, want 100 and 0", done.Percent(), done.ETA())
+	}
+}
+
+func TestParseFFmpegDuration(t *testing.T) {
+	output := "Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'movie.mp4':\n  Duration: 01:02:03.50, start: 0.000000, bitrate: 1000 kb/s\n"
+	if got := ParseFFmpegDuration(output); got != time.Hour+2*time.Minute+3500*time.Millisecond {
+		t.Fatalf("ParseFFmpegDuration() = %v", got)
+	}
+	if got := ParseFFmpegDuration("Duration: N/A"); got != 0 {
+		t.Fatalf("ParseFFmpegDuration(N/A) = %v, want 0", got)
+	}
+}
+
+type progressFFmpegRunner struct {
+	fakeFFmpegRunner
+}
+
+func (r *progressFFmpegRunner) RunContext(_ context.Context, progress func(FFmpegProgress), args ...string) ([]byte, error) {
+	progress(FFmpegProgress{OutTime: time.Minute, Speed: 2})
+	return r.Run(args...)
+}
+
+func TestRunFFmpegContext_ReportsProbedDuration(t *testing.T) {
+	SetFFmpegRunner(&progressFFmpegRunner{fakeFFmpegRunner{installed: true}})
+	t.Cleanup(func() { SetFFmpegRunner(nil) })
+
+	var reported FFmpegProgress
+	if _, err := RunFFmpegContext(context.Background(), 4*time.Minute, func(progress FFmpegProgress) {
+		reported = progress
+	}, "-i", "movie.mkv"); err != nil {
+		t.Fatalf("RunFFmpegContext() error = %v", err)
+	}
+	if reported.Duration != 4*time.Minute || reported.Percent() != 25 {
+		t.Fatalf("reported = %+v, want duration and 25%%", reported)
+	}
+}
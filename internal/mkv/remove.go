@@ -0,0 +1,112 @@
+package mkv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RemovalSelection describes which subtitle streams to remove. A stream is
+// removed when it matches any of the criteria.
+type RemovalSelection struct {
+	IDs []string
+	// Languages lists tags to remove, or, when every entry is prefixed
+	// with "!", the only tags to keep.
+	Languages []string
+	// Formats matches the codec name (hdmv_pgs_subtitle) or the short
+	// subtitle format (srt, ass).
+	Formats []string
+	All     bool
+}
+
+// SelectSubtitleStreamsForRemoval returns the subtitle streams matched by
+// selection, in stream order.
+func SelectSubtitleStreamsForRemoval(allStreams []StreamInfo, selection RemovalSelection) ([]StreamInfo, error) {
+	selected := map[string]bool{}
+	for _, targetID := range selection.IDs {
+		stream, err := FindStreamForSubtitleRemoval(allStreams, targetID)
+		if err != nil {
+			return nil, err
+		}
+		selected[stream.ID] = true
+	}
+
+	var removeLanguages, keepLanguages []string
+	for _, language := range selection.Languages {
+		tag := strings.TrimPrefix(language, "!")
+		if !ValidLanguageTag(tag) {
+			return nil, fmt.Errorf("invalid language tag: %s", tag)
+		}
+		if strings.HasPrefix(language, "!") {
+			keepLanguages = append(keepLanguages, tag)
+		} else {
+			removeLanguages = append(removeLanguages, tag)
+		}
+	}
+	if len(removeLanguages) > 0 && len(keepLanguages) > 0 {
+		return nil, fmt.Errorf("cannot mix removed and kept languages: %s", strings.Join(selection.Languages, ","))
+	}
+
+	var selectedStreams []StreamInfo
+	for _, stream := range allStreams {
+		if stream.Type != "Subtitle" {
+			continue
+		}
+
+		language := stream.Language
+		if language == "" {
+			language = undeterminedLanguage
+		}
+		switch {
+		case selection.All:
+		case selected[stream.ID]:
+		case len(removeLanguages) > 0 && containsLanguage(removeLanguages, language):
+		case len(keepLanguages) > 0 && !containsLanguage(keepLanguages, language):
+		case matchesSubtitleFormat(stream, selection.Formats):
+		default:
+			continue
+		}
+		selectedStreams = append(selectedStreams, stream)
+	}
+	return selectedStreams, nil
+}
+
+func containsLanguage(languages []string, language string) bool {
+	for _, candidate := range languages {
+		if SameLanguage(candidate, language) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesSubtitleFormat(stream StreamInfo, formats []string) bool {
+	for _, format := range formats {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format == "" {
+			continue
+		}
+		if strings.EqualFold(stream.Codec, format) {
+			return true
+		}
+		if stream.SubtitleFormat != "" && SubtitleFileExtension(stream.SubtitleFormat) == format {
+			return true
+		}
+	}
+	return false
+}
+
+// BuildRemoveStreamsFFmpegArgs removes all given streams in a single remux.
+func BuildRemoveStreamsFFmpegArgs(sourceFile string, streams []StreamInfo) []string {
+	ffmpegArgs := []string{
+		"-hide_banner",
+		"-y",
+		"-i",
+		sourceFile,
+		"-map",
+		"0",
+	}
+	for _, stream := range streams {
+		ffmpegArgs = append(ffmpegArgs, "-map", "-"+stream.ID)
+	}
+	return append(ffmpegArgs, "-c", "copy")
+}
@@ -0,0 +1,51 @@
+package mkv
+
+import "testing"
+
+func TestSelectSubtitleStreamsForRemoval(t *testing.T) {
+	streams := []StreamInfo{
+		{ID: "0:0", Type: "Video"},
+		{ID: "0:1", Type: "Audio", Language: "eng"},
+		{ID: "0:2", Type: "Subtitle", Language: "eng", Codec: "subrip", SubtitleFormat: "srt"},
+		{ID: "0:3", Type: "Subtitle", Language: "chi", Codec: "ass", SubtitleFormat: "ass (ssa)"},
+		{ID: "0:4", Type: "Subtitle", Language: "zho", Codec: "hdmv_pgs_subtitle"},
+		{ID: "0:5", Type: "Subtitle", Codec: "subrip", SubtitleFormat: "srt"},
+	}
+
+	cases := []struct {
+		name      string
+		selection RemovalSelection
+		want      string
+	}{
+		{"ids", RemovalSelection{IDs: []string{"5", "2"}}, "0:2,0:5"},
+		{"languages", RemovalSelection{Languages: []string{"eng", "und"}}, "0:2,0:5"},
+		{"keep only", RemovalSelection{Languages: []string{"!chi"}}, "0:2,0:5"},
+		{"codec format", RemovalSelection{Formats: []string{"hdmv_pgs_subtitle"}}, "0:4"},
+		{"short format", RemovalSelection{Formats: []string{"ass"}}, "0:3"},
+		{"union", RemovalSelection{IDs: []string{"3"}, Formats: []string{"srt"}}, "0:2,0:3,0:5"},
+		{"all", RemovalSelection{All: true}, "0:2,0:3,0:4,0:5"},
+	}
+	for _, tc := range cases {
+		selected, err := SelectSubtitleStreamsForRemoval(streams, tc.selection)
+		if err != nil {
+			t.Fatalf("%s: SelectSubtitleStreamsForRemoval() error = %v", tc.name, err)
+		}
+		if got := streamIDs(selected); got != tc.want {
+			t.Fatalf("%s: selected = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+
+	if _, err := SelectSubtitleStreamsForRemoval(streams, RemovalSelection{IDs: []string{"1"}}); err == nil {
+		t.Fatal("expected non-subtitle stream error")
+	}
+	if _, err := SelectSubtitleStreamsForRemoval(streams, RemovalSelection{Languages: []string{"eng", "!chi"}}); err == nil {
+		t.Fatal("expected mixed languages error")
+	}
+}
+
+func TestBuildRemoveStreamsFFmpegArgs(t *testing.T) {
+	args := BuildRemoveStreamsFFmpegArgs("target.mkv", []StreamInfo{{ID: "0:3"}, {ID: "0:5"}})
+	if !containsArgPair(args, "-map", "0") || !containsArgPair(args, "-map", "-0:3") || !containsArgPair(args, "-map", "-0:5") {
+		t.Fatalf("unexpected args: %#v", args)
+	}
+}
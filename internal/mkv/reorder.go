@@ -0,0 +1,145 @@
+package mkv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// languageTagAliases maps ISO 639-2 bibliographic codes to their
+// terminology counterparts, so chi and zho name the same language.
+var languageTagAliases = map[string]string{
+	"alb": "sqi",
+	"arm": "hye",
+	"baq": "eus",
+	"bur": "mya",
+	"chi": "zho",
+	"cze": "ces",
+	"dut": "nld",
+	"fre": "fra",
+	"geo": "kat",
+	"ger": "deu",
+	"gre": "ell",
+	"ice": "isl",
+	"mac": "mkd",
+	"mao": "mri",
+	"may": "msa",
+	"per": "fas",
+	"rum": "ron",
+	"slo": "slk",
+	"tib": "bod",
+	"wel": "cym",
+}
+
+// SameLanguage reports whether two language tags name the same language.
+func SameLanguage(a, b string) bool {
+	return normalizeLanguageTag(a) == normalizeLanguageTag(b)
+}
+
+func normalizeLanguageTag(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if alias, ok := languageTagAliases[language]; ok {
+		return alias
+	}
+	return language
+}
+
+// OrderSubtitleStreamsByID returns the subtitle streams of allStreams with
+// the given stream ids first, in that order, followed by the remaining
+// subtitle streams in their current order.
+func OrderSubtitleStreamsByID(allStreams []StreamInfo, order []string) ([]StreamInfo, error) {
+	var ordered []StreamInfo
+	picked := map[string]bool{}
+	for _, targetID := range order {
+		stream, err := FindStreamForSubtitleRemoval(allStreams, targetID)
+		if err != nil {
+			return nil, err
+		}
+		if picked[stream.ID] {
+			return nil, fmt.Errorf("stream id %s is listed more than once", targetID)
+		}
+		picked[stream.ID] = true
+		ordered = append(ordered, stream)
+	}
+
+	for _, stream := range allStreams {
+		if stream.Type == "Subtitle" && !picked[stream.ID] {
+			ordered = append(ordered, stream)
+		}
+	}
+	return ordered, nil
+}
+
+// OrderSubtitleStreamsByLanguage returns the subtitle streams of allStreams
+// grouped by the given language priority. Streams of the same language, and
+// streams of unlisted languages, keep their current relative order.
+func OrderSubtitleStreamsByLanguage(allStreams []StreamInfo, languages []string) ([]StreamInfo, error) {
+	for _, language := range languages {
+		if !ValidLanguageTag(language) {
+			return nil, fmt.Errorf("invalid language tag: %s", language)
+		}
+	}
+
+	var ordered []StreamInfo
+	picked := map[string]bool{}
+	for _, language := range languages {
+		for _, stream := range allStreams {
+			if stream.Type == "Subtitle" && !picked[stream.ID] && SameLanguage(stream.Language, language) {
+				picked[stream.ID] = true
+				ordered = append(ordered, stream)
+			}
+		}
+	}
+
+	for _, stream := range allStreams {
+		if stream.Type == "Subtitle" && !picked[stream.ID] {
+			ordered = append(ordered, stream)
+		}
+	}
+	return ordered, nil
+}
+
+// SubtitleOrderChanged reports whether orderedSubtitles differs from the
+// current subtitle order of allStreams.
+func SubtitleOrderChanged(allStreams, orderedSubtitles []StreamInfo) bool {
+	subtitleIndex := 0
+	for _, stream := range allStreams {
+		if stream.Type != "Subtitle" {
+			continue
+		}
+		if subtitleIndex >= len(orderedSubtitles) || orderedSubtitles[subtitleIndex].ID != stream.ID {
+			return true
+		}
+		subtitleIndex++
+	}
+	return false
+}
+
+// BuildReorderFFmpegArgs remuxes sourceFile mapping every stream explicitly:
+// non-subtitle streams stay where they are and the subtitle slots are filled
+// with orderedSubtitles. Stream copy carries dispositions and metadata along.
+func BuildReorderFFmpegArgs(sourceFile string, allStreams, orderedSubtitles []StreamInfo) []string {
+	ffmpegArgs := []string{
+		"-hide_banner",
+		"-y",
+		"-i",
+		sourceFile,
+	}
+
+	subtitleIndex := 0
+	for _, stream := range allStreams {
+		mapped := stream
+		if stream.Type == "Subtitle" {
+			if subtitleIndex >= len(orderedSubtitles) {
+				return nil
+			}
+			mapped = orderedSubtitles[subtitleIndex]
+			subtitleIndex++
+		}
+		ffmpegArgs = append(ffmpegArgs, "-map", "0:"+StreamIDTail(mapped.ID))
+	}
+	if subtitleIndex != len(orderedSubtitles) {
+		return nil
+	}
+
+	return append(ffmpegArgs, "-c", "copy")
+}
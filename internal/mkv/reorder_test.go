@@ -0,0 +1,97 @@
+package mkv
+
+import (
+	"strings"
+	"testing"
+)
+
+func reorderTestStreams() []StreamInfo {
+	return []StreamInfo{
+		{ID: "0:0", Type: "Video"},
+		{ID: "0:1", Type: "Audio", Language: "jpn"},
+		{ID: "0:2", Type: "Subtitle", Language: "eng"},
+		{ID: "0:3", Type: "Subtitle", Language: "jpn"},
+		{ID: "0:4", Type: "Audio", Language: "eng"},
+		{ID: "0:5", Type: "Subtitle", Language: "zho"},
+		{ID: "0:6", Type: "Attachment"},
+	}
+}
+
+func streamIDs(streams []StreamInfo) string {
+	ids := make([]string, 0, len(streams))
+	for _, stream := range streams {
+		ids = append(ids, stream.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestOrderSubtitleStreamsByID(t *testing.T) {
+	streams := reorderTestStreams()
+
+	ordered, err := OrderSubtitleStreamsByID(streams, []string{"5", "3"})
+	if err != nil {
+		t.Fatalf("OrderSubtitleStreamsByID() error = %v", err)
+	}
+	if got := streamIDs(ordered); got != "0:5,0:3,0:2" {
+		t.Fatalf("order = %s, want 0:5,0:3,0:2", got)
+	}
+
+	if _, err := OrderSubtitleStreamsByID(streams, []string{"5", "5"}); err == nil {
+		t.Fatal("expected duplicate id error")
+	}
+	if _, err := OrderSubtitleStreamsByID(streams, []string{"4"}); err == nil {
+		t.Fatal("expected non-subtitle stream error")
+	}
+}
+
+func TestOrderSubtitleStreamsByLanguage(t *testing.T) {
+	streams := reorderTestStreams()
+
+	ordered, err := OrderSubtitleStreamsByLanguage(streams, []string{"chi", "eng"})
+	if err != nil {
+		t.Fatalf("OrderSubtitleStreamsByLanguage() error = %v", err)
+	}
+	if got := streamIDs(ordered); got != "0:5,0:2,0:3" {
+		t.Fatalf("order = %s, want 0:5,0:2,0:3", got)
+	}
+
+	if _, err := OrderSubtitleStreamsByLanguage(streams, []string{"Chinese"}); err == nil {
+		t.Fatal("expected invalid language tag error")
+	}
+}
+
+func TestSubtitleOrderChanged(t *testing.T) {
+	streams := reorderTestStreams()
+
+	unchanged, _ := OrderSubtitleStreamsByID(streams, []string{"2"})
+	if SubtitleOrderChanged(streams, unchanged) {
+		t.Fatal("expected unchanged order")
+	}
+	changed, _ := OrderSubtitleStreamsByID(streams, []string{"3"})
+	if !SubtitleOrderChanged(streams, changed) {
+		t.Fatal("expected changed order")
+	}
+}
+
+func TestBuildReorderFFmpegArgs(t *testing.T) {
+	streams := reorderTestStreams()
+	ordered, _ := OrderSubtitleStreamsByID(streams, []string{"5"})
+
+	args := BuildReorderFFmpegArgs("target.mkv", streams, ordered)
+	var maps []string
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == "-map" {
+			maps = append(maps, args[i+1])
+		}
+	}
+	if got := strings.Join(maps, ","); got != "0:0,0:1,0:5,0:2,0:4,0:3,0:6" {
+		t.Fatalf("maps = %s, want video/audio in place and subtitles reordered", got)
+	}
+	if !containsArgPair(args, "-c", "copy") {
+		t.Fatalf("expected stream copy: %#v", args)
+	}
+
+	if args := BuildReorderFFmpegArgs("target.mkv", streams, ordered[:1]); args != nil {
+		t.Fatalf("expected nil args for incomplete order, got %#v", args)
+	}
+}
@@ -0,0 +1,353 @@
+package mkv
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ExpectedStream is a stream a remux must produce. Flags lists the
+// disposition flags (default, forced, hearing_impaired) whose state the remux
+// sets; other flags are not checked.
+type ExpectedStream struct {
+	Type  string
+	Codec string
+	Flags map[string]bool
+}
+
+// ExpectSameStreams expects the streams of a plain stream copy.
+func ExpectSameStreams(allStreams []StreamInfo) []ExpectedStream {
+	var expected []ExpectedStream
+	for _, stream := range verifiableStreams(allStreams) {
+		expected = append(expected, ExpectedStream{Type: stream.Type, Codec: stream.Codec})
+	}
+	return expected
+}
+
+// ExpectStreamsAfterRemoval expects allStreams without removed.
+func ExpectStreamsAfterRemoval(allStreams, removed []StreamInfo) []ExpectedStream {
+	removedIDs := map[string]bool{}
+	for _, stream := range removed {
+		removedIDs[stream.ID] = true
+	}
+
+	var kept []StreamInfo
+	for _, stream := range allStreams {
+		if !removedIDs[stream.ID] {
+			kept = append(kept, stream)
+		}
+	}
+	return ExpectSameStreams(kept)
+}
+
+// ExpectStreamsAfterToggle expects flag to be cleared on target when
+// targetSet, and otherwise set on target and cleared on the other subtitles,
+// as BuildDefaultToggleFFmpegArgs and BuildForceToggleFFmpegArgs do.
+func ExpectStreamsAfterToggle(allStreams []StreamInfo, target StreamInfo, flag string, targetSet bool) []ExpectedStream {
+	expected := ExpectSameStreams(allStreams)
+	for i, stream := range verifiableStreams(allStreams) {
+		if stream.Type != "Subtitle" {
+			continue
+		}
+		if stream.ID == target.ID {
+			expected[i].Flags = map[string]bool{flag: !targetSet}
+		} else if !targetSet {
+			expected[i].Flags = map[string]bool{flag: false}
+		}
+	}
+	return expected
+}
+
+// ExpectStreamsAfterReorder expects the subtitle streams of allStreams in
+// the order of orderedSubtitles, as BuildReorderFFmpegArgs maps them.
+func ExpectStreamsAfterReorder(allStreams, orderedSubtitles []StreamInfo) []ExpectedStream {
+	reordered := make([]StreamInfo, 0, len(allStreams))
+	subtitleIndex := 0
+	for _, stream := range allStreams {
+		if stream.Type == "Subtitle" && subtitleIndex < len(orderedSubtitles) {
+			stream = orderedSubtitles[subtitleIndex]
+			subtitleIndex++
+		}
+		reordered = append(reordered, stream)
+	}
+	return ExpectSameStreams(reordered)
+}
+
+// ExpectStreamsAfterDisposition expects the flags BuildDispositionFFmpegArgs
+// sets and clears on target, and with exclusive clears on the other streams
+// of its type. Flags the probe does not report are not checked.
+func ExpectStreamsAfterDisposition(allStreams []StreamInfo, target StreamInfo, setFlags, clearFlags []string, exclusive bool) []ExpectedStream {
+	expected := ExpectSameStreams(allStreams)
+	for i, stream := range verifiableStreams(allStreams) {
+		flags := map[string]bool{}
+		switch {
+		case stream.ID == target.ID:
+			for _, flag := range setFlags {
+				flags[flag] = true
+			}
+			for _, flag := range clearFlags {
+				flags[flag] = false
+			}
+		case exclusive && stream.Type == target.Type:
+			for _, flag := range setFlags {
+				flags[flag] = false
+			}
+		}
+		for flag := range flags {
+			if flag != "default" && flag != "forced" && flag != "hearing_impaired" {
+				delete(flags, flag)
+			}
+		}
+		if len(flags) > 0 {
+			expected[i].Flags = flags
+		}
+	}
+	return expected
+}
+
+// ExpectStreamsAfterMerge expects subtitles to be added to targetStreams
+// after all streams, or before the existing subtitle stream at position when
+// position is not negative.
+func ExpectStreamsAfterMerge(targetFile string, targetStreams []StreamInfo, subtitles []MergeSubtitle, position int) []ExpectedStream {
+	hasNewDefault := false
+	var added []ExpectedStream
+	for _, subtitle := range subtitles {
+		hasNewDefault = hasNewDefault || subtitle.Default
+		added = append(added, ExpectedStream{
+			Type:  "Subtitle",
+			Codec: mergedSubtitleCodec(targetFile, subtitle),
+			Flags: map[string]bool{
+				"default":          subtitle.Default,
+				"forced":           subtitle.Forced,
+				"hearing_impaired": subtitle.HearingImpaired,
+			},
+		})
+	}
+
+	var expected []ExpectedStream
+	subtitleIndex := 0
+	for _, stream := range verifiableStreams(targetStreams) {
+		if stream.Type == "Subtitle" {
+			if subtitleIndex == position {
+				expected = append(expected, added...)
+			}
+			subtitleIndex++
+		}
+
+		kept := ExpectedStream{Type: stream.Type, Codec: stream.Codec}
+		if stream.Type == "Subtitle" && hasNewDefault {
+			kept.Flags = map[string]bool{"default": false}
+		}
+		expected = append(expected, kept)
+	}
+	if position < 0 || position >= subtitleIndex {
+		expected = append(expected, added...)
+	}
+	return expected
+}
+
+func mergedSubtitleCodec(targetFile string, subtitle MergeSubtitle) string {
+	if IsMP4File(targetFile) {
+		return mp4SubtitleCodec
+	}
+	switch strings.ToLower(filepath.Ext(subtitle.Path)) {
+	case ".ass", ".ssa":
+		return "ass"
+	}
+	return "subrip"
+}
+
+// verifiableStreams leaves out data streams, which ffmpeg lists for some
+// mp4 chapter tracks but does not copy.
+func verifiableStreams(allStreams []StreamInfo) []StreamInfo {
+	var streams []StreamInfo
+	for _, stream := range allStreams {
+		if stream.Type != "Data" {
+			streams = append(streams, stream)
+		}
+	}
+	return streams
+}
+
+// VerifyRemuxOutput re-probes outputFile before it replaces sourceFile and
+// checks the streams against expected. The media of the output must not end
+// earlier than that of the source, which catches output cut short when the
+// disk ran full; it may end later when a merged subtitle ends after the
+// video.
+func VerifyRemuxOutput(sourceFile, outputFile string, expected []ExpectedStream) error {
+	info, err := os.Stat(outputFile)
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		return fmt.Errorf("output %s is empty", outputFile)
+	}
+
+	streams, err := ProbeStreams(outputFile)
+	if err != nil {
+		return fmt.Errorf("cannot read output %s: %w", outputFile, err)
+	}
+	streams = verifiableStreams(streams)
+	if len(streams) != len(expected) {
+		return fmt.Errorf("output has %d streams, expected %d", len(streams), len(expected))
+	}
+
+	for i, stream := range streams {
+		want := expected[i]
+		if stream.Type != want.Type {
+			return fmt.Errorf("output stream %s is %s, expected %s", stream.ID, stream.Type, want.Type)
+		}
+		if want.Codec != "" && !strings.EqualFold(stream.Codec, want.Codec) {
+			return fmt.Errorf("output stream %s has codec %s, expected %s", stream.ID, stream.Codec, want.Codec)
+		}
+		for flag, value := range want.Flags {
+			if streamFlag(stream, flag) != value {
+				return fmt.Errorf("output stream %s has %s=%t, expected %t", stream.ID, flag, !value, value)
+			}
+		}
+	}
+
+	sourceLength, outputLength, err := mediaLengths(sourceFile, outputFile)
+	if err != nil {
+		return err
+	}
+	if sourceLength <= 0 {
+		return nil
+	}
+	tolerance := max(2*time.Second, sourceLength/100)
+	if outputLength < sourceLength-tolerance {
+		return fmt.Errorf("output is %s long, source is %s", outputLength.Round(time.Millisecond), sourceLength.Round(time.Millisecond))
+	}
+	return nil
+}
+
+// mediaLengths measures Matroska files by their last block, since the
+// header duration is only metadata, and other files by the duration ffmpeg
+// reports.
+func mediaLengths(sourceFile, outputFile string) (time.Duration, time.Duration, error) {
+	if sourceEnd, err := lastBlockTimestamp(sourceFile); err == nil {
+		outputEnd, err := lastBlockTimestamp(outputFile)
+		if err == nil {
+			return sourceEnd, outputEnd, nil
+		}
+		if !errors.Is(err, ErrNotMatroska) {
+			return 0, 0, fmt.Errorf("cannot read media of output %s: %w", outputFile, err)
+		}
+	}
+
+	sourceDuration := ProbeDuration(sourceFile)
+	if sourceDuration <= 0 {
+		return 0, 0, nil
+	}
+	return sourceDuration, ProbeDuration(outputFile), nil
+}
+
+// lastBlockTimestamp returns the latest block timestamp in the last cluster
+// of a Matroska file, which is where the written media ends.
+func lastBlockTimestamp(fileName string) (time.Duration, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	container, err := ParseContainer(file, info.Size())
+	if err != nil {
+		return 0, err
+	}
+
+	var cluster ebmlElement
+	offset := container.segment.DataOffset
+	for offset < container.segment.End() {
+		element, err := readElementHeader(file, offset, container.segment.End())
+		if err != nil {
+			return 0, err
+		}
+		if element.ID == clusterID {
+			cluster = element
+		}
+		offset = element.End()
+	}
+	if cluster.ID != clusterID {
+		return 0, fmt.Errorf("no cluster found")
+	}
+
+	var clusterTimestamp uint64
+	latest, found := int16(0), false
+	readBlock := func(block ebmlElement) error {
+		relative, err := readBlockTimestamp(file, block)
+		if err == nil && (!found || relative > latest) {
+			latest, found = relative, true
+		}
+		return err
+	}
+	err = forEachChild(file, cluster, func(child ebmlElement) error {
+		var err error
+		switch child.ID {
+		case clusterTimestampID:
+			clusterTimestamp, err = readUnsigned(file, child)
+		case simpleBlockID:
+			err = readBlock(child)
+		case blockGroupID:
+			err = forEachChild(file, child, func(groupChild ebmlElement) error {
+				if groupChild.ID != blockID {
+					return nil
+				}
+				return readBlock(groupChild)
+			})
+		}
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration((int64(clusterTimestamp) + int64(latest)) * int64(container.Info.TimestampScale)), nil
+}
+
+func streamFlag(stream StreamInfo, flag string) bool {
+	switch flag {
+	case "default":
+		return stream.IsDefault
+	case "forced":
+		return stream.IsForced
+	case "hearing_impaired":
+		return stream.IsHearingImpaired
+	}
+	return false
+}
+
+// CheckFreeDiskSpace fails when the file system of dir has less than
+// required bytes available. It does nothing where free space cannot be
+// queried.
+func CheckFreeDiskSpace(dir string, required int64) error {
+	available, ok, err := availableDiskSpace(dir)
+	if err != nil {
+		return err
+	}
+	if !ok || required <= 0 || available >= uint64(required) {
+		return nil
+	}
+	return fmt.Errorf("not enough free disk space in %s: %s needed, %s available", dir, formatByteSize(uint64(required)), formatByteSize(available))
+}
+
+func formatByteSize(size uint64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size)
+	for _, suffix := range []string{"KiB", "MiB", "GiB", "TiB"} {
+		value /= unit
+		if value < unit || suffix == "TiB" {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+	}
+	return fmt.Sprintf("%d B", size)
+}
@@ -0,0 +1,249 @@
+package mkv
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeVerifyTestMatroska writes a file whose media ends at lastBlockMS,
+// spread over two clusters. The header duration always claims 1m30s.
+func writeVerifyTestMatroska(t *testing.T, name string, lastBlockMS uint64) string {
+	t.Helper()
+
+	block := func(relative int16) []byte {
+		var timestamp [2]byte
+		binary.BigEndian.PutUint16(timestamp[:], uint16(relative))
+		return ebmlTestBytes(simpleBlockID, append(append([]byte{0x81}, timestamp[:]...), 0x80, 'x'))
+	}
+	clusterTimestamp := lastBlockMS - 1000
+	clusters := append(
+		ebmlTestMaster(clusterID, ebmlTestUint(clusterTimestampID, 0), block(0)),
+		ebmlTestMaster(clusterID, ebmlTestUint(clusterTimestampID, clusterTimestamp), block(0), block(1000), block(500))...,
+	)
+
+	fileName := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(fileName, buildTestMatroskaWithCluster(t, clusters), 0o644); err != nil {
+		t.Fatalf("write test mkv failed: %v", err)
+	}
+	return fileName
+}
+
+func TestVerifyRemuxOutput(t *testing.T) {
+	source := writeVerifyTestMatroska(t, "movie.mkv", 90000)
+	output := writeVerifyTestMatroska(t, "movie.mkv.tmp_subs.mkv", 90000)
+	streams, err := ProbeStreams(source)
+	if err != nil {
+		t.Fatalf("ProbeStreams() error = %v", err)
+	}
+
+	if err := VerifyRemuxOutput(source, output, ExpectSameStreams(streams)); err != nil {
+		t.Fatalf("VerifyRemuxOutput() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		expected []ExpectedStream
+		want     string
+	}{
+		{
+			name:     "stream count",
+			expected: ExpectStreamsAfterRemoval(streams, streams[1:2]),
+			want:     "output has 3 streams, expected 2",
+		},
+		{
+			name: "codec",
+			expected: []ExpectedStream{
+				{Type: "Video", Codec: "h264"},
+				{Type: "Subtitle", Codec: "subrip"},
+				{Type: "Attachment"},
+			},
+			want: "output stream 0:1 has codec ass, expected subrip",
+		},
+		{
+			name:     "disposition",
+			expected: ExpectStreamsAfterToggle(streams, streams[1], "forced", true),
+			want:     "output stream 0:1 has forced=true, expected false",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyRemuxOutput(source, output, tt.expected)
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("VerifyRemuxOutput() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyRemuxOutput_RejectsTruncatedOutput(t *testing.T) {
+	source := writeVerifyTestMatroska(t, "movie.mkv", 90000)
+	streams, err := ProbeStreams(source)
+	if err != nil {
+		t.Fatalf("ProbeStreams() error = %v", err)
+	}
+
+	almost := writeVerifyTestMatroska(t, "almost.mkv", 89000)
+	if err := VerifyRemuxOutput(source, almost, ExpectSameStreams(streams)); err != nil {
+		t.Fatalf("VerifyRemuxOutput() within tolerance error = %v", err)
+	}
+
+	// The header of the short file still claims the full duration.
+	short := writeVerifyTestMatroska(t, "short.mkv", 30000)
+	err = VerifyRemuxOutput(source, short, ExpectSameStreams(streams))
+	if err == nil || err.Error() != "output is 30s long, source is 1m30s" {
+		t.Fatalf("VerifyRemuxOutput() error = %v, want short output error", err)
+	}
+
+	data, err := os.ReadFile(source)
+	if err != nil {
+		t.Fatalf("read source failed: %v", err)
+	}
+	cut := filepath.Join(t.TempDir(), "cut.mkv")
+	if err := os.WriteFile(cut, data[:len(data)-8], 0o644); err != nil {
+		t.Fatalf("write cut output failed: %v", err)
+	}
+	SetFFmpegRunner(&fakeFFmpegRunner{installed: true, output: "Stream #0:0: Video: h264\nStream #0:1: Subtitle: ass\nStream #0:2: Attachment: ttf\n"})
+	t.Cleanup(func() { SetFFmpegRunner(nil) })
+	err = VerifyRemuxOutput(source, cut, ExpectSameStreams(streams))
+	if err == nil || !strings.Contains(err.Error(), "cannot read media of output") {
+		t.Fatalf("VerifyRemuxOutput() error = %v, want cut output error", err)
+	}
+}
+
+func TestVerifyRemuxOutput_RejectsEmptyOutput(t *testing.T) {
+	source := writeVerifyTestMatroska(t, "movie.mkv", 90000)
+	output := filepath.Join(t.TempDir(), "empty.mkv")
+	if err := os.WriteFile(output, nil, 0o644); err != nil {
+		t.Fatalf("write output failed: %v", err)
+	}
+
+	err := VerifyRemuxOutput(source, output, nil)
+	if err == nil || !strings.Contains(err.Error(), "is empty") {
+		t.Fatalf("VerifyRemuxOutput() error = %v, want empty output error", err)
+	}
+}
+
+func TestExpectStreamsAfterToggle(t *testing.T) {
+	streams := []StreamInfo{
+		{ID: "0:0", Type: "Video", Codec: "h264"},
+		{ID: "0:1", Type: "Subtitle", Codec: "subrip", IsDefault: true},
+		{ID: "0:2", Type: "Subtitle", Codec: "ass"},
+	}
+
+	setting := ExpectStreamsAfterToggle(streams, streams[2], "default", false)
+	if setting[0].Flags != nil || setting[1].Flags["default"] || !setting[2].Flags["default"] {
+		t.Fatalf("set toggle expectations = %+v", setting)
+	}
+
+	clearing := ExpectStreamsAfterToggle(streams, streams[1], "default", true)
+	if clearing[1].Flags["default"] || clearing[2].Flags != nil {
+		t.Fatalf("clear toggle expectations = %+v", clearing)
+	}
+}
+
+func TestExpectStreamsAfterReorder(t *testing.T) {
+	streams := []StreamInfo{
+		{ID: "0:0", Type: "Video", Codec: "h264"},
+		{ID: "0:1", Type: "Subtitle", Codec: "subrip"},
+		{ID: "0:2", Type: "Data", Codec: "bin_data"},
+		{ID: "0:3", Type: "Subtitle", Codec: "ass"},
+	}
+
+	expected := ExpectStreamsAfterReorder(streams, []StreamInfo{streams[3], streams[1]})
+	var codecs []string
+	for _, stream := range expected {
+		codecs = append(codecs, stream.Codec)
+	}
+	if got := strings.Join(codecs, ","); got != "h264,ass,subrip" {
+		t.Fatalf("reordered codecs = %s", got)
+	}
+}
+
+func TestExpectStreamsAfterDisposition(t *testing.T) {
+	streams := []StreamInfo{
+		{ID: "0:0", Type: "Audio", Codec: "aac", IsDefault: true},
+		{ID: "0:1", Type: "Subtitle", Codec: "subrip", IsForced: true},
+		{ID: "0:2", Type: "Subtitle", Codec: "ass", IsForced: true},
+	}
+
+	expected := ExpectStreamsAfterDisposition(streams, streams[2], []string{"default", "captions"}, []string{"forced"}, true)
+	if expected[0].Flags != nil {
+		t.Fatalf("audio flags = %+v, want unchecked", expected[0].Flags)
+	}
+	if len(expected[1].Flags) != 1 || expected[1].Flags["default"] {
+		t.Fatalf("sibling flags = %+v, want default cleared", expected[1].Flags)
+	}
+	want := map[string]bool{"default": true, "forced": false}
+	if len(expected[2].Flags) != len(want) || !expected[2].Flags["default"] || expected[2].Flags["forced"] {
+		t.Fatalf("target flags = %+v, want %+v", expected[2].Flags, want)
+	}
+}
+
+func TestExpectStreamsAfterMerge(t *testing.T) {
+	streams := []StreamInfo{
+		{ID: "0:0", Type: "Video", Codec: "h264"},
+		{ID: "0:1", Type: "Subtitle", Codec: "subrip", IsDefault: true},
+		{ID: "0:2", Type: "Attachment", Codec: "ttf"},
+		{ID: "0:3", Type: "Data", Codec: "bin_data"},
+	}
+	subtitles := []MergeSubtitle{
+		{Path: "movie.chs.ass", Default: true},
+		{Path: "movie.eng.srt", Forced: true},
+	}
+
+	appended := ExpectStreamsAfterMerge("movie.mkv", streams, subtitles, -1)
+	var codecs []string
+	for _, stream := range appended {
+		codecs = append(codecs, stream.Codec)
+	}
+	if got := strings.Join(codecs, ","); got != "h264,subrip,ttf,ass,subrip" {
+		t.Fatalf("appended codecs = %s", got)
+	}
+	if appended[1].Flags["default"] || !appended[3].Flags["default"] || !appended[4].Flags["forced"] {
+		t.Fatalf("appended flags = %+v", appended)
+	}
+
+	inserted := ExpectStreamsAfterMerge("movie.mp4", streams, subtitles[1:], 0)
+	codecs = nil
+	for _, stream := range inserted {
+		codecs = append(codecs, stream.Codec)
+	}
+	if got := strings.Join(codecs, ","); got != "h264,mov_text,subrip,ttf" {
+		t.Fatalf("inserted codecs = %s", got)
+	}
+	if inserted[2].Flags != nil {
+		t.Fatalf("existing subtitle flags = %+v, want unchecked", inserted[2].Flags)
+	}
+}
+
+func TestCheckFreeDiskSpace(t *testing.T) {
+	dir := t.TempDir()
+	if err := CheckFreeDiskSpace(dir, 1); err != nil {
+		t.Fatalf("CheckFreeDiskSpace() error = %v", err)
+	}
+
+	available, ok, err := availableDiskSpace(dir)
+	if err != nil || !ok {
+		t.Skipf("free disk space is not available: %v", err)
+	}
+	err = CheckFreeDiskSpace(dir, int64(available)+1<<40)
+	if err == nil || !strings.Contains(err.Error(), "not enough free disk space in "+dir) {
+		t.Fatalf("CheckFreeDiskSpace() error = %v, want not enough space error", err)
+	}
+}
+
+func TestFormatByteSize(t *testing.T) {
+	for size, want := range map[uint64]string{
+		512:            "512 B",
+		1536:           "1.5 KiB",
+		5 << 30:        "5.0 GiB",
+		3 << 40 * 2048: "6144.0 TiB",
+	} {
+		if got := formatByteSize(size); got != want {
+			t.Fatalf("formatByteSize(%d) = %s, want %s", size, got, want)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package mkv
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	vobSubCodecID    = "S_VOBSUB"
+	vobSubSectorSize = 2048
+)
+
+// vobSubPackHeader is an MPEG-2 pack header with a zero SCR, as written by
+// mkvextract.
+var vobSubPackHeader = []byte{0x00, 0x00, 0x01, 0xBA, 0x44, 0x00, 0x04, 0x00, 0x04, 0x01, 0x01, 0x89, 0xC3, 0xF8}
+
+// vobSubLanguageIDs maps ISO 639-2/T codes to the two-letter ids used in
+// VobSub idx files.
+var vobSubLanguageIDs = map[string]string{
+	"ara": "ar",
+	"ces": "cs",
+	"dan": "da",
+	"deu": "de",
+	"ell": "el",
+	"eng": "en",
+	"fin": "fi",
+	"fra": "fr",
+	"heb": "he",
+	"hun": "hu",
+	"ita": "it",
+	"jpn": "ja",
+	"kor": "ko",
+	"nld": "nl",
+	"nor": "no",
+	"pol": "pl",
+	"por": "pt",
+	"rus": "ru",
+	"spa": "es",
+	"swe": "sv",
+	"tha": "th",
+	"tur": "tr",
+	"vie": "vi",
+	"zho": "zh",
+}
+
+type trackFrame struct {
+	timestamp time.Duration
+	data      []byte
+}
+
+// ExtractVobSub writes the VobSub track of stream to idxPath and the matching
+// .sub file next to it. ffmpeg cannot write VobSub, so the packets are read
+// from the Matroska clusters and wrapped into MPEG program stream sectors the
+// way mkvextract does.
+func ExtractVobSub(fileName string, stream StreamInfo, idxPath string) error {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	container, err := ParseContainer(file, info.Size())
+	if err != nil {
+		if errors.Is(err, ErrNotMatroska) {
+			return fmt.Errorf("vobsub extraction needs a matroska file: %s", fileName)
+		}
+		return err
+	}
+
+	index, err := StreamIndex(stream.ID)
+	if err != nil {
+		return err
+	}
+	trackIndex, ok := container.trackIndex(index)
+	if !ok {
+		return fmt.Errorf("stream id %s not found", stream.ID)
+	}
+	track := container.Tracks[trackIndex]
+	if track.CodecID != vobSubCodecID {
+		return fmt.Errorf("stream id %s is not a vobsub stream", stream.ID)
+	}
+
+	frames, err := readTrackFrames(file, container, track)
+	if err != nil {
+		return err
+	}
+
+	var sub bytes.Buffer
+	var idx strings.Builder
+	idx.WriteString(strings.TrimRight(string(track.CodecPrivate), "\x00\r\n"))
+	fmt.Fprintf(&idx, "\n\nid: %s, index: 0\n", vobSubLanguageID(track.Language))
+	for _, frame := range frames {
+		fmt.Fprintf(&idx, "timestamp: %s, filepos: %09x\n", formatVobSubTimestamp(frame.timestamp), sub.Len())
+		writeVobSubPacket(&sub, frame)
+	}
+
+	subPath := strings.TrimSuffix(idxPath, filepath.Ext(idxPath)) + ".sub"
+	if err := os.WriteFile(subPath, sub.Bytes(), 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(idxPath, []byte(idx.String()), 0o644)
+}
+
+func vobSubLanguageID(language string) string {
+	language = normalizeLanguageTag(language)
+	if id, ok := vobSubLanguageIDs[language]; ok {
+		return id
+	}
+	if language == "" {
+		return undeterminedLanguage
+	}
+	return language
+}
+
+func formatVobSubTimestamp(timestamp time.Duration) string {
+	if timestamp < 0 {
+		timestamp = 0
+	}
+	milliseconds := timestamp.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d:%03d", milliseconds/3600000, milliseconds/60000%60, milliseconds/1000%60, milliseconds%1000)
+}
+
+// writeVobSubPacket splits one subtitle packet into 2048-byte sectors, each a
+// pack header plus a private stream 1 PES packet. The first PES packet
+// carries the presentation timestamp.
+func writeVobSubPacket(w *bytes.Buffer, frame trackFrame) {
+	data := frame.data
+	for first := true; first || len(data) > 0; first = false {
+		headerDataLength := 0
+		ptsFlag := byte(0x00)
+		if first {
+			headerDataLength = 5
+			ptsFlag = 0x80
+		}
+
+		capacity := vobSubSectorSize - len(vobSubPackHeader) - 9 - headerDataLength - 1
+		chunk := min(len(data), capacity)
+		padding := capacity - chunk
+		stuffing := 0
+		if padding > 0 && padding < 6 {
+			// Too small for a padding packet; stuff the PES header instead.
+			stuffing = padding
+			padding = 0
+		}
+
+		pesLength := 3 + headerDataLength + stuffing + 1 + chunk
+		w.Write(vobSubPackHeader)
+		w.Write([]byte{0x00, 0x00, 0x01, 0xBD, byte(pesLength >> 8), byte(pesLength), 0x81, ptsFlag, byte(headerDataLength + stuffing)})
+		if first {
+			w.Write(encodePTS(uint64(max(frame.timestamp, 0).Microseconds()) * 9 / 100))
+		}
+		w.Write(bytes.Repeat([]byte{0xFF}, stuffing))
+		w.WriteByte(0x20)
+		w.Write(data[:chunk])
+		data = data[chunk:]
+
+		if padding > 0 {
+			length := padding - 6
+			w.Write([]byte{0x00, 0x00, 0x01, 0xBE, byte(length >> 8), byte(length)})
+			w.Write(bytes.Repeat([]byte{0xFF}, length))
+		}
+	}
+}
+
+func encodePTS(pts uint64) []byte {
+	return []byte{
+		0x21 | byte(pts>>29)&0x0E,
+		byte(pts >> 22),
+		byte(pts>>14) | 0x01,
+		byte(pts >> 7),
+		byte(pts<<1) | 0x01,
+	}
+}
+
+// readTrackFrames returns the frames of track from all clusters, with
+// timestamps and decompressed payloads.
+func readTrackFrames(r io.ReaderAt, container *Container, track Track) ([]trackFrame, error) {
+	if track.compression != nil && track.compression.encrypted {
+		return nil, fmt.Errorf("track %d is encrypted", track.Number)
+	}
+
+	scale := container.Info.TimestampScale
+	var frames []trackFrame
+	offset := container.segment.DataOffset
+	for offset < container.segment.End() {
+		element, err := readElementHeader(r, offset, container.segment.End())
+		if err != nil {
+			return nil, err
+		}
+		offset = element.End()
+		if element.ID != clusterID {
+			continue
+		}
+
+		var clusterTimestamp uint64
+		readBlock := func(block ebmlElement) error {
+			relative, data, ok, err := readBlockFrame(r, block, track.Number)
+			if err != nil || !ok {
+				return err
+			}
+			data, err = track.compression.decompress(data)
+			if err != nil {
+				return err
+			}
+			timestamp := time.Duration((int64(clusterTimestamp) + int64(relative)) * int64(scale))
+			frames = append(frames, trackFrame{timestamp: timestamp, data: data})
+			return nil
+		}
+
+		err = forEachChild(r, element, func(child ebmlElement) error {
+			var err error
+			switch child.ID {
+			case clusterTimestampID:
+				clusterTimestamp, err = readUnsigned(r, child)
+			case simpleBlockID:
+				err = readBlock(child)
+			case blockGroupID:
+				err = forEachChild(r, child, func(groupChild ebmlElement) error {
+					if groupChild.ID != blockID {
+						return nil
+					}
+					return readBlock(groupChild)
+				})
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return frames, nil
+}
+
+// readBlockFrame returns the relative timestamp and payload of a Block or
+// SimpleBlock belonging to trackNumber; ok is false for other tracks.
+func readBlockFrame(r io.ReaderAt, block ebmlElement, trackNumber uint64) (int16, []byte, bool, error) {
+	header := make([]byte, min(block.Size, 11))
+	if _, err := r.ReadAt(header, block.DataOffset); err != nil {
+		return 0, nil, false, err
+	}
+	number, length, err := decodeVint(header)
+	if err != nil {
+		return 0, nil, false, err
+	}
+	if number != trackNumber {
+		return 0, nil, false, nil
+	}
+	if len(header) < length+3 {
+		return 0, nil, false, io.ErrUnexpectedEOF
+	}
+	if header[length+2]&0x06 != 0 {
+		return 0, nil, false, fmt.Errorf("laced blocks of track %d are not supported", trackNumber)
+	}
+
+	data, err := readElementData(r, block)
+	if err != nil {
+		return 0, nil, false, err
+	}
+	return int16(binary.BigEndian.Uint16(header[length:])), data[length+3:], true, nil
+}
+
+// readBlockTimestamp returns the timestamp of a Block or SimpleBlock
+// relative to its cluster.
+func readBlockTimestamp(r io.ReaderAt, block ebmlElement) (int16, error) {
+	header := make([]byte, min(block.Size, 11))
+	if _, err := r.ReadAt(header, block.DataOffset); err != nil {
+		return 0, err
+	}
+	_, length, err := decodeVint(header)
+	if err != nil {
+		return 0, err
+	}
+	if len(header) < length+2 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return int16(binary.BigEndian.Uint16(header[length:])), nil
+}
+
+func (c *trackCompression) decompress(data []byte) ([]byte, error) {
+	if c == nil {
+		return data, nil
+	}
+
+	switch c.algorithm {
+	case compressionZlib:
+		reader, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(io.LimitReader(reader, maxLeafElementSize))
+	case compressionHeaderStripping:
+		return append(append([]byte{}, c.settings...), data...), nil
+	}
+	return nil, fmt.Errorf("unsupported compression algorithm %d", c.algorithm)
+}
@@ -0,0 +1,162 @@
+package mkv
+
+import (
+	"bytes"
+	"compress/zlib"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func vobSubTestBlock(track byte, relative int16, payload []byte) []byte {
+	return append([]byte{0x80 | track, byte(relative >> 8), byte(relative), 0x80}, payload...)
+}
+
+func TestExtractVobSub(t *testing.T) {
+	firstPacket := bytes.Repeat([]byte{0x11}, 3000)
+	secondPacket := []byte{0x22, 0x22, 0x22}
+
+	tracks := ebmlTestMaster(tracksID,
+		ebmlTestMaster(trackEntryID,
+			ebmlTestUint(trackNumberID, 1),
+			ebmlTestUint(trackTypeID, trackTypeVideo),
+			ebmlTestString(codecIDID, "V_MPEG4/ISO/AVC"),
+		),
+		ebmlTestMaster(trackEntryID,
+			ebmlTestUint(trackNumberID, 2),
+			ebmlTestUint(trackTypeID, trackTypeSubtitle),
+			ebmlTestString(codecIDID, "S_VOBSUB"),
+			ebmlTestString(trackLanguageID, "ger"),
+			ebmlTestBytes(codecPrivateID, []byte("# VobSub index file, v7\nsize: 720x576\npalette: 000000, ffffff\n")),
+			ebmlTestMaster(contentEncodingsID,
+				ebmlTestMaster(contentEncodingID,
+					ebmlTestMaster(contentCompressionID, ebmlTestUint(contentCompAlgoID, compressionZlib)),
+				),
+			),
+		),
+	)
+	cluster := ebmlTestMaster(clusterID,
+		ebmlTestUint(clusterTimestampID, 1000),
+		ebmlTestBytes(simpleBlockID, vobSubTestBlock(1, 0, []byte("video"))),
+		ebmlTestBytes(simpleBlockID, vobSubTestBlock(2, 500, compressedTestPacket(t, firstPacket))),
+	)
+	secondCluster := ebmlTestMaster(clusterID,
+		ebmlTestUint(clusterTimestampID, 3723000),
+		ebmlTestMaster(blockGroupID, ebmlTestBytes(blockID, vobSubTestBlock(2, 4, compressedTestPacket(t, secondPacket)))),
+	)
+	segment := ebmlTestMaster(segmentID, tracks, cluster, secondCluster)
+	data := append(ebmlTestMaster(ebmlHeaderID, ebmlTestString(ebmlDocTypeID, "matroska")), segment...)
+
+	dir := t.TempDir()
+	source := filepath.Join(dir, "movie.mkv")
+	if err := os.WriteFile(source, data, 0o644); err != nil {
+		t.Fatalf("write synthesized mkv failed: %v", err)
+	}
+
+	streams, err := ReadStreams(source)
+	if err != nil {
+		t.Fatalf("ReadStreams() error = %v", err)
+	}
+	if !IsImageSubtitle(streams[1]) || !IsVobSubStream(streams[1]) {
+		t.Fatalf("stream %+v should be an image vobsub stream", streams[1])
+	}
+
+	idxPath := filepath.Join(dir, "movie.idx")
+	if err := ExtractVobSub(source, streams[1], idxPath); err != nil {
+		t.Fatalf("ExtractVobSub() error = %v", err)
+	}
+
+	idx, err := os.ReadFile(idxPath)
+	if err != nil {
+		t.Fatalf("read idx failed: %v", err)
+	}
+	wantIdx := "# VobSub index file, v7\nsize: 720x576\npalette: 000000, ffffff\n\n" +
+		"id: de, index: 0\n" +
+		"timestamp: 00:00:01:500, filepos: 000000000\n" +
+		"timestamp: 01:02:03:004, filepos: 000001000\n"
+	if string(idx) != wantIdx {
+		t.Fatalf("idx = %q, want %q", idx, wantIdx)
+	}
+
+	sub, err := os.ReadFile(filepath.Join(dir, "movie.sub"))
+	if err != nil {
+		t.Fatalf("read sub failed: %v", err)
+	}
+	if len(sub) != 3*vobSubSectorSize {
+		t.Fatalf("sub size = %d, want three sectors", len(sub))
+	}
+	if !bytes.HasPrefix(sub, vobSubPackHeader) || !bytes.Equal(sub[14:18], []byte{0x00, 0x00, 0x01, 0xBD}) {
+		t.Fatalf("sub does not start with a pack header and private stream: % x", sub[:18])
+	}
+	// PTS of 1.5s is 135000 ticks of the 90kHz clock.
+	if !bytes.Equal(sub[23:28], encodePTS(135000)) {
+		t.Fatalf("pts = % x, want % x", sub[23:28], encodePTS(135000))
+	}
+	if !bytes.Contains(sub[vobSubSectorSize:2*vobSubSectorSize], bytes.Repeat([]byte{0x11}, 3000-2019)) {
+		t.Fatal("second sector does not carry the rest of the first packet")
+	}
+	if !bytes.Contains(sub[2*vobSubSectorSize:], secondPacket) {
+		t.Fatal("third sector does not carry the second packet")
+	}
+}
+
+func compressedTestPacket(t *testing.T, packet []byte) []byte {
+	t.Helper()
+
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	if _, err := writer.Write(packet); err != nil {
+		t.Fatalf("compress packet failed: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("compress packet failed: %v", err)
+	}
+	return compressed.Bytes()
+}
+
+func TestExtractVobSub_RejectsOtherCodecs(t *testing.T) {
+	samplePath := filepath.Join("..", "..", "resources", "low_quality_with_subtitles_5s.mkv")
+	if _, err := os.Stat(samplePath); err != nil {
+		t.Skip("skip vobsub test: sample mkv not found")
+	}
+
+	err := ExtractVobSub(samplePath, StreamInfo{ID: "0:2", Type: "Subtitle"}, filepath.Join(t.TempDir(), "out.idx"))
+	if err == nil || !strings.Contains(err.Error(), "not a vobsub stream") {
+		t.Fatalf("ExtractVobSub() error = %v, want not a vobsub stream", err)
+	}
+}
+
+func TestSubtitleStreamExtension(t *testing.T) {
+	tests := []struct {
+		stream StreamInfo
+		want   string
+	}{
+		{StreamInfo{Codec: "subrip", SubtitleFormat: "srt"}, "srt"},
+		{StreamInfo{Codec: "ass", SubtitleFormat: "ass (ssa)"}, "ass"},
+		{StreamInfo{Codec: "webvtt", SubtitleFormat: "webvtt"}, "vtt"},
+		{StreamInfo{Codec: "mov_text", SubtitleFormat: "mov_text"}, "srt"},
+		{StreamInfo{Codec: "hdmv_pgs_subtitle", SubtitleFormat: "pgssub"}, "sup"},
+		{StreamInfo{Codec: "dvd_subtitle", SubtitleFormat: "dvdsub"}, "idx"},
+		{StreamInfo{Codec: "dvb_subtitle", SubtitleFormat: "dvbsub"}, "mks"},
+		{StreamInfo{SubtitleFormat: "pgssub"}, "sup"},
+		{StreamInfo{Codec: "subrip"}, "srt"},
+	}
+	for _, tt := range tests {
+		if got := SubtitleStreamExtension(tt.stream); got != tt.want {
+			t.Fatalf("SubtitleStreamExtension(%+v) = %q, want %q", tt.stream, got, tt.want)
+		}
+	}
+
+	if IsImageSubtitle(StreamInfo{Type: "Subtitle", Codec: "subrip"}) {
+		t.Fatal("subrip is not an image subtitle")
+	}
+	if !IsImageSubtitle(StreamInfo{Type: "Subtitle", SubtitleFormat: "pgssub"}) {
+		t.Fatal("pgssub is an image subtitle")
+	}
+
+	args := BuildExtractFFmpegArgs("movie.mkv", StreamInfo{ID: "0:3", Codec: "dvb_subtitle"}, "out.mks")
+	if !containsArgPair(args, "-f", "matroska") {
+		t.Fatalf("dvb subtitles should be written as matroska: %#v", args)
+	}
+}
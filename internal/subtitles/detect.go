@@ -17,13 +17,76 @@ var (
 )
 
 func IsLikelyChineseEnglishBilingual(file string) bool {
+	text, ok := readSubtitleText(file)
+	return ok && containsChinese(text) && containsEnglish(text)
+}
+
+// DetectSubtitleLanguage guesses the language tag and a track title for a
+// subtitle file. A language suffix in the file name (Show.S01E01.chs.srt)
+// wins over the content; from content only Chinese and Chinese-English
+// subtitles are recognized.
+func DetectSubtitleLanguage(file string) (language, title string) {
+	base := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	if dot := strings.LastIndex(base, "."); dot >= 0 {
+		if suffix, ok := subtitleLanguageSuffixes[strings.ToLower(base[dot+1:])]; ok {
+			language, title = suffix.language, suffix.title
+		}
+	}
+
+	text, ok := readSubtitleText(file)
+	if !ok || !containsChinese(text) {
+		return language, title
+	}
+	if containsEnglish(text) && title == "" {
+		title = "Chinese-English"
+	}
+	if language == "" {
+		language = "zho"
+	}
+	return language, title
+}
+
+var subtitleLanguageSuffixes = map[string]struct {
+	language string
+	title    string
+}{
+	"chs":     {"zho", "简体中文"},
+	"sc":      {"zho", "简体中文"},
+	"zh-hans": {"zho", "简体中文"},
+	"zh-cn":   {"zho", "简体中文"},
+	"gb":      {"zho", "简体中文"},
+	"cht":     {"zho", "繁體中文"},
+	"tc":      {"zho", "繁體中文"},
+	"zh-hant": {"zho", "繁體中文"},
+	"zh-tw":   {"zho", "繁體中文"},
+	"big5":    {"zho", "繁體中文"},
+	"zh":      {"zho", ""},
+	"chi":     {"chi", ""},
+	"zho":     {"zho", ""},
+	"en":      {"eng", ""},
+	"eng":     {"eng", ""},
+	"ja":      {"jpn", ""},
+	"jpn":     {"jpn", ""},
+	"ko":      {"kor", ""},
+	"kor":     {"kor", ""},
+	"fre":     {"fre", ""},
+	"fra":     {"fra", ""},
+	"ger":     {"ger", ""},
+	"deu":     {"deu", ""},
+	"spa":     {"spa", ""},
+	"ita":     {"ita", ""},
+	"por":     {"por", ""},
+	"rus":     {"rus", ""},
+}
+
+func readSubtitleText(file string) (string, bool) {
 	if err := validateSubtitleFileSize(file); err != nil {
-		return false
+		return "", false
 	}
 
 	content, err := os.ReadFile(file)
 	if err != nil || len(content) == 0 {
-		return false
+		return "", false
 	}
 
 	if !utf8.Valid(content) {
@@ -36,8 +99,7 @@ func IsLikelyChineseEnglishBilingual(file string) bool {
 		}
 	}
 
-	text := extractSubtitleText(filepath.Ext(file), string(content))
-	return containsChinese(text) && containsEnglish(text)
+	return extractSubtitleText(filepath.Ext(file), string(content)), true
 }
 
 func extractSubtitleText(ext, content string) string {
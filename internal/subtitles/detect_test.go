@@ -44,3 +44,36 @@ func TestIsLikelyChineseEnglishBilingual_ASSHeaderDoesNotTriggerFalsePositive(t
 		t.Fatalf("expected %s to be detected as non-bilingual", file)
 	}
 }
+
+func TestDetectSubtitleLanguage(t *testing.T) {
+	tmpDir := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		file := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s failed: %v", name, err)
+		}
+		return file
+	}
+	chinese := "1\n00:00:01,000 --> 00:00:02,000\n你好。\n"
+	english := "1\n00:00:01,000 --> 00:00:02,000\nHello.\n"
+	bilingual := "1\n00:00:01,000 --> 00:00:02,000\n你好。\nHello.\n"
+
+	cases := []struct {
+		file, language, title string
+	}{
+		{write("Show.S01E01.chs.srt", chinese), "zho", "简体中文"},
+		{write("Show.S01E01.cht.srt", chinese), "zho", "繁體中文"},
+		{write("Show.S01E01.eng.srt", english), "eng", ""},
+		{write("Show.S01E01.srt", chinese), "zho", ""},
+		{write("Show.S01E02.srt", bilingual), "zho", "Chinese-English"},
+		{write("Show.S01E03.srt", english), "", ""},
+		{write("Show.S01E03.web.srt", english), "", ""},
+	}
+	for _, tc := range cases {
+		language, title := DetectSubtitleLanguage(tc.file)
+		if language != tc.language || title != tc.title {
+			t.Fatalf("DetectSubtitleLanguage(%s) = %q, %q; want %q, %q", filepath.Base(tc.file), language, title, tc.language, tc.title)
+		}
+	}
+}
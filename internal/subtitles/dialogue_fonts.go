@@ -76,6 +76,34 @@ func PruneDialogueFontTagsFromAssFiles() (DialogueFontPruneResult, error) {
 	return result, nil
 }
 
+// PlanDialogueFontTagPrune returns the files that
+// PruneDialogueFontTagsFromAssFiles would rewrite, without writing them.
+func PlanDialogueFontTagPrune() ([]FileChange, error) {
+	files, err := listCurrentDirAssFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []FileChange
+	for _, file := range files {
+		if err := validateSubtitleFileSize(file); err != nil {
+			return nil, err
+		}
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		prunedContent, removedTags := pruneDialogueFontTagsInText(string(content))
+		if removedTags > 0 {
+			changes = append(changes, FileChange{FileName: file, Before: string(content), After: prunedContent})
+		}
+	}
+
+	return changes, nil
+}
+
 func listCurrentDirAssFiles() ([]string, error) {
 	entries, err := os.ReadDir(".")
 	if err != nil {
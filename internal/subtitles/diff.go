@@ -0,0 +1,164 @@
+package subtitles
+
+import (
+	"fmt"
+	"strings"
+)
+
+const diffContextLines = 3
+
+// FileChange is a planned rewrite of FileName. Before and After are UTF-8
+// text; Encoding is the source encoding when the rewrite converts the file
+// to UTF-8.
+type FileChange struct {
+	FileName string
+	Encoding string
+	Before   string
+	After    string
+}
+
+// UnifiedDiff formats change as a unified diff with three lines of context.
+// An encoding-only change has headers naming both encodings and no hunks.
+func (change FileChange) UnifiedDiff() string {
+	fromLabel := "a/" + change.FileName
+	toLabel := "b/" + change.FileName
+	if change.Encoding != "" {
+		fromLabel += " (" + change.Encoding + ")"
+		toLabel += " (UTF-8)"
+	}
+	return unifiedDiff(fromLabel, toLabel, change.Before, change.After)
+}
+
+type diffOp struct {
+	kind byte
+	line string
+}
+
+func unifiedDiff(fromLabel, toLabel, before, after string) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromLabel, toLabel)
+
+	ops := diffLines(splitDiffLines(before), splitDiffLines(after))
+	beforePos := make([]int, len(ops)+1)
+	afterPos := make([]int, len(ops)+1)
+	for i, op := range ops {
+		beforePos[i+1], afterPos[i+1] = beforePos[i], afterPos[i]
+		if op.kind != '+' {
+			beforePos[i+1]++
+		}
+		if op.kind != '-' {
+			afterPos[i+1]++
+		}
+	}
+
+	for i := 0; i < len(ops); {
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+
+		start := max(i-diffContextLines, 0)
+		end := i
+		for {
+			for end < len(ops) && ops[end].kind != ' ' {
+				end++
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next < len(ops) && next-end <= 2*diffContextLines {
+				end = next
+				continue
+			}
+			end = min(end+diffContextLines, len(ops))
+			break
+		}
+
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n",
+			hunkRange(beforePos[start], beforePos[end]-beforePos[start]),
+			hunkRange(afterPos[start], afterPos[end]-afterPos[start]),
+		)
+		for _, op := range ops[start:end] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return out.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func splitDiffLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines compares lines one to one after the common prefix and suffix,
+// which fits the rewrites here: they edit lines but keep their count. When
+// the counts differ, the whole middle is replaced.
+func diffLines(before, after []string) []diffOp {
+	prefix := 0
+	for prefix < len(before) && prefix < len(after) && before[prefix] == after[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(before)-prefix && suffix < len(after)-prefix && before[len(before)-1-suffix] == after[len(after)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	for _, line := range before[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	middleBefore := before[prefix : len(before)-suffix]
+	middleAfter := after[prefix : len(after)-suffix]
+	if len(middleBefore) == len(middleAfter) {
+		for i := 0; i < len(middleBefore); {
+			if middleBefore[i] == middleAfter[i] {
+				ops = append(ops, diffOp{' ', middleBefore[i]})
+				i++
+				continue
+			}
+			end := i
+			for end < len(middleBefore) && middleBefore[end] != middleAfter[end] {
+				end++
+			}
+			ops = appendReplacement(ops, middleBefore[i:end], middleAfter[i:end])
+			i = end
+		}
+	} else {
+		ops = appendReplacement(ops, middleBefore, middleAfter)
+	}
+	for _, line := range before[len(before)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+func appendReplacement(ops []diffOp, removed, added []string) []diffOp {
+	for _, line := range removed {
+		ops = append(ops, diffOp{'-', line})
+	}
+	for _, line := range added {
+		ops = append(ops, diffOp{'+', line})
+	}
+	return ops
+}
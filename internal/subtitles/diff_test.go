@@ -0,0 +1,164 @@
+package subtitles
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestFileChangeUnifiedDiff(t *testing.T) {
+	var beforeLines, afterLines []string
+	for i := 1; i <= 12; i++ {
+		line := "line " + string(rune('a'+i-1))
+		beforeLines = append(beforeLines, line)
+		if i == 2 || i == 11 {
+			line += " changed"
+		}
+		afterLines = append(afterLines, line)
+	}
+	change := FileChange{
+		FileName: "episode.ass",
+		Before:   strings.Join(beforeLines, "\n") + "\n",
+		After:    strings.Join(afterLines, "\n"),
+	}
+
+	want := `--- a/episode.ass
++++ b/episode.ass
+@@ -1,5 +1,5 @@
+ line a
+-line b
++line b changed
+ line c
+ line d
+ line e
+@@ -8,5 +8,5 @@
+ line h
+ line i
+ line j
+-line k
+-line l
++line k changed
++line l
+\ No newline at end of file
+`
+	if got := change.UnifiedDiff(); got != want {
+		t.Fatalf("UnifiedDiff() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFileChangeUnifiedDiff_MergesCloseHunks(t *testing.T) {
+	change := FileChange{
+		FileName: "a.ass",
+		Before:   "1\n2\n3\n4\n5\n6\n7\n8\n",
+		After:    "1\nTWO\n3\n4\n5\n6\n7\nEIGHT\n",
+	}
+
+	got := change.UnifiedDiff()
+	if strings.Count(got, "@@ ") != 1 || !strings.Contains(got, "@@ -1,8 +1,8 @@\n") {
+		t.Fatalf("UnifiedDiff() = %q, want a single hunk", got)
+	}
+}
+
+func TestFileChangeUnifiedDiff_DifferentLineCounts(t *testing.T) {
+	change := FileChange{FileName: "a.srt", Before: "keep\nold\n", After: "keep\nnew 1\nnew 2\n"}
+
+	want := "--- a/a.srt\n+++ b/a.srt\n@@ -1,2 +1,3 @@\n keep\n-old\n+new 1\n+new 2\n"
+	if got := change.UnifiedDiff(); got != want {
+		t.Fatalf("UnifiedDiff() = %q, want %q", got, want)
+	}
+}
+
+func TestFileChangeUnifiedDiff_EncodingOnly(t *testing.T) {
+	change := FileChange{FileName: "a.srt", Encoding: "GBK", Before: "你好\n", After: "你好\n"}
+
+	want := "--- a/a.srt (GBK)\n+++ b/a.srt (UTF-8)\n"
+	if got := change.UnifiedDiff(); got != want {
+		t.Fatalf("UnifiedDiff() = %q, want %q", got, want)
+	}
+}
+
+func TestPlanDialogueFontTagPrune_DoesNotWrite(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd failed: %v", err)
+	}
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(originalDir)
+	})
+
+	content := "Dialogue: 0,0:00:00.00,0:00:01.00,Default,,0,0,0,,{\\fnArial}Hello\n"
+	if err := os.WriteFile("a.ass", []byte(content), 0o644); err != nil {
+		t.Fatalf("write a.ass failed: %v", err)
+	}
+	if err := os.WriteFile("b.ass", []byte("Dialogue: 0,0:00:00.00,0:00:01.00,Default,,0,0,0,,Plain\n"), 0o644); err != nil {
+		t.Fatalf("write b.ass failed: %v", err)
+	}
+
+	changes, err := PlanDialogueFontTagPrune()
+	if err != nil {
+		t.Fatalf("PlanDialogueFontTagPrune() error = %v", err)
+	}
+	if len(changes) != 1 || changes[0].FileName != "a.ass" || changes[0].Before != content {
+		t.Fatalf("changes = %+v, want a.ass only", changes)
+	}
+	if !strings.HasSuffix(changes[0].After, ",,{}Hello\n") {
+		t.Fatalf("After = %q, want pruned text", changes[0].After)
+	}
+	if data, err := os.ReadFile("a.ass"); err != nil || string(data) != content {
+		t.Fatalf("a.ass was modified: %q, %v", data, err)
+	}
+}
+
+func TestPlanCurrentDirSubtitleFilesUTF8Reset(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd failed: %v", err)
+	}
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(originalDir)
+	})
+
+	gbk, err := encodeTextByCharset("你好，世界\n", "GBK")
+	if err != nil {
+		t.Fatalf("encodeTextByCharset() error = %v", err)
+	}
+	if err := os.WriteFile("a.srt", gbk, 0o644); err != nil {
+		t.Fatalf("write a.srt failed: %v", err)
+	}
+	originalDetector := detectTextEncoding
+	detectTextEncoding = func(content []byte) (string, error) {
+		if utf8.Valid(content) {
+			return "UTF-8", nil
+		}
+		return "GBK", nil
+	}
+	t.Cleanup(func() {
+		detectTextEncoding = originalDetector
+	})
+	if err := os.WriteFile("b.srt", []byte("1\n00:00:00,000 --> 00:00:01,000\nhello\n"), 0o644); err != nil {
+		t.Fatalf("write b.srt failed: %v", err)
+	}
+
+	changes, err := PlanCurrentDirSubtitleFilesUTF8Reset()
+	if err != nil {
+		t.Fatalf("PlanCurrentDirSubtitleFilesUTF8Reset() error = %v", err)
+	}
+	if len(changes) != 1 || changes[0].FileName != "a.srt" || changes[0].Encoding != "GBK" {
+		t.Fatalf("changes = %+v, want a.srt with its encoding", changes)
+	}
+	if !strings.Contains(changes[0].After, "你好，世界") {
+		t.Fatalf("After = %q, want decoded text", changes[0].After)
+	}
+	if data, err := os.ReadFile("a.srt"); err != nil || string(data) != string(gbk) {
+		t.Fatalf("a.srt was modified: %v", err)
+	}
+}
@@ -99,6 +99,35 @@ func detectFileEncoding(file string) string {
 	return strings.ToUpper(encoding)
 }
 
+// SubtitleCharacterEncoding returns the encoding of a non-UTF-8 subtitle
+// file under the name ffmpeg's -sub_charenc expects, or "" for UTF-8 files.
+func SubtitleCharacterEncoding(file string) (string, error) {
+	if err := validateSubtitleFileSize(file); err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	if utf8.Valid(content) {
+		return "", nil
+	}
+
+	encoding := normalizeEncoding(detectFileEncoding(file))
+	if encoding == UnknownEncoding {
+		return "", fmt.Errorf("cannot detect the encoding of %s", file)
+	}
+
+	switch encoding {
+	case "GB-18030":
+		return "GB18030", nil
+	case "ANSI":
+		return "WINDOWS-1252", nil
+	}
+	return encoding, nil
+}
+
 func defaultDetectTextEncoding(content []byte) (string, error) {
 	detector := chardet.NewTextDetector()
 	result, err := detector.DetectBest(content)
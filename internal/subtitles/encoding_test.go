@@ -138,3 +138,40 @@ func TestEnsureCurrentDirAssFilesUTF8_AllowsUTF8(t *testing.T) {
 		t.Fatalf("EnsureCurrentDirAssFilesUTF8() error = %v", err)
 	}
 }
+
+func TestSubtitleCharacterEncoding(t *testing.T) {
+	tmpDir := t.TempDir()
+	utf8File := tmpDir + "/utf8.srt"
+	gbFile := tmpDir + "/gb.srt"
+	if err := os.WriteFile(utf8File, []byte("1\n00:00:00,000 --> 00:00:01,000\n你好\n"), 0o644); err != nil {
+		t.Fatalf("write utf8 file failed: %v", err)
+	}
+	if err := os.WriteFile(gbFile, []byte{0xc4, 0xe3, 0xba, 0xc3}, 0o644); err != nil {
+		t.Fatalf("write gb file failed: %v", err)
+	}
+
+	originalDetector := detectTextEncoding
+	t.Cleanup(func() {
+		detectTextEncoding = originalDetector
+	})
+	detectTextEncoding = func(content []byte) (string, error) {
+		return "GB-18030", nil
+	}
+
+	encoding, err := SubtitleCharacterEncoding(utf8File)
+	if err != nil || encoding != "" {
+		t.Fatalf("SubtitleCharacterEncoding(utf8) = %q, %v, want empty", encoding, err)
+	}
+
+	encoding, err = SubtitleCharacterEncoding(gbFile)
+	if err != nil || encoding != "GB18030" {
+		t.Fatalf("SubtitleCharacterEncoding(gb) = %q, %v, want GB18030", encoding, err)
+	}
+
+	detectTextEncoding = func(content []byte) (string, error) {
+		return "", errors.New("no match")
+	}
+	if _, err := SubtitleCharacterEncoding(gbFile); err == nil || !strings.Contains(err.Error(), "cannot detect the encoding") {
+		t.Fatalf("SubtitleCharacterEncoding(unknown) error = %v", err)
+	}
+}
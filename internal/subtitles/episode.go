@@ -0,0 +1,216 @@
+package subtitles
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// EpisodeKey is the canonical form of the episode a file name refers to.
+// Absolute keys come from names without a season, such as anime numbering;
+// date keys come from daily shows and only match the same date.
+type EpisodeKey struct {
+	Season      int
+	Episode     int
+	LastEpisode int
+	Absolute    bool
+	Date        string
+}
+
+// String renders key for display and for the {episode} placeholder of
+// rename templates. It is not meant to be parsed again: an absolute key
+// renders as E05, which no default matcher reads.
+func (key EpisodeKey) String() string {
+	if key.Date != "" {
+		return key.Date
+	}
+
+	tag := fmt.Sprintf("E%02d", key.Episode)
+	if !key.Absolute {
+		tag = fmt.Sprintf("S%02d", key.Season) + tag
+	}
+	if key.LastEpisode > key.Episode {
+		tag += fmt.Sprintf("-E%02d", key.LastEpisode)
+	}
+	return tag
+}
+
+// Matches reports whether key and other can be the same episode: the same
+// date, or overlapping episode ranges in the same season. An absolute key
+// matches an episode of any season.
+func (key EpisodeKey) Matches(other EpisodeKey) bool {
+	if key.Date != "" || other.Date != "" {
+		return key.Date == other.Date
+	}
+	if !key.Absolute && !other.Absolute && key.Season != other.Season {
+		return false
+	}
+	return key.Episode <= other.lastEpisode() && other.Episode <= key.lastEpisode()
+}
+
+func (key EpisodeKey) lastEpisode() int {
+	return max(key.Episode, key.LastEpisode)
+}
+
+// EpisodeMatcher turns the names matched by its pattern into episode keys.
+type EpisodeMatcher struct {
+	Name    string
+	pattern *regexp.Regexp
+	parse   func(groups map[string]string) (EpisodeKey, bool)
+}
+
+// EpisodeMatchers are tried in order; the first one that matches a name
+// decides its key.
+type EpisodeMatchers []EpisodeMatcher
+
+var defaultEpisodeMatchers = EpisodeMatchers{
+	{
+		Name:    "SxxEyy",
+		pattern: regexp.MustCompile(`(?i)(?:^|[^a-z0-9])S(?P<season>\d{1,3})[ ._-]?E(?P<episode>\d{1,4})(?P<more>(?:[-_]?E\d{1,4})*)(?:[^0-9]|$)`),
+		parse:   parseSeasonEpisode,
+	},
+	{
+		Name:    "NxNN",
+		pattern: regexp.MustCompile(`(?i)(?:^|[^a-z0-9])(?P<season>\d{1,2})x(?P<episode>\d{2,3})(?:[^0-9]|$)`),
+		parse:   parseSeasonEpisode,
+	},
+	{
+		Name:    "date",
+		pattern: regexp.MustCompile(`(?:^|[^0-9])(?P<year>(?:19|20)\d{2})[ ._-](?P<month>\d{2})[ ._-](?P<day>\d{2})(?:[^0-9]|$)`),
+		parse:   parseDateEpisode,
+	},
+	{
+		Name:    "EPxx",
+		pattern: regexp.MustCompile(`(?i)(?:^|[^a-z0-9])(?:EP|Episode)[ ._-]?(?P<episode>\d{1,4})(?:[^0-9]|$)`),
+		parse:   parseAbsoluteEpisode,
+	},
+	{
+		Name:    "第xx集",
+		pattern: regexp.MustCompile(`第\s*(?P<episode>\d{1,4})\s*[集话話]`),
+		parse:   parseAbsoluteEpisode,
+	},
+	{
+		Name:    "anime",
+		pattern: regexp.MustCompile(`(?i)(?:\[(?P<episode>\d{1,4})(?:v\d)?\]|\s-\s(?P<dashed>\d{1,4})(?:v\d)?(?:[\s.\[(]|$))`),
+		parse:   parseAnimeEpisode,
+	},
+}
+
+var moreEpisodesPattern = regexp.MustCompile(`(?i)E(\d{1,4})`)
+
+func DefaultEpisodeMatchers() EpisodeMatchers {
+	return append(EpisodeMatchers(nil), defaultEpisodeMatchers...)
+}
+
+// NewPatternEpisodeMatcher builds a matcher from a user regular expression,
+// matched case-insensitively. It must capture an episode named group, and
+// may capture season and last (the end of a multi-episode range); without a
+// season the key is absolute.
+func NewPatternEpisodeMatcher(pattern string) (EpisodeMatcher, error) {
+	compiled, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return EpisodeMatcher{}, fmt.Errorf("invalid episode pattern %q: %w", pattern, err)
+	}
+	if compiled.SubexpIndex("episode") < 0 {
+		return EpisodeMatcher{}, fmt.Errorf("episode pattern %q has no (?P<episode>...) group", pattern)
+	}
+
+	return EpisodeMatcher{
+		Name:    pattern,
+		pattern: compiled,
+		parse: func(groups map[string]string) (EpisodeKey, bool) {
+			if groups["season"] == "" {
+				return parseAbsoluteEpisode(groups)
+			}
+			return parseSeasonEpisode(groups)
+		},
+	}, nil
+}
+
+// WithPatterns returns matchers with the user patterns tried first.
+func (matchers EpisodeMatchers) WithPatterns(patterns []string) (EpisodeMatchers, error) {
+	var combined EpisodeMatchers
+	for _, pattern := range patterns {
+		matcher, err := NewPatternEpisodeMatcher(pattern)
+		if err != nil {
+			return nil, err
+		}
+		combined = append(combined, matcher)
+	}
+	return append(combined, matchers...), nil
+}
+
+// Match returns the episode key of fileName, ignoring its extension.
+func (matchers EpisodeMatchers) Match(fileName string) (EpisodeKey, bool) {
+	name := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	for _, matcher := range matchers {
+		for _, match := range matcher.pattern.FindAllStringSubmatch(name, -1) {
+			groups := map[string]string{}
+			for i, group := range matcher.pattern.SubexpNames() {
+				if group != "" && match[i] != "" {
+					groups[group] = match[i]
+				}
+			}
+			if key, ok := matcher.parse(groups); ok {
+				return key, true
+			}
+		}
+	}
+	return EpisodeKey{}, false
+}
+
+func parseSeasonEpisode(groups map[string]string) (EpisodeKey, bool) {
+	season, err := strconv.Atoi(groups["season"])
+	if err != nil {
+		return EpisodeKey{}, false
+	}
+	key, ok := parseAbsoluteEpisode(groups)
+	if !ok {
+		return EpisodeKey{}, false
+	}
+
+	key.Absolute = false
+	key.Season = season
+	for _, more := range moreEpisodesPattern.FindAllStringSubmatch(groups["more"], -1) {
+		if last, err := strconv.Atoi(more[1]); err == nil && last > key.LastEpisode {
+			key.LastEpisode = last
+		}
+	}
+	return key, true
+}
+
+func parseAbsoluteEpisode(groups map[string]string) (EpisodeKey, bool) {
+	episode, err := strconv.Atoi(groups["episode"])
+	if err != nil {
+		return EpisodeKey{}, false
+	}
+
+	key := EpisodeKey{Episode: episode, LastEpisode: episode, Absolute: true}
+	if last, err := strconv.Atoi(groups["last"]); err == nil && last > episode {
+		key.LastEpisode = last
+	}
+	return key, true
+}
+
+func parseDateEpisode(groups map[string]string) (EpisodeKey, bool) {
+	month, _ := strconv.Atoi(groups["month"])
+	day, _ := strconv.Atoi(groups["day"])
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return EpisodeKey{}, false
+	}
+	return EpisodeKey{Date: groups["year"] + "-" + groups["month"] + "-" + groups["day"]}, true
+}
+
+// parseAnimeEpisode reads [05] and " - 05 " numbering. Four digit numbers
+// that look like years are left to other matchers.
+func parseAnimeEpisode(groups map[string]string) (EpisodeKey, bool) {
+	if groups["episode"] == "" {
+		groups["episode"] = groups["dashed"]
+	}
+	if episode := groups["episode"]; len(episode) == 4 && (strings.HasPrefix(episode, "19") || strings.HasPrefix(episode, "20")) {
+		return EpisodeKey{}, false
+	}
+	return parseAbsoluteEpisode(groups)
+}
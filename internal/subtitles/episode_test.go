@@ -0,0 +1,125 @@
+package subtitles
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDefaultEpisodeMatchers_Match(t *testing.T) {
+	cases := map[string]string{
+		"Show.S01E02.srt":                       "S01E02",
+		"show.s01e02.720p.srt":                  "S01E02",
+		"Show.S01E102.mkv":                      "S01E102",
+		"Show.S01E01E02.mkv":                    "S01E01-E02",
+		"Show.S01E01-E02.srt":                   "S01E01-E02",
+		"Show 1x02 Title.srt":                   "S01E02",
+		"Show.EP05.srt":                         "E05",
+		"Show Episode 5.mkv":                    "E05",
+		"节目 第05集.srt":                           "E05",
+		"[Group] Anime - 05 [1080p].ass":        "E05",
+		"[Group][Anime][05][1080p].mkv":         "E05",
+		"The.Daily.Show.2024.03.15.720p.mkv":    "2024-03-15",
+		"Show.2024.S02E03.mkv":                  "S02E03",
+		"[Group] Anime [2024] - 12 [1080p].mkv": "E12",
+		"Movie.1920x1080.mkv":                   "",
+		"Movie.2019.mkv":                        "",
+		"Show.2024.13.40.srt":                   "",
+	}
+	for name, want := range cases {
+		key, ok := DefaultEpisodeMatchers().Match(name)
+		got := ""
+		if ok {
+			got = key.String()
+		}
+		if got != want {
+			t.Fatalf("Match(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestEpisodeKey_Matches(t *testing.T) {
+	matchers := DefaultEpisodeMatchers()
+	cases := []struct {
+		subtitle, video string
+		want            bool
+	}{
+		{"show.s01e02.srt", "Show.S01E02.mkv", true},
+		{"Show 1x02.srt", "Show.S01E02.mkv", true},
+		{"Show.S01E01.srt", "Show.S01E01E02.mkv", true},
+		{"Show.S01E03.srt", "Show.S01E01E02.mkv", false},
+		{"Show.S02E02.srt", "Show.S01E02.mkv", false},
+		{"第02集.srt", "Show.S01E02.mkv", true},
+		{"Show.2024.03.15.srt", "Show.2024-03-15.mkv", true},
+		{"Show.2024.03.15.srt", "Show.2024.03.16.mkv", false},
+	}
+	for _, tc := range cases {
+		subtitleKey, ok := matchers.Match(tc.subtitle)
+		if !ok {
+			t.Fatalf("Match(%q) failed", tc.subtitle)
+		}
+		videoKey, ok := matchers.Match(tc.video)
+		if !ok {
+			t.Fatalf("Match(%q) failed", tc.video)
+		}
+		if got := subtitleKey.Matches(videoKey); got != tc.want {
+			t.Fatalf("%q matches %q = %v, want %v", tc.subtitle, tc.video, got, tc.want)
+		}
+	}
+}
+
+func TestEpisodeMatchers_WithPatterns(t *testing.T) {
+	matchers, err := DefaultEpisodeMatchers().WithPatterns([]string{`Part[ .](?P<episode>\d+)`, `Vol(?P<season>\d)-(?P<episode>\d+)`})
+	if err != nil {
+		t.Fatalf("WithPatterns() error = %v", err)
+	}
+
+	cases := map[string]string{
+		"Doc.part.3.srt":  "E03",
+		"Show Vol2-7.srt": "S02E07",
+		"Show.S01E02.srt": "S01E02",
+	}
+	for name, want := range cases {
+		key, ok := matchers.Match(name)
+		if !ok || key.String() != want {
+			t.Fatalf("Match(%q) = %q, %v; want %q", name, key.String(), ok, want)
+		}
+	}
+
+	if _, err := DefaultEpisodeMatchers().WithPatterns([]string{`E(\d+)`}); err == nil || !strings.Contains(err.Error(), "(?P<episode>...)") {
+		t.Fatalf("WithPatterns() error = %v, want missing episode group", err)
+	}
+	if _, err := DefaultEpisodeMatchers().WithPatterns([]string{`(?P<episode>\d+`}); err == nil || !strings.Contains(err.Error(), "invalid episode pattern") {
+		t.Fatalf("WithPatterns() error = %v, want invalid pattern", err)
+	}
+}
+
+func TestFindVideoFileByEpisode(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd failed: %v", err)
+	}
+
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(originalDir)
+	})
+
+	writeTestFile(t, "[Group] Anime - 05 [1080p].mkv")
+	writeTestFile(t, "[Group] Anime - 06 [1080p].mkv")
+
+	key, ok := DefaultEpisodeMatchers().Match("Anime.EP05.ass")
+	if !ok {
+		t.Fatalf("Match() failed")
+	}
+	got, err := FindVideoFileByEpisode(key, DefaultEpisodeMatchers())
+	if err != nil {
+		t.Fatalf("FindVideoFileByEpisode() error = %v", err)
+	}
+	if got != "[Group] Anime - 05 [1080p].mkv" {
+		t.Fatalf("FindVideoFileByEpisode() = %q, want episode 5", got)
+	}
+}
@@ -3,9 +3,25 @@ package subtitles
 import (
 	"os"
 	"path/filepath"
+
+	"github.com/cuimingda/subs-cli/internal/journal"
 )
 
+// activeJournal records the original content of every rewritten file when
+// set, so that `subs undo` can restore it.
+var activeJournal *journal.Journal
+
+func SetJournal(j *journal.Journal) {
+	activeJournal = j
+}
+
 func writeFilePreserveMode(path string, data []byte) error {
+	if activeJournal != nil {
+		if err := activeJournal.RecordWrite(path, data); err != nil {
+			return err
+		}
+	}
+
 	perm := os.FileMode(0o644)
 	if info, err := os.Stat(path); err == nil {
 		perm = info.Mode().Perm()
@@ -0,0 +1,256 @@
+package subtitles
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SubtitleMarkers are the language and flags a subtitle file is named with.
+// Language is a BCP 47 tag such as en or zh-Hans.
+type SubtitleMarkers struct {
+	Language string
+	Forced   bool
+	SDH      bool
+}
+
+type NamingScheme string
+
+const (
+	SchemePlex     NamingScheme = "plex"
+	SchemeJellyfin NamingScheme = "jellyfin"
+	SchemeKodi     NamingScheme = "kodi"
+)
+
+type subtitleLanguage struct {
+	tag     string
+	part2B  string
+	name    string
+	aliases []string
+}
+
+var subtitleLanguages = []subtitleLanguage{
+	{tag: "zh-Hans", part2B: "chi", name: "Chinese (Simplified)", aliases: []string{"chs", "sc", "gb", "zh-hans", "zh-cn", "zh-sg", "简体", "简中", "简", "中英", "简英"}},
+	{tag: "zh-Hant", part2B: "chi", name: "Chinese (Traditional)", aliases: []string{"cht", "tc", "big5", "zh-hant", "zh-tw", "zh-hk", "繁体", "繁體", "繁中", "繁", "繁英"}},
+	{tag: "zh", part2B: "chi", name: "Chinese", aliases: []string{"zh", "chi", "zho", "chinese", "中文"}},
+	{tag: "en", part2B: "eng", name: "English", aliases: []string{"en", "eng", "english", "英文"}},
+	{tag: "ja", part2B: "jpn", name: "Japanese", aliases: []string{"ja", "jp", "jpn", "japanese", "日文", "日语"}},
+	{tag: "ko", part2B: "kor", name: "Korean", aliases: []string{"ko", "kor", "korean", "韩文", "韩语"}},
+	{tag: "fr", part2B: "fre", name: "French", aliases: []string{"fr", "fre", "fra", "french"}},
+	{tag: "de", part2B: "ger", name: "German", aliases: []string{"de", "ger", "deu", "german"}},
+	{tag: "es", part2B: "spa", name: "Spanish", aliases: []string{"es", "spa", "spanish"}},
+	{tag: "it", part2B: "ita", name: "Italian", aliases: []string{"it", "ita", "italian"}},
+	{tag: "pt", part2B: "por", name: "Portuguese", aliases: []string{"pt", "por", "portuguese"}},
+	{tag: "ru", part2B: "rus", name: "Russian", aliases: []string{"ru", "rus", "russian"}},
+}
+
+var (
+	forcedMarkers = map[string]bool{"forced": true, "foreign": true}
+	sdhMarkers    = map[string]bool{"sdh": true, "hi": true, "cc": true}
+	// simplifiedOnly and traditionalOnly are common characters written
+	// differently in the two scripts.
+	simplifiedOnly  = "这们说为会来时个对没么还后发过让现问开见经头电话间东车听气"
+	traditionalOnly = "這們說為會來時個對沒麼還後發過讓現問開見經頭電話間東車聽氣"
+)
+
+func ParseNamingScheme(value string) (NamingScheme, error) {
+	switch scheme := NamingScheme(strings.ToLower(value)); scheme {
+	case SchemePlex, SchemeJellyfin, SchemeKodi:
+		return scheme, nil
+	}
+	return "", fmt.Errorf("invalid naming scheme: %s (want plex, jellyfin or kodi)", value)
+}
+
+// DetectSubtitleMarkers reads the language, forced and SDH markers at the end
+// of the subtitle name (Show.S01E02.chs.forced.srt). When the name has no
+// language, it is guessed from the script of the text, and SDH from sound
+// descriptions such as [door slams].
+func DetectSubtitleMarkers(subtitleFile string) SubtitleMarkers {
+	markers := detectNameMarkers(subtitleFile)
+	if markers.Language != "" && markers.SDH {
+		return markers
+	}
+
+	if validateSubtitleFileSize(subtitleFile) != nil {
+		return markers
+	}
+	content, err := os.ReadFile(subtitleFile)
+	if err != nil || !utf8.Valid(content) {
+		return markers
+	}
+	text := string(content)
+	if markers.Language == "" {
+		markers.Language = detectTextLanguage(text)
+	}
+	if !markers.SDH {
+		markers.SDH = looksLikeSDH(text)
+	}
+	return markers
+}
+
+// detectNameMarkers reads the run of marker words before the extension. A
+// bilingual name such as chs.eng keeps the first language.
+func detectNameMarkers(subtitleFile string) SubtitleMarkers {
+	name := strings.TrimSuffix(filepath.Base(subtitleFile), filepath.Ext(subtitleFile))
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '.' || r == '_' || r == ' ' || r == '&' || r == '+' || r == '[' || r == ']'
+	})
+
+	markers := SubtitleMarkers{}
+	for i := len(parts) - 1; i > 0; i-- {
+		part := strings.ToLower(parts[i])
+		switch {
+		case forcedMarkers[part]:
+			markers.Forced = true
+		case sdhMarkers[part]:
+			markers.SDH = true
+		default:
+			language, ok := lookupSubtitleLanguage(part)
+			if !ok {
+				return markers
+			}
+			markers.Language = language.tag
+		}
+	}
+	return markers
+}
+
+func lookupSubtitleLanguage(value string) (subtitleLanguage, bool) {
+	value = strings.ToLower(value)
+	for _, language := range subtitleLanguages {
+		if strings.ToLower(language.tag) == value {
+			return language, true
+		}
+		for _, alias := range language.aliases {
+			if alias == value {
+				return language, true
+			}
+		}
+	}
+	return subtitleLanguage{}, false
+}
+
+// detectTextLanguage guesses CJK languages from their scripts. Latin text is
+// left undetected since the script does not tell the language.
+func detectTextLanguage(text string) string {
+	var han, kana, hangul, simplified, traditional int
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Han, r):
+			han++
+			if strings.ContainsRune(simplifiedOnly, r) {
+				simplified++
+			} else if strings.ContainsRune(traditionalOnly, r) {
+				traditional++
+			}
+		}
+	}
+
+	switch {
+	case kana > 0 && kana*5 >= han:
+		return "ja"
+	case hangul > han:
+		return "ko"
+	case han == 0:
+		return ""
+	case simplified > traditional:
+		return "zh-Hans"
+	case traditional > simplified:
+		return "zh-Hant"
+	}
+	return "zh"
+}
+
+// looksLikeSDH reports whether at least 5% of the text lines, and at least
+// three, are sound descriptions in brackets or music notes.
+func looksLikeSDH(text string) bool {
+	textLines, soundLines := 0, 0
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || srtIndexLineRE.MatchString(line) || srtTimingLineRE.MatchString(line) {
+			continue
+		}
+		if strings.HasPrefix(line, "Dialogue:") {
+			if fields := strings.SplitN(line, ",", 10); len(fields) == 10 {
+				line = fields[9]
+			}
+		}
+		line = strings.TrimSpace(htmlTagRE.ReplaceAllString(assTagRE.ReplaceAllString(line, ""), ""))
+		if line == "" {
+			continue
+		}
+		textLines++
+		if (strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]")) ||
+			(strings.HasPrefix(line, "(") && strings.HasSuffix(line, ")")) ||
+			strings.HasPrefix(line, "♪") {
+			soundLines++
+		}
+	}
+	return soundLines >= 3 && soundLines*20 >= textLines
+}
+
+// SubtitleName returns the name of subtitleFile next to videoFile under the
+// scheme: the video base name, then the language and the sdh and forced
+// flags. Plex uses ISO 639-1 codes, Jellyfin ISO 639-2 codes and Kodi English
+// language names; Chinese scripts stay zh-Hans and zh-Hant except for Kodi.
+func (scheme NamingScheme) SubtitleName(videoFile, subtitleFile string, markers SubtitleMarkers) string {
+	parts := []string{strings.TrimSuffix(videoFile, filepath.Ext(videoFile))}
+	if markers.Language != "" {
+		parts = append(parts, scheme.languageCode(markers.Language))
+	}
+	if markers.SDH {
+		parts = append(parts, "sdh")
+	}
+	if markers.Forced {
+		parts = append(parts, "forced")
+	}
+	return strings.Join(parts, ".") + filepath.Ext(subtitleFile)
+}
+
+func (scheme NamingScheme) languageCode(tag string) string {
+	language, ok := lookupSubtitleLanguage(tag)
+	if !ok {
+		return tag
+	}
+	switch scheme {
+	case SchemeJellyfin:
+		if strings.HasPrefix(language.tag, "zh-") {
+			return language.tag
+		}
+		return language.part2B
+	case SchemeKodi:
+		return language.name
+	}
+	return language.tag
+}
+
+// NumberSubtitleName returns name, or name with a number after the video
+// base name (Show.S01E02.2.en.srt) when taken reports it is in use. Names
+// that do not start with the video base name get the number before the
+// extension.
+func NumberSubtitleName(name, videoFile string, taken func(string) bool) string {
+	if !taken(name) {
+		return name
+	}
+
+	dir, base := filepath.Split(name)
+	head := strings.TrimSuffix(base, filepath.Ext(base))
+	videoBase := strings.TrimSuffix(videoFile, filepath.Ext(videoFile))
+	if strings.HasPrefix(base, videoBase) {
+		head = videoBase
+	}
+	rest := strings.TrimPrefix(base, head)
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s%s.%d%s", dir, head, n, rest)
+		if !taken(candidate) {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package subtitles
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectNameMarkers(t *testing.T) {
+	cases := map[string]SubtitleMarkers{
+		"foo.S01E02.zh.srt":         {Language: "zh"},
+		"foo.S01E02.chs.srt":        {Language: "zh-Hans"},
+		"foo.S01E02.cht.srt":        {Language: "zh-Hant"},
+		"Movie.2019.eng.forced.srt": {Language: "en", Forced: true},
+		"Movie.en.sdh.srt":          {Language: "en", SDH: true},
+		"Movie.chs&eng.ass":         {Language: "zh-Hans"},
+		"Movie.简体.srt":              {Language: "zh-Hans"},
+		"Show.S01E02.srt":           {},
+		"Hi.srt":                    {},
+		"Movie.forced.1080p.en.srt": {Language: "en"},
+	}
+	for name, want := range cases {
+		if got := detectNameMarkers(name); got != want {
+			t.Fatalf("detectNameMarkers(%q) = %+v, want %+v", name, got, want)
+		}
+	}
+}
+
+func TestDetectTextLanguage(t *testing.T) {
+	cases := map[string]string{
+		"这是什么？我们走吧。":  "zh-Hans",
+		"這是什麼？我們走吧。":  "zh-Hant",
+		"中文字幕":        "zh",
+		"これは何ですか":     "ja",
+		"안녕하세요":       "ko",
+		"Hello there": "",
+	}
+	for text, want := range cases {
+		if got := detectTextLanguage(text); got != want {
+			t.Fatalf("detectTextLanguage(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestLooksLikeSDH(t *testing.T) {
+	sdh := strings.Join([]string{
+		"1", "00:00:01,000 --> 00:00:02,000", "[DOOR SLAMS]", "",
+		"2", "00:00:03,000 --> 00:00:04,000", "<i>(sighs)</i>", "",
+		"3", "00:00:05,000 --> 00:00:06,000", "♪ soft music ♪", "",
+		"4", "00:00:07,000 --> 00:00:08,000", "Where are you going?", "",
+	}, "\n")
+	if !looksLikeSDH(sdh) {
+		t.Fatalf("looksLikeSDH() = false, want true")
+	}
+	if looksLikeSDH("1\n00:00:01,000 --> 00:00:02,000\n[Hi]\n\n2\n00:00:03,000 --> 00:00:04,000\nHello\n") {
+		t.Fatalf("looksLikeSDH() = true for a single bracketed line")
+	}
+}
+
+func TestNamingScheme_SubtitleName(t *testing.T) {
+	markers := SubtitleMarkers{Language: "zh-Hans", Forced: true}
+	english := SubtitleMarkers{Language: "en", SDH: true}
+	cases := []struct {
+		scheme  NamingScheme
+		markers SubtitleMarkers
+		want    string
+	}{
+		{SchemePlex, markers, "Video.S01E02.zh-Hans.forced.srt"},
+		{SchemeJellyfin, markers, "Video.S01E02.zh-Hans.forced.srt"},
+		{SchemeKodi, markers, "Video.S01E02.Chinese (Simplified).forced.srt"},
+		{SchemePlex, english, "Video.S01E02.en.sdh.srt"},
+		{SchemeJellyfin, english, "Video.S01E02.eng.sdh.srt"},
+		{SchemeKodi, english, "Video.S01E02.English.sdh.srt"},
+		{SchemePlex, SubtitleMarkers{}, "Video.S01E02.srt"},
+	}
+	for _, tc := range cases {
+		if got := tc.scheme.SubtitleName("Video.S01E02.mkv", "sub.srt", tc.markers); got != tc.want {
+			t.Fatalf("%s SubtitleName(%+v) = %q, want %q", tc.scheme, tc.markers, got, tc.want)
+		}
+	}
+
+	if _, err := ParseNamingScheme("emby"); err == nil {
+		t.Fatalf("ParseNamingScheme(emby) error = nil, want error")
+	}
+	if scheme, err := ParseNamingScheme("Kodi"); err != nil || scheme != SchemeKodi {
+		t.Fatalf("ParseNamingScheme(Kodi) = %q, %v", scheme, err)
+	}
+}
+
+func TestNumberSubtitleName(t *testing.T) {
+	taken := map[string]bool{"Video.en.srt": true, "Video.2.en.srt": true}
+	got := NumberSubtitleName("Video.en.srt", "Video.mkv", func(name string) bool { return taken[name] })
+	if got != "Video.3.en.srt" {
+		t.Fatalf("NumberSubtitleName() = %q, want Video.3.en.srt", got)
+	}
+	if got := NumberSubtitleName("Video.fr.srt", "Video.mkv", func(name string) bool { return taken[name] }); got != "Video.fr.srt" {
+		t.Fatalf("NumberSubtitleName() = %q, want the free name kept", got)
+	}
+}
+
+func TestNumberSubtitleName_TemplateNames(t *testing.T) {
+	taken := map[string]bool{
+		filepath.Join("Subs", "Video.en.srt"): true,
+		"custom.srt":                          true,
+	}
+	isTaken := func(name string) bool { return taken[name] }
+
+	if got, want := NumberSubtitleName(filepath.Join("Subs", "Video.en.srt"), "Video.mkv", isTaken), filepath.Join("Subs", "Video.2.en.srt"); got != want {
+		t.Fatalf("NumberSubtitleName() = %q, want %q", got, want)
+	}
+	if got := NumberSubtitleName("custom.srt", "Video.mkv", isTaken); got != "custom.2.srt" {
+		t.Fatalf("NumberSubtitleName() = %q, want custom.2.srt", got)
+	}
+}
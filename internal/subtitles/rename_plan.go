@@ -0,0 +1,265 @@
+package subtitles
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	RenameActionRename        = "rename"
+	RenameActionSame          = "same"
+	RenameActionIgnore        = "ignore"
+	RenameActionNotFound      = "not-found"
+	RenameActionNeedsMinScore = "needs-min-score"
+)
+
+// RenamePlanEntry is one subtitle of a `file rename` plan. Only rename
+// entries are applied; SHA256 is the subtitle content the plan was made for.
+type RenamePlanEntry struct {
+	Action   string  `json:"action"`
+	Subtitle string  `json:"subtitle"`
+	Video    string  `json:"video,omitempty"`
+	NewName  string  `json:"new_name,omitempty"`
+	Score    float64 `json:"score,omitempty"`
+	SHA256   string  `json:"sha256,omitempty"`
+	Note     string  `json:"note,omitempty"`
+}
+
+var renamePlanColumns = []string{"action", "subtitle", "video", "new_name", "score", "sha256", "note"}
+
+var (
+	templateFieldPattern = regexp.MustCompile(`\{([^{}]*)\}`)
+	templatePartsPattern = regexp.MustCompile(`^([^A-Za-z_]*)([A-Za-z_]+)([^A-Za-z_]*)$`)
+	repeatedDotsPattern  = regexp.MustCompile(`\.{2,}`)
+	templateFields       = map[string]bool{
+		"video_base": true, "video_ext": true, "subtitle_base": true, "ext": true,
+		"lang": true, "forced": true, "sdh": true, "episode": true,
+	}
+)
+
+// ValidateSubtitleTemplate checks that template only uses known fields.
+func ValidateSubtitleTemplate(template string) error {
+	for _, match := range templateFieldPattern.FindAllStringSubmatch(template, -1) {
+		parts := templatePartsPattern.FindStringSubmatch(match[1])
+		if parts == nil {
+			return fmt.Errorf("invalid template field %s", match[0])
+		}
+		if !templateFields[parts[2]] {
+			return fmt.Errorf("unknown template field %s", match[0])
+		}
+	}
+	return nil
+}
+
+// RenderSubtitleTemplate fills {field} placeholders with values. Characters
+// around the field name inside the braces, as in {.forced}, are only written
+// when the value is not empty, and repeated dots left by empty fields in the
+// file name are collapsed. The result must stay below the current directory.
+func RenderSubtitleTemplate(template string, values map[string]string) (string, error) {
+	if err := ValidateSubtitleTemplate(template); err != nil {
+		return "", err
+	}
+
+	rendered := templateFieldPattern.ReplaceAllStringFunc(template, func(field string) string {
+		parts := templatePartsPattern.FindStringSubmatch(field[1 : len(field)-1])
+		value := values[parts[2]]
+		if value == "" {
+			return ""
+		}
+		return parts[1] + value + parts[3]
+	})
+	dir, base := filepath.Split(rendered)
+	rendered = dir + repeatedDotsPattern.ReplaceAllString(base, ".")
+
+	if err := validatePlanPath(rendered); err != nil {
+		return "", fmt.Errorf("template %q: %w", template, err)
+	}
+	return filepath.Clean(rendered), nil
+}
+
+func validatePlanPath(path string) error {
+	cleaned := filepath.Clean(path)
+	if path == "" || cleaned == "." || filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%q is not a file below the current directory", path)
+	}
+	return nil
+}
+
+// SubtitleTemplateValues returns the template fields of renaming
+// subtitleFile after videoFile.
+func SubtitleTemplateValues(scheme NamingScheme, videoFile, subtitleFile string, markers SubtitleMarkers, episode string) map[string]string {
+	values := map[string]string{
+		"video_base":    strings.TrimSuffix(videoFile, filepath.Ext(videoFile)),
+		"video_ext":     strings.TrimPrefix(filepath.Ext(videoFile), "."),
+		"subtitle_base": strings.TrimSuffix(subtitleFile, filepath.Ext(subtitleFile)),
+		"ext":           strings.TrimPrefix(filepath.Ext(subtitleFile), "."),
+		"episode":       episode,
+	}
+	if markers.Language != "" {
+		values["lang"] = scheme.languageCode(markers.Language)
+	}
+	if markers.Forced {
+		values["forced"] = "forced"
+	}
+	if markers.SDH {
+		values["sdh"] = "sdh"
+	}
+	return values
+}
+
+// FileSHA256 returns the hex SHA-256 of the content of path.
+func FileSHA256(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// RenamePlanFormat returns csv or json from the extension of path.
+func RenamePlanFormat(path string) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv", ".json":
+		return ext[1:], nil
+	}
+	return "", fmt.Errorf("unsupported plan file: %s (want .csv or .json)", path)
+}
+
+func WriteRenamePlan(w io.Writer, format string, entries []RenamePlanEntry) error {
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if entries == nil {
+			entries = []RenamePlanEntry{}
+		}
+		return encoder.Encode(entries)
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(renamePlanColumns); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		score := ""
+		if entry.Score > 0 {
+			score = strconv.FormatFloat(entry.Score, 'f', 2, 64)
+		}
+		record := []string{entry.Action, entry.Subtitle, entry.Video, entry.NewName, score, entry.SHA256, entry.Note}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func ReadRenamePlan(r io.Reader, format string) ([]RenamePlanEntry, error) {
+	var entries []RenamePlanEntry
+	if format == "json" {
+		if err := json.NewDecoder(r).Decode(&entries); err != nil {
+			return nil, fmt.Errorf("invalid plan: %w", err)
+		}
+	} else {
+		records, err := csv.NewReader(r).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("invalid plan: %w", err)
+		}
+		if len(records) == 0 || strings.Join(records[0], ",") != strings.Join(renamePlanColumns, ",") {
+			return nil, fmt.Errorf("invalid plan: header must be %s", strings.Join(renamePlanColumns, ","))
+		}
+		for i, record := range records[1:] {
+			entry := RenamePlanEntry{
+				Action:   record[0],
+				Subtitle: record[1],
+				Video:    record[2],
+				NewName:  record[3],
+				SHA256:   record[5],
+				Note:     record[6],
+			}
+			if record[4] != "" {
+				score, err := strconv.ParseFloat(record[4], 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid plan: line %d: bad score %q", i+2, record[4])
+				}
+				entry.Score = score
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	for _, entry := range entries {
+		switch entry.Action {
+		case RenameActionRename, RenameActionSame, RenameActionIgnore, RenameActionNotFound, RenameActionNeedsMinScore:
+		default:
+			return nil, fmt.Errorf("invalid plan: unknown action %q for %s", entry.Action, entry.Subtitle)
+		}
+	}
+	return entries, nil
+}
+
+// CheckRenamePlan makes sure every rename of entries can be made as planned:
+// the subtitle is unchanged since the plan was made and the new names are
+// free, distinct and below the current directory. A new name may be one an
+// earlier rename of the plan frees. Nothing is renamed.
+func CheckRenamePlan(entries []RenamePlanEntry) error {
+	var problems []string
+	newNames := map[string]string{}
+	moved := map[string]bool{}
+	exists := func(path string) bool {
+		if present, ok := moved[path]; ok {
+			return present
+		}
+		_, err := os.Lstat(path)
+		return err == nil
+	}
+	for _, entry := range entries {
+		if entry.Action != RenameActionRename {
+			continue
+		}
+		if err := validatePlanPath(entry.Subtitle); err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if err := validatePlanPath(entry.NewName); err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+
+		hash, err := FileSHA256(entry.Subtitle)
+		if err != nil {
+			problems = append(problems, entry.Subtitle+" cannot be read")
+			continue
+		}
+		if entry.SHA256 != "" && hash != entry.SHA256 {
+			problems = append(problems, entry.Subtitle+" changed since the plan was made")
+		}
+
+		newName := filepath.Clean(entry.NewName)
+		if other, ok := newNames[newName]; ok {
+			problems = append(problems, fmt.Sprintf("%s and %s are both renamed to %s", other, entry.Subtitle, newName))
+			continue
+		}
+		newNames[newName] = entry.Subtitle
+		subtitle := filepath.Clean(entry.Subtitle)
+		if exists(newName) && newName != subtitle {
+			problems = append(problems, newName+" already exists")
+		}
+		moved[subtitle] = false
+		moved[newName] = true
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("cannot apply plan: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
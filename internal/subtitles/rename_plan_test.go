@@ -0,0 +1,147 @@
+package subtitles
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenderSubtitleTemplate(t *testing.T) {
+	values := SubtitleTemplateValues(SchemePlex, "Show.S01E02.mkv", "x.S01E02.chs.srt", SubtitleMarkers{Language: "zh-Hans"}, "S01E02")
+	cases := map[string]string{
+		"{video_base}{.lang}{.forced}.{ext}":     "Show.S01E02.zh-Hans.srt",
+		"{video_base}.{lang}.{sdh}.{ext}":        "Show.S01E02.zh-Hans.srt",
+		"Subs/{episode}/{subtitle_base}.{ext}":   filepath.Join("Subs", "S01E02", "x.S01E02.chs.srt"),
+		"{video_base}.{video_ext}.{ext}":         "Show.S01E02.mkv.srt",
+		"Subs/{video_base}{ (lang)}{.sdh}.{ext}": filepath.Join("Subs", "Show.S01E02 (zh-Hans).srt"),
+	}
+	for template, want := range cases {
+		got, err := RenderSubtitleTemplate(template, values)
+		if err != nil {
+			t.Fatalf("RenderSubtitleTemplate(%q) error = %v", template, err)
+		}
+		if got != want {
+			t.Fatalf("RenderSubtitleTemplate(%q) = %q, want %q", template, got, want)
+		}
+	}
+}
+
+func TestRenderSubtitleTemplate_RejectsBadTemplates(t *testing.T) {
+	values := map[string]string{"video_base": "Show", "ext": "srt"}
+	cases := map[string]string{
+		"{video_base}.{language}.{ext}": "unknown template field {language}",
+		"{video_base}.{1}.{ext}":        "invalid template field {1}",
+		"../{video_base}.{ext}":         "not a file below the current directory",
+		"/tmp/{video_base}.{ext}":       "not a file below the current directory",
+		"{lang}":                        "not a file below the current directory",
+	}
+	for template, want := range cases {
+		if _, err := RenderSubtitleTemplate(template, values); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("RenderSubtitleTemplate(%q) error = %v, want %q", template, err, want)
+		}
+	}
+}
+
+func TestRenamePlanRoundTrip(t *testing.T) {
+	plan := []RenamePlanEntry{
+		{Action: RenameActionRename, Subtitle: "a, b.srt", Video: "Show.S01E01.mkv", NewName: "Show.S01E01.en.srt", SHA256: "abc", Note: "Show.S01E01.srt exists"},
+		{Action: RenameActionNeedsMinScore, Subtitle: "movie.srt", Video: "Movie.2019.mkv", Score: 0.75, SHA256: "def"},
+		{Action: RenameActionIgnore, Subtitle: "notes.srt"},
+	}
+	for _, format := range []string{"csv", "json"} {
+		var buf bytes.Buffer
+		if err := WriteRenamePlan(&buf, format, plan); err != nil {
+			t.Fatalf("WriteRenamePlan(%s) error = %v", format, err)
+		}
+		got, err := ReadRenamePlan(&buf, format)
+		if err != nil {
+			t.Fatalf("ReadRenamePlan(%s) error = %v", format, err)
+		}
+		if !reflect.DeepEqual(got, plan) {
+			t.Fatalf("%s round trip = %+v, want %+v", format, got, plan)
+		}
+	}
+}
+
+func TestReadRenamePlan_RejectsUnknownAction(t *testing.T) {
+	input := "action,subtitle,video,new_name,score,sha256,note\ndelete,a.srt,,,,,\n"
+	if _, err := ReadRenamePlan(strings.NewReader(input), "csv"); err == nil || !strings.Contains(err.Error(), `unknown action "delete"`) {
+		t.Fatalf("ReadRenamePlan() error = %v, want unknown action", err)
+	}
+	if _, err := ReadRenamePlan(strings.NewReader("subtitle\na.srt\n"), "csv"); err == nil || !strings.Contains(err.Error(), "header must be") {
+		t.Fatalf("ReadRenamePlan() error = %v, want header error", err)
+	}
+}
+
+func TestCheckRenamePlan(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd failed: %v", err)
+	}
+
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(originalDir)
+	})
+
+	for _, name := range []string{"a.srt", "b.srt", "c.srt", "Taken.srt"} {
+		if err := os.WriteFile(name, []byte(name), 0o644); err != nil {
+			t.Fatalf("write %s failed: %v", name, err)
+		}
+	}
+	hash := func(name string) string {
+		sum, err := FileSHA256(name)
+		if err != nil {
+			t.Fatalf("FileSHA256(%s) error = %v", name, err)
+		}
+		return sum
+	}
+
+	valid := []RenamePlanEntry{
+		{Action: RenameActionRename, Subtitle: "a.srt", NewName: "Subs/A.srt", SHA256: hash("a.srt")},
+		{Action: RenameActionIgnore, Subtitle: "b.srt", NewName: "Taken.srt"},
+	}
+	if err := CheckRenamePlan(valid); err != nil {
+		t.Fatalf("CheckRenamePlan() error = %v", err)
+	}
+
+	chained := []RenamePlanEntry{
+		{Action: RenameActionRename, Subtitle: "Taken.srt", NewName: "Taken.en.srt", SHA256: hash("Taken.srt")},
+		{Action: RenameActionRename, Subtitle: "c.srt", NewName: "Taken.srt", SHA256: hash("c.srt")},
+	}
+	if err := CheckRenamePlan(chained); err != nil {
+		t.Fatalf("CheckRenamePlan() of a chained plan error = %v", err)
+	}
+	if err := CheckRenamePlan([]RenamePlanEntry{chained[1], chained[0]}); err == nil || !strings.Contains(err.Error(), "Taken.srt already exists") {
+		t.Fatalf("CheckRenamePlan() of a reversed chain error = %v, want Taken.srt already exists", err)
+	}
+
+	invalid := []RenamePlanEntry{
+		{Action: RenameActionRename, Subtitle: "a.srt", NewName: "A.srt", SHA256: hash("b.srt")},
+		{Action: RenameActionRename, Subtitle: "b.srt", NewName: "Taken.srt", SHA256: hash("b.srt")},
+		{Action: RenameActionRename, Subtitle: "c.srt", NewName: "A.srt", SHA256: hash("c.srt")},
+		{Action: RenameActionRename, Subtitle: "missing.srt", NewName: "M.srt"},
+		{Action: RenameActionRename, Subtitle: "c.srt", NewName: "../c.srt"},
+	}
+	err = CheckRenamePlan(invalid)
+	if err == nil {
+		t.Fatal("CheckRenamePlan() error = nil, want problems")
+	}
+	for _, want := range []string{
+		"a.srt changed since the plan was made",
+		"Taken.srt already exists",
+		"a.srt and c.srt are both renamed to A.srt",
+		"missing.srt cannot be read",
+		"not a file below the current directory",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("CheckRenamePlan() error = %v, want contains %q", err, want)
+		}
+	}
+}
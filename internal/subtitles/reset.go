@@ -42,20 +42,57 @@ func ResetCurrentDirSubtitleFilesToUTF8() (ResetResult, error) {
 	return result, nil
 }
 
+// PlanCurrentDirSubtitleFilesUTF8Reset returns the files that
+// ResetCurrentDirSubtitleFilesToUTF8 would convert, without writing them.
+func PlanCurrentDirSubtitleFilesUTF8Reset() ([]FileChange, error) {
+	files, err := ListCurrentDirSubtitleFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []FileChange
+	for _, file := range files {
+		change, err := utf8ResetChange(file)
+		if err != nil {
+			return nil, err
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+
+	return changes, nil
+}
+
 func resetFileToUTF8(file string) (bool, error) {
-	if err := validateSubtitleFileSize(file); err != nil {
+	change, err := utf8ResetChange(file)
+	if err != nil || change == nil {
+		return false, err
+	}
+
+	if err := writeFilePreserveMode(file, []byte(change.After)); err != nil {
 		return false, err
 	}
 
+	return true, nil
+}
+
+// utf8ResetChange returns the conversion of file to UTF-8, or nil when the
+// file is UTF-8 already.
+func utf8ResetChange(file string) (*FileChange, error) {
+	if err := validateSubtitleFileSize(file); err != nil {
+		return nil, err
+	}
+
 	content, err := os.ReadFile(file)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
 	encoding := detectFileEncoding(file)
 	if isUTF8Encoding(encoding) {
 		if utf8.Valid(content) {
-			return false, nil
+			return nil, nil
 		}
 
 		encoding = UnknownEncoding
@@ -63,18 +100,21 @@ func resetFileToUTF8(file string) (bool, error) {
 
 	converted, err := convertToUTF8(content, encoding)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
 	if bytes.Equal(content, converted) {
-		return false, nil
+		return nil, nil
 	}
 
-	if err := writeFilePreserveMode(file, converted); err != nil {
-		return false, err
-	}
-
-	return true, nil
+	// The decoded text is the same before and after; only the encoding
+	// changes.
+	return &FileChange{
+		FileName: file,
+		Encoding: normalizeEncoding(encoding),
+		Before:   string(converted),
+		After:    string(converted),
+	}, nil
 }
 
 func isUTF8Encoding(encoding string) bool {
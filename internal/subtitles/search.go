@@ -3,22 +3,12 @@ package subtitles
 import (
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
 )
 
-var episodeTagPattern = regexp.MustCompile(`S[0-9]{2}E[0-9]{2}`)
-
-func ExtractEpisodeTag(fileName string) (string, bool) {
-	match := episodeTagPattern.FindString(fileName)
-	if match == "" {
-		return "", false
-	}
-
-	return match, true
-}
-
-func FindVideoFileByEpisodeTag(episodeTag string) (string, error) {
+// FindVideoFileByEpisode returns the first mkv, then mp4, file in the current
+// directory whose name matchers read as the same episode as key.
+func FindVideoFileByEpisode(key EpisodeKey, matchers EpisodeMatchers) (string, error) {
 	entries, err := os.ReadDir(".")
 	if err != nil {
 		return "", err
@@ -26,11 +16,11 @@ func FindVideoFileByEpisodeTag(episodeTag string) (string, error) {
 
 	for _, ext := range []string{".mkv", ".mp4"} {
 		for _, entry := range entries {
-			if !entry.Type().IsRegular() {
+			if !entry.Type().IsRegular() || !strings.EqualFold(filepath.Ext(entry.Name()), ext) {
 				continue
 			}
 
-			if strings.EqualFold(filepath.Ext(entry.Name()), ext) && strings.Contains(entry.Name(), episodeTag) {
+			if videoKey, ok := matchers.Match(entry.Name()); ok && videoKey.Matches(key) {
 				return entry.Name(), nil
 			}
 		}
@@ -38,3 +28,40 @@ func FindVideoFileByEpisodeTag(episodeTag string) (string, error) {
 
 	return "", nil
 }
+
+// FindMKVFileForSubtitle returns the mkv file in the current directory that
+// subtitleFile belongs to: one with the same base name, optionally followed
+// by a language suffix (Show.S01E01.chs.srt), or else one with the same
+// episode tag.
+func FindMKVFileForSubtitle(subtitleFile string) (string, error) {
+	entries, err := os.ReadDir(".")
+	if err != nil {
+		return "", err
+	}
+
+	var mkvFiles []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.EqualFold(filepath.Ext(entry.Name()), ".mkv") {
+			mkvFiles = append(mkvFiles, entry.Name())
+		}
+	}
+
+	subtitleBase := strings.TrimSuffix(subtitleFile, filepath.Ext(subtitleFile))
+	for _, mkvFile := range mkvFiles {
+		mkvBase := strings.TrimSuffix(mkvFile, filepath.Ext(mkvFile))
+		if subtitleBase == mkvBase || strings.HasPrefix(subtitleBase, mkvBase+".") {
+			return mkvFile, nil
+		}
+	}
+
+	key, ok := defaultEpisodeMatchers.Match(subtitleFile)
+	if !ok {
+		return "", nil
+	}
+	for _, mkvFile := range mkvFiles {
+		if mkvKey, ok := defaultEpisodeMatchers.Match(mkvFile); ok && mkvKey.Matches(key) {
+			return mkvFile, nil
+		}
+	}
+	return "", nil
+}
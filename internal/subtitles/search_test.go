@@ -5,18 +5,7 @@ import (
 	"testing"
 )
 
-func TestExtractEpisodeTag(t *testing.T) {
-	got, ok := ExtractEpisodeTag("Show.S03E12.srt")
-	if !ok || got != "S03E12" {
-		t.Fatalf("ExtractEpisodeTag() = %q, %v; want %q, true", got, ok, "S03E12")
-	}
-
-	if _, ok := ExtractEpisodeTag("Show.s03e12.srt"); ok {
-		t.Fatalf("expected lowercase tags to be not matched")
-	}
-}
-
-func TestFindVideoFileByEpisodeTag(t *testing.T) {
+func TestFindVideoFileByEpisode_ExtensionPriority(t *testing.T) {
 	tmpDir := t.TempDir()
 	originalDir, err := os.Getwd()
 	if err != nil {
@@ -30,46 +19,27 @@ func TestFindVideoFileByEpisodeTag(t *testing.T) {
 		_ = os.Chdir(originalDir)
 	})
 
-	writeTestFile(t, "video.S03E12.mp4")
-	writeTestFile(t, "video.S03E12.mkv")
-	writeTestFile(t, "video.S03E99.mkv")
+	writeTestFile(t, "show.S03E12.mp4")
+	writeTestFile(t, "show.S03E12.mkv")
 
-	got, err := FindVideoFileByEpisodeTag("S03E12")
+	got, err := FindVideoFileByEpisode(EpisodeKey{Season: 3, Episode: 12, LastEpisode: 12}, DefaultEpisodeMatchers())
 	if err != nil {
-		t.Fatalf("FindVideoFileByEpisodeTag() error = %v", err)
+		t.Fatalf("FindVideoFileByEpisode() error = %v", err)
 	}
-	if got != "video.S03E12.mkv" {
-		t.Fatalf("FindVideoFileByEpisodeTag() = %q, want %q", got, "video.S03E12.mkv")
+	if got != "show.S03E12.mkv" {
+		t.Fatalf("FindVideoFileByEpisode() = %q, want %q", got, "show.S03E12.mkv")
 	}
 }
 
-func TestFindVideoFileByEpisodeTag_NotFound(t *testing.T) {
-	tmpDir := t.TempDir()
-	originalDir, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("getwd failed: %v", err)
-	}
-
-	if err := os.Chdir(tmpDir); err != nil {
-		t.Fatalf("chdir failed: %v", err)
-	}
-	t.Cleanup(func() {
-		_ = os.Chdir(originalDir)
-	})
-
-	writeTestFile(t, "video.S03E99.mkv")
-	writeTestFile(t, "video.S04E12.mp4")
+func writeTestFile(t *testing.T, name string) {
+	t.Helper()
 
-	got, err := FindVideoFileByEpisodeTag("S03E12")
-	if err != nil {
-		t.Fatalf("FindVideoFileByEpisodeTag() error = %v", err)
-	}
-	if got != "" {
-		t.Fatalf("FindVideoFileByEpisodeTag() = %q, want empty", got)
+	if err := os.WriteFile(name, []byte("content"), 0o644); err != nil {
+		t.Fatalf("write file %s failed: %v", name, err)
 	}
 }
 
-func TestFindVideoFileByEpisodeTag_ExtensionPriority(t *testing.T) {
+func TestFindMKVFileForSubtitle(t *testing.T) {
 	tmpDir := t.TempDir()
 	originalDir, err := os.Getwd()
 	if err != nil {
@@ -83,22 +53,25 @@ func TestFindVideoFileByEpisodeTag_ExtensionPriority(t *testing.T) {
 		_ = os.Chdir(originalDir)
 	})
 
-	writeTestFile(t, "show.S03E12.mp4")
-	writeTestFile(t, "show.S03E12.mkv")
-
-	got, err := FindVideoFileByEpisodeTag("S03E12")
-	if err != nil {
-		t.Fatalf("FindVideoFileByEpisodeTag() error = %v", err)
-	}
-	if got != "show.S03E12.mkv" {
-		t.Fatalf("FindVideoFileByEpisodeTag() = %q, want %q", got, "show.S03E12.mkv")
+	writeTestFile(t, "Movie.mkv")
+	writeTestFile(t, "Show.S01E01.1080p.mkv")
+	writeTestFile(t, "Show.S01E02.mp4")
+
+	cases := map[string]string{
+		"Movie.srt":         "Movie.mkv",
+		"Movie.chs.srt":     "Movie.mkv",
+		"Show.S01E01.ass":   "Show.S01E01.1080p.mkv",
+		"Show.S01E02.srt":   "",
+		"Unrelated.chs.srt": "",
+		"MovieExtended.srt": "",
 	}
-}
-
-func writeTestFile(t *testing.T, name string) {
-	t.Helper()
-
-	if err := os.WriteFile(name, []byte("content"), 0o644); err != nil {
-		t.Fatalf("write file %s failed: %v", name, err)
+	for subtitleFile, want := range cases {
+		got, err := FindMKVFileForSubtitle(subtitleFile)
+		if err != nil {
+			t.Fatalf("FindMKVFileForSubtitle(%q) error = %v", subtitleFile, err)
+		}
+		if got != want {
+			t.Fatalf("FindMKVFileForSubtitle(%q) = %q, want %q", subtitleFile, got, want)
+		}
 	}
 }
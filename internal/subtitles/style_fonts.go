@@ -177,12 +177,50 @@ func ResetCurrentDirAssStyleFontsToMicrosoftYaHei() (AssStyleFontResetResult, er
 	return result, nil
 }
 
+// PlanCurrentDirAssStyleFontReset returns the files that
+// ResetCurrentDirAssStyleFontsToMicrosoftYaHei would rewrite, without
+// writing them.
+func PlanCurrentDirAssStyleFontReset() ([]FileChange, error) {
+	files, err := listCurrentDirAssFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []FileChange
+	for _, file := range files {
+		content, err := readAssText(file)
+		if err != nil {
+			return nil, err
+		}
+
+		updatedContent, updatedStyles := resetStyleFontsInAssText(content)
+		if updatedStyles > 0 {
+			changes = append(changes, FileChange{FileName: file, Before: content, After: updatedContent})
+		}
+	}
+
+	return changes, nil
+}
+
 func resetStyleFontsInAssFile(path string) (int, error) {
 	content, err := readAssText(path)
 	if err != nil {
 		return 0, err
 	}
 
+	updatedContent, updated := resetStyleFontsInAssText(content)
+	if updated == 0 {
+		return 0, nil
+	}
+
+	if err := writeFilePreserveMode(path, []byte(updatedContent)); err != nil {
+		return 0, err
+	}
+
+	return updated, nil
+}
+
+func resetStyleFontsInAssText(content string) (string, int) {
 	lines := strings.Split(content, "\n")
 	out := make([]string, 0, len(lines))
 
@@ -246,15 +284,10 @@ func resetStyleFontsInAssFile(path string) (int, error) {
 	}
 
 	if updated == 0 {
-		return 0, nil
+		return content, 0
 	}
 
-	returned := strings.Join(out, "\n")
-	if err := writeFilePreserveMode(path, []byte(returned)); err != nil {
-		return 0, err
-	}
-
-	return updated, nil
+	return strings.Join(out, "\n"), updated
 }
 
 func readAssText(path string) (string, error) {
@@ -0,0 +1,194 @@
+package subtitles
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DefaultTitleMinScore is the score a title match needs when no other
+// threshold is given.
+const DefaultTitleMinScore = 0.6
+
+var (
+	bracketedTagPattern = regexp.MustCompile(`\[[^\]]*\]|【[^】]*】`)
+	videoCodecPattern   = regexp.MustCompile(`(?i)\b[hx]\.?(26[45])\b`)
+	yearTokenPattern    = regexp.MustCompile(`^(?:19|20)\d{2}$`)
+	releaseTokenPattern = regexp.MustCompile(`^(?:\d{3,4}[pi]|[48]k|uhd|x26[45]|hevc|avc|xvid|divx|av1|vp9|\d+bit|hdr\d*(?:plus)?|dv|dovi|sdr|blu|bluray|bd|bdrip|brrip|bdremux|remux|web|webdl|webrip|dl|hdtv|hdrip|dvdrip|dvd|dvdscr|cam|hc|aac\d*|ac3|dts\w*|ddp?\d*|eac3|truehd|atmos|flac|opus|mp3|proper|repack|internal|limited|multi)$`)
+	subtitleTagTokens   = map[string]bool{
+		"chs": true, "cht": true, "chi": true, "zho": true, "zh": true, "sc": true, "tc": true, "gb": true, "big5": true,
+		"eng": true, "en": true, "jpn": true, "ja": true, "kor": true, "ko": true,
+		"fre": true, "fra": true, "fr": true, "ger": true, "deu": true, "de": true, "spa": true, "es": true,
+		"sdh": true, "forced": true, "cc": true,
+		"简体": true, "繁体": true, "中英": true, "简中": true, "繁中": true,
+	}
+)
+
+// ReleaseTitle is the title part of a release name with resolution, codec,
+// source and group tags removed, and the year that ended the title.
+type ReleaseTitle struct {
+	Tokens []string
+	Year   string
+}
+
+// ParseReleaseTitle reads the title from a release name: the words before
+// the year or the first resolution, codec or source tag. Bracketed group tags
+// and trailing subtitle language tags are dropped.
+func ParseReleaseTitle(fileName string) ReleaseTitle {
+	name := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	name = bracketedTagPattern.ReplaceAllString(name, " ")
+	name = videoCodecPattern.ReplaceAllString(name, "x$1")
+
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	title := ReleaseTitle{}
+	for i, word := range words {
+		if i > 0 && yearTokenPattern.MatchString(word) {
+			title.Year = word
+			break
+		}
+		if releaseTokenPattern.MatchString(word) {
+			break
+		}
+		title.Tokens = append(title.Tokens, word)
+	}
+
+	for len(title.Tokens) > 1 && subtitleTagTokens[title.Tokens[len(title.Tokens)-1]] {
+		title.Tokens = title.Tokens[:len(title.Tokens)-1]
+	}
+	return title
+}
+
+// TitleScore rates how alike the titles of two release names are, from 0 to
+// 1, by the Dice coefficient of their letter pairs; word breaks are ignored
+// so Spider-Man and Spiderman score 1. Different years halve the score.
+func TitleScore(subtitleFile, videoFile string) float64 {
+	subtitleTitle := ParseReleaseTitle(subtitleFile)
+	videoTitle := ParseReleaseTitle(videoFile)
+
+	score := diceCoefficient(strings.Join(subtitleTitle.Tokens, ""), strings.Join(videoTitle.Tokens, ""))
+	if subtitleTitle.Year != "" && videoTitle.Year != "" && subtitleTitle.Year != videoTitle.Year {
+		score /= 2
+	}
+	return score
+}
+
+func diceCoefficient(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+
+	aPairs := runePairs(a)
+	bPairs := runePairs(b)
+	if len(aPairs) == 0 || len(bPairs) == 0 {
+		return 0
+	}
+
+	counts := map[string]int{}
+	for _, pair := range aPairs {
+		counts[pair]++
+	}
+	common := 0
+	for _, pair := range bPairs {
+		if counts[pair] > 0 {
+			counts[pair]--
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(len(aPairs)+len(bPairs))
+}
+
+func runePairs(text string) []string {
+	runes := []rune(text)
+	pairs := make([]string, 0, len(runes))
+	for i := 0; i+1 < len(runes); i++ {
+		pairs = append(pairs, string(runes[i:i+2]))
+	}
+	return pairs
+}
+
+type TitleMatch struct {
+	Subtitle string
+	Video    string
+	Score    float64
+}
+
+// MatchTitles pairs subtitle and video files whose titles score at least
+// minScore. Each file is used at most once; the best scoring pairs are taken
+// first.
+func MatchTitles(subtitleFiles, videoFiles []string, minScore float64) []TitleMatch {
+	var candidates []TitleMatch
+	for _, subtitleFile := range subtitleFiles {
+		for _, videoFile := range videoFiles {
+			score := TitleScore(subtitleFile, videoFile)
+			if score > 0 && score >= minScore {
+				candidates = append(candidates, TitleMatch{Subtitle: subtitleFile, Video: videoFile, Score: score})
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		if candidates[i].Subtitle != candidates[j].Subtitle {
+			return candidates[i].Subtitle < candidates[j].Subtitle
+		}
+		return candidates[i].Video < candidates[j].Video
+	})
+
+	usedSubtitles := map[string]bool{}
+	usedVideos := map[string]bool{}
+	var matches []TitleMatch
+	for _, candidate := range candidates {
+		if usedSubtitles[candidate.Subtitle] || usedVideos[candidate.Video] {
+			continue
+		}
+		usedSubtitles[candidate.Subtitle] = true
+		usedVideos[candidate.Video] = true
+		matches = append(matches, candidate)
+	}
+	return matches
+}
+
+// MatchCurrentDirTitles matches the subtitle files that have no episode tag
+// to the mkv and mp4 files in the current directory that have none either,
+// keyed by subtitle file.
+func MatchCurrentDirTitles(subtitleFiles []string, matchers EpisodeMatchers, minScore float64) (map[string]TitleMatch, error) {
+	entries, err := os.ReadDir(".")
+	if err != nil {
+		return nil, err
+	}
+
+	var videoFiles []string
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if !entry.Type().IsRegular() || (ext != ".mkv" && ext != ".mp4") {
+			continue
+		}
+		if _, ok := matchers.Match(entry.Name()); !ok {
+			videoFiles = append(videoFiles, entry.Name())
+		}
+	}
+
+	var titleSubtitles []string
+	for _, subtitleFile := range subtitleFiles {
+		if _, ok := matchers.Match(subtitleFile); !ok {
+			titleSubtitles = append(titleSubtitles, subtitleFile)
+		}
+	}
+
+	matches := map[string]TitleMatch{}
+	for _, match := range MatchTitles(titleSubtitles, videoFiles, minScore) {
+		matches[match.Subtitle] = match
+	}
+	return matches, nil
+}
@@ -0,0 +1,79 @@
+package subtitles
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseReleaseTitle(t *testing.T) {
+	cases := map[string]ReleaseTitle{
+		"The.Matrix.1999.1080p.BluRay.x264-SPARKS.mkv": {Tokens: []string{"the", "matrix"}, Year: "1999"},
+		"The Matrix (1999).chs.srt":                    {Tokens: []string{"the", "matrix"}, Year: "1999"},
+		"[Group] Spirited Away [BD 1080p HEVC].mkv":    {Tokens: []string{"spirited", "away"}},
+		"Spider-Man.Into.the.Spider-Verse.H.264.mp4":   {Tokens: []string{"spider", "man", "into", "the", "spider", "verse"}},
+		"2012.2009.720p.mkv":                           {Tokens: []string{"2012"}, Year: "2009"},
+		"Arrival.eng.srt":                              {Tokens: []string{"arrival"}},
+		"流浪地球.2019.WEB-DL.mkv":                         {Tokens: []string{"流浪地球"}, Year: "2019"},
+	}
+	for name, want := range cases {
+		if got := ParseReleaseTitle(name); !reflect.DeepEqual(got, want) {
+			t.Fatalf("ParseReleaseTitle(%q) = %+v, want %+v", name, got, want)
+		}
+	}
+}
+
+func TestTitleScore(t *testing.T) {
+	if score := TitleScore("The Matrix (1999).chs.srt", "The.Matrix.1999.1080p.BluRay.x264-SPARKS.mkv"); score != 1 {
+		t.Fatalf("same title score = %v, want 1", score)
+	}
+	if score := TitleScore("Spiderman.Into.The.Spiderverse.srt", "Spider-Man.Into.the.Spider-Verse.2018.mkv"); score != 1 {
+		t.Fatalf("word break score = %v, want 1", score)
+	}
+	if score := TitleScore("The.Matrix.srt", "The.Batman.2022.mkv"); score >= DefaultTitleMinScore {
+		t.Fatalf("different title score = %v, want below %v", score, DefaultTitleMinScore)
+	}
+	if score := TitleScore("Dune.1984.srt", "Dune.2021.2160p.mkv"); score != 0.5 {
+		t.Fatalf("different year score = %v, want 0.5", score)
+	}
+}
+
+func TestMatchTitles_OneToOne(t *testing.T) {
+	subtitleFiles := []string{"Dune.2021.srt", "Dune.srt", "Arrival.srt"}
+	videoFiles := []string{"Dune.2021.2160p.WEB-DL.mkv", "Arrival.2016.1080p.mkv", "Sicario.2015.mkv"}
+
+	got := MatchTitles(subtitleFiles, videoFiles, DefaultTitleMinScore)
+	want := []TitleMatch{
+		{Subtitle: "Arrival.srt", Video: "Arrival.2016.1080p.mkv", Score: 1},
+		{Subtitle: "Dune.2021.srt", Video: "Dune.2021.2160p.WEB-DL.mkv", Score: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("MatchTitles() = %+v, want %+v", got, want)
+	}
+}
+
+func TestMatchCurrentDirTitles_SkipsEpisodes(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd failed: %v", err)
+	}
+
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(originalDir)
+	})
+
+	writeTestFile(t, "Show.S01E01.mkv")
+	writeTestFile(t, "Arrival.2016.1080p.mkv")
+
+	matches, err := MatchCurrentDirTitles([]string{"Show.srt", "Arrival.chs.srt", "Show.S01E01.srt"}, DefaultEpisodeMatchers(), DefaultTitleMinScore)
+	if err != nil {
+		t.Fatalf("MatchCurrentDirTitles() error = %v", err)
+	}
+	if len(matches) != 1 || matches["Arrival.chs.srt"].Video != "Arrival.2016.1080p.mkv" {
+		t.Fatalf("MatchCurrentDirTitles() = %+v, want only the movie matched", matches)
+	}
+}